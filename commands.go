@@ -0,0 +1,450 @@
+// Discordgo - Discord bindings for Go
+// Available at https://github.com/bwmarrin/discordgo
+
+// Copyright 2015-2016 Bruce Marriner <bruce@sqls.net>.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains a router for text commands invoked with a prefix, and
+// the parsing of their arguments
+
+package discordgo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ErrCommandArgMissing is returned by the argument helpers of
+// CommandContext when the command was invoked with too few arguments.
+var ErrCommandArgMissing = errors.New("command argument missing")
+
+// A CommandRouter dispatches the messages starting with its prefix to the
+// registered commands, e.g. "!ping" to the command "ping". Create one with
+// NewCommandRouter.
+type CommandRouter struct {
+	sync.RWMutex
+
+	// The prefix of the commands, such as "!". Only mentions are used as
+	// the prefix if empty.
+	Prefix string
+
+	// Can commands also be invoked by mentioning the bot, e.g.
+	// "@Bot ping", as recommended by Discord. The bot user is read from
+	// State. Set by NewCommandRouter.
+	MentionPrefix bool
+
+	// The reply sent when a command is invoked during its cooldown, in
+	// which "%s" is replaced by the time remaining. No reply is sent if
+	// empty. Set by NewCommandRouter.
+	CooldownMessage string
+
+	// The reply sent when a command is invoked by a member lacking the
+	// permissions it requires. No reply is sent if empty. Set by
+	// NewCommandRouter.
+	PermissionDeniedMessage string
+
+	session  *Session
+	commands map[string]*command
+	remove   func()
+}
+
+// command is a command registered on a CommandRouter.
+type command struct {
+	sync.Mutex
+	handler func(*CommandContext)
+
+	cooldown     time.Duration
+	userCooldown time.Duration
+	permissions  int64
+
+	// the time of the last invocation, and of the last invocation by
+	// each user during userCooldown
+	last       time.Time
+	lastByUser map[string]time.Time
+}
+
+// A CommandOption configures a command registered with Register.
+type CommandOption func(*command)
+
+// CommandCooldown allows a command to be invoked once per d, by anyone.
+func CommandCooldown(d time.Duration) CommandOption {
+	return func(c *command) {
+		c.cooldown = d
+	}
+}
+
+// CommandUserCooldown allows a command to be invoked once per d by each
+// user.
+func CommandUserCooldown(d time.Duration) CommandOption {
+	return func(c *command) {
+		c.userCooldown = d
+	}
+}
+
+// CommandPermissions allows a command to be invoked only by the members who
+// have all of permissions in the channel it is invoked in, such as
+// PermissionManageMessages. The permissions are computed with
+// UserChannelPermissions, from the 32 bit permissions of API v6, so the
+// permissions above them, such as PermissionModerateMembers, are only
+// granted to administrators and the guild owner. Commands requiring
+// permissions can not be invoked in direct messages.
+func CommandPermissions(permissions int64) CommandOption {
+	return func(c *command) {
+		c.permissions = permissions
+	}
+}
+
+// use records an invocation of the command by a user, unless the command is
+// on cooldown, in which case the time remaining is returned.
+func (c *command) use(userID string) (remaining time.Duration) {
+	if c.cooldown <= 0 && c.userCooldown <= 0 {
+		return 0
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	if c.cooldown > 0 {
+		remaining = c.last.Add(c.cooldown).Sub(now)
+	}
+	if c.userCooldown > 0 {
+		for id, last := range c.lastByUser {
+			if now.Sub(last) >= c.userCooldown {
+				delete(c.lastByUser, id)
+			}
+		}
+		if last, ok := c.lastByUser[userID]; ok {
+			if d := last.Add(c.userCooldown).Sub(now); d > remaining {
+				remaining = d
+			}
+		}
+	}
+	if remaining > 0 {
+		return remaining
+	}
+
+	c.last = now
+	if c.userCooldown > 0 {
+		if c.lastByUser == nil {
+			c.lastByUser = make(map[string]time.Time)
+		}
+		c.lastByUser[userID] = now
+	}
+	return 0
+}
+
+// A CommandContext holds the invocation of a command.
+type CommandContext struct {
+	Session *Session
+
+	// The message invoking the command.
+	Message *Message
+
+	// The name of the command, as registered, e.g. "role add" for a
+	// subcommand.
+	Command string
+
+	// The arguments following the name of the command, split on spaces.
+	// Arguments in double quotes may contain spaces, e.g. `"two words"`.
+	Args []string
+}
+
+// Reply sends a reply to the message invoking the command.
+// content : The content of the reply.
+func (c *CommandContext) Reply(content string) (*Message, error) {
+	return c.Session.ChannelMessageSendReply(c.Message.ChannelID, content, c.Message.Reference())
+}
+
+// arg returns the argument n, counting from 0.
+func (c *CommandContext) arg(n int) (string, error) {
+	if n < 0 || n >= len(c.Args) {
+		return "", ErrCommandArgMissing
+	}
+	return c.Args[n], nil
+}
+
+// IntArg returns the argument n, counting from 0, as an integer.
+// n : The index of the argument.
+func (c *CommandContext) IntArg(n int) (int, error) {
+	arg, err := c.arg(n)
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("argument %d is not an integer: %q", n, arg)
+	}
+	return i, nil
+}
+
+var (
+	patternUserArg    = regexp.MustCompile(`^(?:<@!?([0-9]+)>|([0-9]+))$`)
+	patternChannelArg = regexp.MustCompile(`^(?:<#([0-9]+)>|([0-9]+))$`)
+)
+
+// argID returns the ID in the argument n, which is either a mention matched
+// by pattern or an ID.
+func (c *CommandContext) argID(n int, pattern *regexp.Regexp, kind string) (string, error) {
+	arg, err := c.arg(n)
+	if err != nil {
+		return "", err
+	}
+
+	match := pattern.FindStringSubmatch(arg)
+	if match == nil {
+		return "", fmt.Errorf("argument %d is not a %s: %q", n, kind, arg)
+	}
+	return match[1] + match[2], nil
+}
+
+// UserArg returns the user mentioned, or whose ID is given, in the argument
+// n, counting from 0. The user is looked up in the mentions of the message,
+// then in State, then fetched.
+// n : The index of the argument.
+func (c *CommandContext) UserArg(n int) (*User, error) {
+	id, err := c.argID(n, patternUserArg, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range c.Message.Mentions {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+
+	if state, err := c.Session.state(); err == nil && c.Message.GuildID != "" {
+		if m, err := state.Member(c.Message.GuildID, id); err == nil && m.User != nil {
+			return m.User, nil
+		}
+	}
+
+	return c.Session.User(id)
+}
+
+// ChannelArg returns the channel mentioned, or whose ID is given, in the
+// argument n, counting from 0. The channel is looked up in State, then
+// fetched.
+// n : The index of the argument.
+func (c *CommandContext) ChannelArg(n int) (*Channel, error) {
+	id, err := c.argID(n, patternChannelArg, "channel")
+	if err != nil {
+		return nil, err
+	}
+
+	if state, err := c.Session.state(); err == nil {
+		if channel, err := state.Channel(id); err == nil {
+			return channel, nil
+		}
+	}
+
+	return c.Session.Channel(id)
+}
+
+// NewCommandRouter returns a CommandRouter which handles the messages
+// received by the session, until Stop is called. Messages from bots are
+// ignored.
+// prefix : The prefix of the commands, such as "!".
+func (s *Session) NewCommandRouter(prefix string) *CommandRouter {
+	r := &CommandRouter{
+		Prefix:          prefix,
+		MentionPrefix:   true,
+		CooldownMessage: "This command is on cooldown, try again in %s.",
+		session:         s,
+		commands:        make(map[string]*command),
+
+		PermissionDeniedMessage: "You do not have the permissions to use this command.",
+	}
+	r.remove = s.AddHandler(r.onMessageCreate)
+
+	return r
+}
+
+// Register adds a command to the router, replacing any command with the same
+// name. Command names are not case sensitive. A subcommand is registered
+// with its name after the name of its command, e.g. "role add", and is
+// preferred over the command when both match.
+// name    : The name of the command, such as "ping".
+// handler : The function called when the command is invoked.
+// opts    : The options of the command, such as CommandUserCooldown.
+func (r *CommandRouter) Register(name string, handler func(*CommandContext), opts ...CommandOption) {
+	cmd := &command{handler: handler}
+	for _, opt := range opts {
+		opt(cmd)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.commands[strings.ToLower(strings.Join(strings.Fields(name), " "))] = cmd
+}
+
+// Stop stops the router from handling messages.
+func (r *CommandRouter) Stop() {
+	r.remove()
+}
+
+// onMessageCreate dispatches a message to the command it invokes, if any.
+func (r *CommandRouter) onMessageCreate(s *Session, m *MessageCreate) {
+	if m.Message == nil || m.Author == nil || m.Author.Bot {
+		return
+	}
+
+	content, ok := r.trimPrefix(s, m.Content)
+	if !ok {
+		return
+	}
+
+	args := splitCommandArgs(content)
+	if len(args) == 0 {
+		return
+	}
+
+	r.RLock()
+	name, cmd, n := "", (*command)(nil), 0
+	if len(args) > 1 {
+		name, n = strings.ToLower(args[0]+" "+args[1]), 2
+		cmd = r.commands[name]
+	}
+	if cmd == nil {
+		name, n = strings.ToLower(args[0]), 1
+		cmd = r.commands[name]
+	}
+	r.RUnlock()
+	if cmd == nil {
+		return
+	}
+
+	ctx := &CommandContext{
+		Session: s,
+		Message: m.Message,
+		Command: name,
+		Args:    args[n:],
+	}
+
+	if cmd.permissions != 0 && !r.permitted(s, m.Message, name, cmd.permissions) {
+		r.RLock()
+		message := r.PermissionDeniedMessage
+		r.RUnlock()
+
+		r.reply(ctx, message)
+		return
+	}
+
+	if remaining := cmd.use(m.Author.ID); remaining > 0 {
+		r.RLock()
+		message := r.CooldownMessage
+		r.RUnlock()
+
+		remaining = (remaining + time.Second - 1).Truncate(time.Second)
+		r.reply(ctx, strings.Replace(message, "%s", remaining.String(), -1))
+		return
+	}
+
+	cmd.handler(ctx)
+}
+
+// permitted returns whether the author of the message m invoking the command
+// name has all of permissions in its channel.
+func (r *CommandRouter) permitted(s *Session, m *Message, name string, permissions int64) bool {
+	// There are no permissions in direct messages.
+	if m.GuildID == "" {
+		return false
+	}
+
+	granted, err := s.UserChannelPermissions(m.Author.ID, m.ChannelID)
+	if err != nil {
+		s.log(LogError, "error getting permissions of %s for command %s, %s", m.Author.ID, name, err)
+		return false
+	}
+
+	// Administrators have every permission, including those above the
+	// 32 bits UserChannelPermissions computes.
+	if granted&PermissionAdministrator == PermissionAdministrator {
+		return true
+	}
+	return int64(granted)&permissions == permissions
+}
+
+// reply replies to a command which was not run, unless message is empty.
+func (r *CommandRouter) reply(ctx *CommandContext, message string) {
+	if message == "" {
+		return
+	}
+
+	if _, err := ctx.Reply(message); err != nil {
+		ctx.Session.log(LogError, "error replying to command %s, %s", ctx.Command, err)
+	}
+}
+
+// trimPrefix returns the content of a message without the prefix of the
+// commands, or false if it does not start with the prefix.
+func (r *CommandRouter) trimPrefix(s *Session, content string) (string, bool) {
+	r.RLock()
+	prefix, mentionPrefix := r.Prefix, r.MentionPrefix
+	r.RUnlock()
+
+	if prefix != "" && strings.HasPrefix(content, prefix) {
+		return content[len(prefix):], true
+	}
+
+	if mentionPrefix && s.State != nil {
+		s.State.RLock()
+		user := s.State.User
+		s.State.RUnlock()
+
+		if user != nil {
+			for _, mention := range []string{"<@" + user.ID + ">", "<@!" + user.ID + ">"} {
+				if strings.HasPrefix(content, mention) {
+					return content[len(mention):], true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// splitCommandArgs splits the content of a command on spaces, keeping the
+// spaces in double quotes. The quotes are removed, a quote or a backslash
+// may be escaped with a backslash.
+func splitCommandArgs(content string) (args []string) {
+	var arg strings.Builder
+	inArg, quoted := false, false
+
+	runes := []rune(content)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\'):
+			i++
+			arg.WriteRune(runes[i])
+			inArg = true
+		case c == '"':
+			inArg, quoted = true, !quoted
+		case unicode.IsSpace(c) && !quoted:
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return
+}
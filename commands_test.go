@@ -0,0 +1,372 @@
+package discordgo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSplitCommandArgs(t *testing.T) {
+	tests := []struct {
+		content string
+		args    []string
+	}{
+		{"ban someone", []string{"ban", "someone"}},
+		{"  spaced   out  ", []string{"spaced", "out"}},
+		{`say "hello world" twice`, []string{"say", "hello world", "twice"}},
+		{`say "" empty`, []string{"say", "", "empty"}},
+		{`tag "half"quoted`, []string{"tag", "halfquoted"}},
+		{`quote \"not grouped\"`, []string{"quote", `"not`, `grouped"`}},
+		{`path C:\dir\\file`, []string{"path", `C:\dir\file`}},
+		{`unterminated "quote here`, []string{"unterminated", "quote here"}},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		args := splitCommandArgs(test.content)
+		if strings.Join(args, "|") != strings.Join(test.args, "|") || len(args) != len(test.args) {
+			t.Errorf("splitCommandArgs(%q) = %q, expected %q", test.content, args, test.args)
+		}
+	}
+}
+
+// newTestCommandRouter returns a router on a session whose requests are
+// handled by handler, and a function sending a message to it.
+func newTestCommandRouter(t *testing.T, prefix string, handler http.HandlerFunc) (*CommandRouter, func(authorID, content string)) {
+	s := newTestSession(handler)
+	s.SyncEvents = true
+	r := s.NewCommandRouter(prefix)
+
+	seq := 0
+	send := func(authorID, content string) {
+		seq++
+		data, _ := json.Marshal(map[string]interface{}{
+			"id":         "message",
+			"channel_id": "channel",
+			"guild_id":   "guild",
+			"content":    content,
+			"author":     map[string]interface{}{"id": authorID, "bot": authorID == "bot"},
+		})
+		if _, err := s.onEvent(websocket.TextMessage, dispatch(seq, "MESSAGE_CREATE", string(data))); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+	}
+	return r, send
+}
+
+func TestCommandRouter(t *testing.T) {
+	var replies []map[string]interface{}
+	r, send := newTestCommandRouter(t, "!", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		replies = append(replies, body)
+		w.Write([]byte(`{"id":"reply"}`))
+	})
+
+	var invoked []*CommandContext
+	r.Register("say", func(c *CommandContext) {
+		invoked = append(invoked, c)
+		c.Reply(strings.Join(c.Args, " "))
+	})
+	r.Register("Ping", func(c *CommandContext) {
+		invoked = append(invoked, c)
+	})
+
+	send("user", `!say "hello there" friend`)
+	if len(invoked) != 1 {
+		t.Fatalf("say was invoked %d times", len(invoked))
+	}
+	if c := invoked[0]; c.Command != "say" || len(c.Args) != 2 || c.Args[0] != "hello there" || c.Args[1] != "friend" || c.Message.ID != "message" {
+		t.Errorf("say was invoked with %+v", c)
+	}
+	if len(replies) != 1 || replies[0]["content"] != "hello there friend" {
+		t.Fatalf("replies were %v", replies)
+	}
+	if ref, _ := replies[0]["message_reference"].(map[string]interface{}); ref["message_id"] != "message" {
+		t.Errorf("reply references %v, expected the invoking message", replies[0]["message_reference"])
+	}
+
+	send("user", "!PING")
+	if len(invoked) != 2 || invoked[1].Command != "ping" || len(invoked[1].Args) != 0 {
+		t.Errorf("ping was not invoked case insensitively")
+	}
+
+	// Messages without the prefix, unknown commands and messages from bots
+	// are ignored.
+	send("user", "say hello")
+	send("user", "!unknown")
+	send("user", "!")
+	send("bot", "!ping")
+	if len(invoked) != 2 {
+		t.Errorf("commands were invoked by %+v", invoked[2:])
+	}
+
+	r.Stop()
+	send("user", "!ping")
+	if len(invoked) != 2 {
+		t.Error("command was invoked after the router was stopped")
+	}
+}
+
+func TestCommandRouterMentionPrefix(t *testing.T) {
+	r, send := newTestCommandRouter(t, "!", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	r.session.State.User = &User{ID: "self"}
+
+	var invoked []*CommandContext
+	r.Register("ping", func(c *CommandContext) {
+		invoked = append(invoked, c)
+	})
+
+	send("user", "!ping prefix")
+	send("user", "<@self> ping mention")
+	send("user", "<@!self>   ping nickname")
+	if len(invoked) != 3 {
+		t.Fatalf("ping was invoked %d times, expected 3", len(invoked))
+	}
+	for i, expected := range []string{"prefix", "mention", "nickname"} {
+		if args := invoked[i].Args; len(args) != 1 || args[0] != expected {
+			t.Errorf("invocation %d has args %q, expected %q", i, args, expected)
+		}
+	}
+
+	// Mentions of other users are not a prefix.
+	send("user", "<@someone> ping")
+	if len(invoked) != 3 {
+		t.Error("ping was invoked by mentioning another user")
+	}
+
+	r.MentionPrefix = false
+	send("user", "<@self> ping")
+	if len(invoked) != 3 {
+		t.Error("ping was invoked by a mention with MentionPrefix unset")
+	}
+
+	r.Prefix = ""
+	r.MentionPrefix = true
+	send("user", "ping")
+	send("user", "<@self> ping only")
+	if len(invoked) != 4 || invoked[3].Args[0] != "only" {
+		t.Errorf("without a prefix, ping was invoked %d times, expected only by the mention", len(invoked))
+	}
+}
+
+func TestCommandContextArgs(t *testing.T) {
+	var requests []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		w.Write([]byte(`{"id":"fetched","username":"fetched"}`))
+	})
+	s.State.GuildAdd(&Guild{ID: "guild", Channels: []*Channel{{ID: "111", Name: "general"}}})
+	s.State.MemberAdd(&Member{GuildID: "guild", User: &User{ID: "222", Username: "member"}})
+
+	c := &CommandContext{
+		Session: s,
+		Message: &Message{GuildID: "guild", Mentions: []*User{{ID: "333", Username: "mentioned"}}},
+		Args:    []string{"<@!333>", "42", "forty-two", "222", "<#111>", "444", "<@333"},
+	}
+
+	if u, err := c.UserArg(0); err != nil || u.Username != "mentioned" {
+		t.Errorf("UserArg(0) = %+v, %v, expected the mentioned user", u, err)
+	}
+	if u, err := c.UserArg(3); err != nil || u.Username != "member" {
+		t.Errorf("UserArg(3) = %+v, %v, expected the member from State", u, err)
+	}
+	if u, err := c.UserArg(5); err != nil || u.Username != "fetched" {
+		t.Errorf("UserArg(5) = %+v, %v, expected the fetched user", u, err)
+	}
+	if len(requests) != 1 || requests[0] != "/api/v6/users/444" {
+		t.Errorf("requests were %q, expected only the unknown user to be fetched", requests)
+	}
+	if _, err := c.UserArg(6); err == nil || err.Error() != `argument 6 is not a user: "<@333"` {
+		t.Errorf("UserArg(6) returned %v, expected a parse error", err)
+	}
+
+	if i, err := c.IntArg(1); err != nil || i != 42 {
+		t.Errorf("IntArg(1) = %d, %v, expected 42", i, err)
+	}
+	if _, err := c.IntArg(2); err == nil || err.Error() != `argument 2 is not an integer: "forty-two"` {
+		t.Errorf("IntArg(2) returned %v, expected a parse error", err)
+	}
+	if _, err := c.IntArg(7); err != ErrCommandArgMissing {
+		t.Errorf("IntArg(7) returned %v, expected ErrCommandArgMissing", err)
+	}
+
+	if ch, err := c.ChannelArg(4); err != nil || ch.Name != "general" {
+		t.Errorf("ChannelArg(4) = %+v, %v, expected the channel from State", ch, err)
+	}
+	if _, err := c.ChannelArg(0); err == nil {
+		t.Error("ChannelArg(0) returned no error for a user mention")
+	}
+}
+
+func TestCommandRouterSubcommands(t *testing.T) {
+	r, send := newTestCommandRouter(t, "!", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+
+	var invoked []*CommandContext
+	handler := func(c *CommandContext) {
+		invoked = append(invoked, c)
+	}
+	r.Register("role", handler)
+	r.Register("role  Add", handler)
+
+	send("user", "!role add <@1> mods")
+	send("user", "!role list")
+	if len(invoked) != 2 {
+		t.Fatalf("commands were invoked %d times, expected 2", len(invoked))
+	}
+	if c := invoked[0]; c.Command != "role add" || strings.Join(c.Args, " ") != "<@1> mods" {
+		t.Errorf("subcommand was invoked as %q with %q", c.Command, c.Args)
+	}
+	if c := invoked[1]; c.Command != "role" || strings.Join(c.Args, " ") != "list" {
+		t.Errorf("command was invoked as %q with %q", c.Command, c.Args)
+	}
+}
+
+func TestCommandRouterCooldown(t *testing.T) {
+	var replies []string
+	r, send := newTestCommandRouter(t, "!", func(w http.ResponseWriter, r *http.Request) {
+		var m MessageSend
+		json.NewDecoder(r.Body).Decode(&m)
+		replies = append(replies, m.Content)
+		w.Write([]byte(`{}`))
+	})
+
+	invoked := map[string]int{}
+	r.Register("daily", func(c *CommandContext) {
+		invoked["daily "+c.Message.Author.ID]++
+	}, CommandUserCooldown(time.Minute))
+	r.Register("ping", func(c *CommandContext) {
+		invoked["ping "+c.Message.Author.ID]++
+	}, CommandCooldown(30*time.Millisecond))
+
+	send("alice", "!daily")
+	send("alice", "!daily")
+	send("bob", "!daily")
+	if invoked["daily alice"] != 1 || invoked["daily bob"] != 1 {
+		t.Errorf("daily was invoked %v, expected once per user", invoked)
+	}
+	if len(replies) != 1 || replies[0] != "This command is on cooldown, try again in 1m0s." {
+		t.Errorf("replies were %q, expected a cooldown message", replies)
+	}
+
+	replies = nil
+	r.CooldownMessage = ""
+	send("alice", "!ping")
+	send("bob", "!ping")
+	if invoked["ping alice"] != 1 || invoked["ping bob"] != 0 {
+		t.Errorf("ping was invoked %v, expected the command cooldown to apply to everyone", invoked)
+	}
+	if len(replies) != 0 {
+		t.Errorf("replies were %q, expected none without a CooldownMessage", replies)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	send("bob", "!ping")
+	if invoked["ping bob"] != 1 {
+		t.Error("ping was not invoked after its cooldown")
+	}
+}
+
+func TestCommandRouterPermissions(t *testing.T) {
+	var replies []string
+	r, send := newTestCommandRouter(t, "!", func(w http.ResponseWriter, r *http.Request) {
+		var m MessageSend
+		json.NewDecoder(r.Body).Decode(&m)
+		replies = append(replies, m.Content)
+		w.Write([]byte(`{}`))
+	})
+
+	state := r.session.State
+	state.GuildAdd(&Guild{
+		ID: "guild",
+		Roles: []*Role{
+			{ID: "guild", Permissions: PermissionSendMessages},
+			{ID: "mods", Permissions: PermissionManageMessages | PermissionKickMembers},
+		},
+		Channels: []*Channel{{ID: "channel", GuildID: "guild"}},
+	})
+	state.MemberAdd(&Member{GuildID: "guild", User: &User{ID: "mod"}, Roles: []string{"mods"}})
+	state.MemberAdd(&Member{GuildID: "guild", User: &User{ID: "member"}})
+
+	var invoked []string
+	r.Register("purge", func(c *CommandContext) {
+		invoked = append(invoked, c.Message.Author.ID)
+	}, CommandPermissions(PermissionManageMessages|PermissionKickMembers), CommandUserCooldown(time.Minute))
+
+	send("member", "!purge 10")
+	if len(invoked) != 0 {
+		t.Errorf("purge was invoked by %q, expected the member to be denied", invoked)
+	}
+	if len(replies) != 1 || replies[0] != "You do not have the permissions to use this command." {
+		t.Errorf("replies were %q, expected a denial", replies)
+	}
+
+	send("mod", "!purge 10")
+	if len(invoked) != 1 || invoked[0] != "mod" {
+		t.Errorf("purge was invoked by %q, expected the mod", invoked)
+	}
+
+	// The denied invocation did not start the cooldown of the member.
+	replies = nil
+	state.MemberAdd(&Member{GuildID: "guild", User: &User{ID: "member"}, Roles: []string{"mods"}})
+	send("member", "!purge 10")
+	if len(invoked) != 2 || len(replies) != 0 {
+		t.Errorf("purge was invoked by %q with replies %q after the member was given the permissions", invoked, replies)
+	}
+}
+
+func TestCommandRouterHighPermissions(t *testing.T) {
+	var requests []string
+	r, send := newTestCommandRouter(t, "!", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{}`))
+	})
+
+	// Roles from API v6 never carry the permissions above 32 bits.
+	state := r.session.State
+	state.GuildAdd(&Guild{
+		ID: "guild",
+		Roles: []*Role{
+			{ID: "guild", Permissions: PermissionSendMessages},
+			{ID: "mods", Permissions: PermissionManageMessages},
+			{ID: "admins", Permissions: PermissionAdministrator},
+		},
+		Channels: []*Channel{{ID: "channel", GuildID: "guild"}},
+	})
+	state.MemberAdd(&Member{GuildID: "guild", User: &User{ID: "mod"}, Roles: []string{"mods"}})
+	state.MemberAdd(&Member{GuildID: "guild", User: &User{ID: "admin"}, Roles: []string{"admins"}})
+
+	var invoked []string
+	r.Register("timeout", func(c *CommandContext) {
+		invoked = append(invoked, c.Message.Author.ID)
+	}, CommandPermissions(PermissionModerateMembers|PermissionManageMessages))
+
+	send("mod", "!timeout")
+	send("admin", "!timeout")
+	if len(invoked) != 1 || invoked[0] != "admin" {
+		t.Errorf("timeout was invoked by %q, expected only the admin", invoked)
+	}
+
+	// Commands requiring permissions are denied in direct messages,
+	// without looking the permissions up.
+	requests = nil
+	r.onMessageCreate(r.session, &MessageCreate{&Message{
+		ChannelID: "dm",
+		Content:   "!timeout",
+		Author:    &User{ID: "admin"},
+	}})
+	if len(invoked) != 1 {
+		t.Errorf("timeout was invoked by %q in a direct message", invoked)
+	}
+	if len(requests) != 1 || requests[0] != "POST /api/v6/channels/dm/messages" {
+		t.Errorf("requests were %q, expected only the denial", requests)
+	}
+}
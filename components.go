@@ -0,0 +1,271 @@
+// Discordgo - Discord bindings for Go
+// Available at https://github.com/bwmarrin/discordgo
+
+// Copyright 2015-2016 Bruce Marriner <bruce@sqls.net>.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains the message components, such as buttons and select
+// menus, and their JSON encoding.
+
+package discordgo
+
+import (
+	"encoding/json"
+)
+
+// ComponentType is the type of a MessageComponent
+type ComponentType int
+
+// Block contains the valid known ComponentType values
+const (
+	ActionsRowComponent ComponentType = 1
+	ButtonComponent     ComponentType = 2
+	SelectMenuComponent ComponentType = 3
+	TextInputComponent  ComponentType = 4
+)
+
+// MessageComponent is a component of a message, such as a button or a
+// select menu. Its JSON encoding includes its type.
+type MessageComponent interface {
+	json.Marshaler
+	Type() ComponentType
+}
+
+// unmarshalableMessageComponent decodes a MessageComponent into the struct
+// of its type.
+type unmarshalableMessageComponent struct {
+	MessageComponent
+}
+
+// UnmarshalJSON unmarshals a MessageComponent according to its type
+func (umc *unmarshalableMessageComponent) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Type ComponentType `json:"type"`
+	}
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+
+	switch v.Type {
+	case ActionsRowComponent:
+		umc.MessageComponent = &ActionsRow{}
+	case ButtonComponent:
+		umc.MessageComponent = &Button{}
+	case SelectMenuComponent:
+		umc.MessageComponent = &SelectMenu{}
+	case TextInputComponent:
+		umc.MessageComponent = &TextInput{}
+	default:
+		umc.MessageComponent = &UnknownComponent{ComponentType: v.Type}
+	}
+	return json.Unmarshal(b, umc.MessageComponent)
+}
+
+// unmarshalMessageComponents converts the decoded components into their
+// MessageComponent values.
+func unmarshalMessageComponents(umcs []unmarshalableMessageComponent) []MessageComponent {
+	if umcs == nil {
+		return nil
+	}
+
+	components := make([]MessageComponent, len(umcs))
+	for i, umc := range umcs {
+		components[i] = umc.MessageComponent
+	}
+	return components
+}
+
+// ActionsRow is a row of components, which holds up to 5 buttons or a
+// single select menu.
+type ActionsRow struct {
+	Components []MessageComponent `json:"components"`
+}
+
+// Type returns the type of the component.
+func (ActionsRow) Type() ComponentType {
+	return ActionsRowComponent
+}
+
+// MarshalJSON marshals an ActionsRow along with its type
+func (r ActionsRow) MarshalJSON() ([]byte, error) {
+	type actionsRow ActionsRow
+
+	return json.Marshal(struct {
+		actionsRow
+		Type ComponentType `json:"type"`
+	}{actionsRow(r), r.Type()})
+}
+
+// UnmarshalJSON unmarshals an ActionsRow, decoding its components according
+// to their type
+func (r *ActionsRow) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Components []unmarshalableMessageComponent `json:"components"`
+	}
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+
+	r.Components = unmarshalMessageComponents(v.Components)
+	return nil
+}
+
+// ButtonStyle is the style of a Button
+type ButtonStyle int
+
+// Block contains the valid known ButtonStyle values
+const (
+	PrimaryButton   ButtonStyle = 1
+	SecondaryButton ButtonStyle = 2
+	SuccessButton   ButtonStyle = 3
+	DangerButton    ButtonStyle = 4
+	LinkButton      ButtonStyle = 5
+)
+
+// ComponentEmoji is the emoji shown on a button or a select menu option.
+// Custom emojis have an ID, unicode emojis only a name.
+type ComponentEmoji struct {
+	Name     string `json:"name,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Animated bool   `json:"animated,omitempty"`
+}
+
+// Button is a button which sends an interaction when it is pressed, or opens
+// its URL if it is a LinkButton.
+type Button struct {
+	Label    string          `json:"label,omitempty"`
+	Style    ButtonStyle     `json:"style"`
+	Disabled bool            `json:"disabled"`
+	Emoji    *ComponentEmoji `json:"emoji,omitempty"`
+
+	// The URL opened by a LinkButton, which has no CustomID.
+	URL string `json:"url,omitempty"`
+
+	// The ID sent in the interaction when the button is pressed.
+	CustomID string `json:"custom_id,omitempty"`
+}
+
+// Type returns the type of the component.
+func (Button) Type() ComponentType {
+	return ButtonComponent
+}
+
+// MarshalJSON marshals a Button along with its type
+func (b Button) MarshalJSON() ([]byte, error) {
+	type button Button
+
+	if b.Style == 0 {
+		b.Style = PrimaryButton
+	}
+
+	return json.Marshal(struct {
+		button
+		Type ComponentType `json:"type"`
+	}{button(b), b.Type()})
+}
+
+// SelectMenuOption is an option of a SelectMenu.
+type SelectMenuOption struct {
+	Label       string          `json:"label"`
+	Value       string          `json:"value"`
+	Description string          `json:"description,omitempty"`
+	Emoji       *ComponentEmoji `json:"emoji,omitempty"`
+
+	// Whether the option is selected by default.
+	Default bool `json:"default"`
+}
+
+// SelectMenu is a drop-down menu which sends an interaction with the
+// values of the selected options.
+type SelectMenu struct {
+	// The ID sent in the interaction when options are selected.
+	CustomID    string             `json:"custom_id"`
+	Placeholder string             `json:"placeholder,omitempty"`
+	Options     []SelectMenuOption `json:"options"`
+	Disabled    bool               `json:"disabled"`
+
+	// The minimum and maximum number of options which can be selected.
+	// MinValues is a pointer so that 0 can be sent, as Discord defaults
+	// to 1.
+	MinValues *int `json:"min_values,omitempty"`
+	MaxValues int  `json:"max_values,omitempty"`
+}
+
+// Type returns the type of the component.
+func (SelectMenu) Type() ComponentType {
+	return SelectMenuComponent
+}
+
+// MarshalJSON marshals a SelectMenu along with its type
+func (m SelectMenu) MarshalJSON() ([]byte, error) {
+	type selectMenu SelectMenu
+
+	return json.Marshal(struct {
+		selectMenu
+		Type ComponentType `json:"type"`
+	}{selectMenu(m), m.Type()})
+}
+
+// TextInputStyle is the style of a TextInput
+type TextInputStyle int
+
+// Block contains the valid known TextInputStyle values
+const (
+	TextInputShort     TextInputStyle = 1
+	TextInputParagraph TextInputStyle = 2
+)
+
+// TextInput is a text field in a modal.
+type TextInput struct {
+	CustomID    string         `json:"custom_id"`
+	Label       string         `json:"label"`
+	Style       TextInputStyle `json:"style"`
+	Placeholder string         `json:"placeholder,omitempty"`
+	Value       string         `json:"value,omitempty"`
+	Required    bool           `json:"required"`
+	MinLength   int            `json:"min_length,omitempty"`
+	MaxLength   int            `json:"max_length,omitempty"`
+}
+
+// Type returns the type of the component.
+func (TextInput) Type() ComponentType {
+	return TextInputComponent
+}
+
+// MarshalJSON marshals a TextInput along with its type
+func (t TextInput) MarshalJSON() ([]byte, error) {
+	type textInput TextInput
+
+	return json.Marshal(struct {
+		textInput
+		Type ComponentType `json:"type"`
+	}{textInput(t), t.Type()})
+}
+
+// UnknownComponent holds a component of a type not known to this package,
+// so that it is kept when a message is decoded.
+type UnknownComponent struct {
+	ComponentType ComponentType
+
+	// The JSON encoding of the component.
+	Raw json.RawMessage
+}
+
+// Type returns the type of the component.
+func (u UnknownComponent) Type() ComponentType {
+	return u.ComponentType
+}
+
+// MarshalJSON returns the JSON encoding the component was decoded from
+func (u UnknownComponent) MarshalJSON() ([]byte, error) {
+	return u.Raw, nil
+}
+
+// UnmarshalJSON keeps the JSON encoding of the component
+func (u *UnknownComponent) UnmarshalJSON(b []byte) error {
+	u.Raw = append(json.RawMessage(nil), b...)
+	return nil
+}
@@ -0,0 +1,102 @@
+package discordgo
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const componentsMessage = `{"id":"message","components":[{"type":1,"components":[
+	{"type":2,"label":"Next","style":1,"custom_id":"next","emoji":{"name":"➡️"}},
+	{"type":2,"label":"Docs","style":5,"url":"https://discord.com/developers/docs"}
+]},{"type":1,"components":[
+	{"type":3,"custom_id":"colour","placeholder":"Pick a colour","min_values":0,"max_values":2,"options":[{"label":"Red","value":"red","default":true},{"label":"Blue","value":"blue"}]}
+]},{"type":42,"custom_id":"future"}]}`
+
+func TestMessageComponentsUnmarshal(t *testing.T) {
+	var m Message
+	if err := json.Unmarshal([]byte(componentsMessage), &m); err != nil {
+		t.Fatalf("error unmarshaling message, %s", err)
+	}
+	if m.ID != "message" || len(m.Components) != 3 {
+		t.Fatalf("message decoded as %+v", m)
+	}
+
+	row, ok := m.Components[0].(*ActionsRow)
+	if !ok || len(row.Components) != 2 {
+		t.Fatalf("first component decoded as %#v, expected an action row with 2 buttons", m.Components[0])
+	}
+	next, ok := row.Components[0].(*Button)
+	if !ok || next.Label != "Next" || next.Style != PrimaryButton || next.CustomID != "next" || next.Emoji == nil || next.Emoji.Name != "➡️" {
+		t.Errorf("first button decoded as %#v", row.Components[0])
+	}
+	if docs, ok := row.Components[1].(*Button); !ok || docs.Style != LinkButton || docs.URL != "https://discord.com/developers/docs" {
+		t.Errorf("second button decoded as %#v", row.Components[1])
+	}
+
+	row, ok = m.Components[1].(*ActionsRow)
+	if !ok || len(row.Components) != 1 {
+		t.Fatalf("second component decoded as %#v, expected an action row with a select menu", m.Components[1])
+	}
+	menu, ok := row.Components[0].(*SelectMenu)
+	if !ok || menu.CustomID != "colour" || menu.Placeholder != "Pick a colour" || menu.MaxValues != 2 || len(menu.Options) != 2 {
+		t.Fatalf("select menu decoded as %#v", row.Components[0])
+	}
+	if menu.MinValues == nil || *menu.MinValues != 0 {
+		t.Errorf("select menu min values decoded as %v, expected 0", menu.MinValues)
+	}
+	if o := menu.Options[0]; o.Label != "Red" || o.Value != "red" || !o.Default {
+		t.Errorf("select menu option decoded as %+v", o)
+	}
+
+	unknown, ok := m.Components[2].(*UnknownComponent)
+	if !ok || unknown.Type() != 42 {
+		t.Fatalf("third component decoded as %#v, expected an unknown component", m.Components[2])
+	}
+	if b, _ := json.Marshal(unknown); string(b) != `{"type":42,"custom_id":"future"}` {
+		t.Errorf("unknown component was re-encoded as %s", b)
+	}
+}
+
+func TestMessageComponentsEvent(t *testing.T) {
+	c := decodeEvent(t, "MESSAGE_CREATE", componentsMessage).(*MessageCreate)
+	if c.ID != "message" || len(c.Components) != 3 {
+		t.Fatalf("MESSAGE_CREATE decoded as %+v", c.Message)
+	}
+	if _, ok := c.Components[0].(*ActionsRow); !ok {
+		t.Errorf("MESSAGE_CREATE components decoded as %#v", c.Components)
+	}
+}
+
+func TestMessageComponentsMarshal(t *testing.T) {
+	data := &MessageSend{
+		Content: "menu",
+		Components: []MessageComponent{
+			ActionsRow{Components: []MessageComponent{
+				Button{Label: "Next", CustomID: "next"},
+				SelectMenu{CustomID: "colour", Options: []SelectMenuOption{{Label: "Red", Value: "red"}}},
+			}},
+		},
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("error marshaling message, %s", err)
+	}
+
+	var v struct {
+		Components []struct {
+			Type       ComponentType            `json:"type"`
+			Components []map[string]interface{} `json:"components"`
+		} `json:"components"`
+	}
+	json.Unmarshal(b, &v)
+	if len(v.Components) != 1 || v.Components[0].Type != ActionsRowComponent || len(v.Components[0].Components) != 2 {
+		t.Fatalf("components were marshaled as %s", b)
+	}
+	if button := v.Components[0].Components[0]; button["type"] != 2.0 || button["style"] != 1.0 || button["custom_id"] != "next" {
+		t.Errorf("button was marshaled as %v, expected a primary button", button)
+	}
+	if menu := v.Components[0].Components[1]; menu["type"] != 3.0 || menu["custom_id"] != "colour" {
+		t.Errorf("select menu was marshaled as %v", menu)
+	}
+}
@@ -51,13 +51,17 @@ func New(args ...interface{}) (s *Session, err error) {
 	s = &Session{
 		State:                  NewState(),
 		Ratelimiter:            NewRatelimiter(),
+		etags:                  newETagCache(),
 		StateEnabled:           true,
 		Compress:               true,
 		ShouldReconnectOnError: true,
 		ShardID:                0,
 		ShardCount:             1,
 		MaxRestRetries:         3,
+		MaxETagCacheCount:      defaultETagCacheCount,
 		Client:                 &http.Client{Timeout: (20 * time.Second)},
+		DialTimeout:            20 * time.Second,
+		HandshakeTimeout:       20 * time.Second,
 		sequence:               new(int64),
 		LastHeartbeatAck:       time.Now().UTC(),
 	}
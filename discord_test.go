@@ -1,12 +1,16 @@
 package discordgo
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"runtime"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 //////////////////////////////////////////////////////////////////////////////
@@ -201,3 +205,151 @@ func TestRemoveHandler(t *testing.T) {
 		t.Fatalf("testHandler was not called once.")
 	}
 }
+
+func TestAddHandlers(t *testing.T) {
+
+	calls := map[string]int{}
+
+	d := Session{SyncEvents: true}
+	removeAll := d.AddHandlers(
+		func(s *Session, m *MessageCreate) { calls["create"]++ },
+		func(s *Session, m *MessageDelete) { calls["delete"]++ },
+		func(s *Session, m *MessageCreate) { calls["create2"]++ },
+	)
+
+	// A handler added separately is not removed with the others.
+	d.AddHandler(func(s *Session, m *MessageCreate) { calls["separate"]++ })
+
+	d.handleEvent(messageCreateEventType, &MessageCreate{})
+	d.handleEvent(messageDeleteEventType, &MessageDelete{})
+
+	removeAll()
+
+	d.handleEvent(messageCreateEventType, &MessageCreate{})
+	d.handleEvent(messageDeleteEventType, &MessageDelete{})
+
+	// The handlers added together are called once, as they were removed in between calls.
+	for _, name := range []string{"create", "delete", "create2"} {
+		if calls[name] != 1 {
+			t.Errorf("handler %s was called %d times, expected 1", name, calls[name])
+		}
+	}
+	if calls["separate"] != 2 {
+		t.Errorf("separately added handler was called %d times, expected 2", calls["separate"])
+	}
+}
+
+func TestAddRawHandler(t *testing.T) {
+
+	var raw []*Event
+	typedCalled := false
+
+	d, _ := New()
+	d.SyncEvents = true
+	remove := d.AddRawHandler(func(s *Session, e *Event) {
+		if typedCalled {
+			t.Error("raw handler was called after the typed handler")
+		}
+		raw = append(raw, e)
+	})
+	d.AddHandler(func(s *Session, m *MessageCreate) { typedCalled = true })
+
+	if _, err := d.onEvent(websocket.TextMessage, []byte(`{"op":0,"s":7,"t":"SOME_NEW_EVENT","d":{"a":1}}`)); err != nil {
+		t.Fatalf("onEvent returned error: %+v", err)
+	}
+	if _, err := d.onEvent(websocket.TextMessage, []byte(`{"op":0,"s":8,"t":"MESSAGE_CREATE","d":{"id":"message"}}`)); err != nil {
+		t.Fatalf("onEvent returned error: %+v", err)
+	}
+
+	if len(raw) != 2 {
+		t.Fatalf("raw handler was called %d times, expected 2", len(raw))
+	}
+	if raw[0].Operation != 0 || raw[0].Sequence != 7 || raw[0].Type != "SOME_NEW_EVENT" || string(raw[0].RawData) != `{"a":1}` {
+		t.Errorf("raw handler got %+v", raw[0])
+	}
+	if raw[1].Sequence != 8 || raw[1].Type != "MESSAGE_CREATE" {
+		t.Errorf("raw handler got %+v", raw[1])
+	}
+	if !typedCalled {
+		t.Error("typed handler was not called")
+	}
+
+	remove()
+	d.onEvent(websocket.TextMessage, []byte(`{"op":0,"s":9,"t":"SOME_NEW_EVENT","d":{}}`))
+	if len(raw) != 2 {
+		t.Errorf("raw handler was called after it was removed")
+	}
+}
+
+func TestOnMemberJoinDM(t *testing.T) {
+	var requests []string
+	var sent []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.URL.Path {
+		case "/api/v6/users/@me/channels":
+			var body struct {
+				RecipientID string `json:"recipient_id"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Write([]byte(`{"id":"dm-` + body.RecipientID + `","type":1}`))
+		case "/api/v6/channels/dm-closed/messages":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"code":50007,"message":"Cannot send messages to this user"}`))
+		default:
+			var m MessageSend
+			json.NewDecoder(r.Body).Decode(&m)
+			sent = append(sent, m.Content)
+			w.Write([]byte(`{"id":"message"}`))
+		}
+	})
+	s.SyncEvents = true
+
+	remove := s.OnMemberJoinDM("guild", func(m *Member) *MessageSend {
+		if m.User.ID == "quiet" {
+			return nil
+		}
+		return &MessageSend{Content: "Welcome, " + m.User.Username + "!"}
+	})
+
+	join := func(seq int, guildID, userID string, bot bool) {
+		data := fmt.Sprintf(`{"guild_id":"%s","user":{"id":"%s","username":"%s","bot":%t}}`, guildID, userID, userID, bot)
+		if _, err := s.onEvent(websocket.TextMessage, dispatch(seq, "GUILD_MEMBER_ADD", data)); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+	}
+
+	join(1, "guild", "newbie", false)
+	if len(sent) != 1 || sent[0] != "Welcome, newbie!" {
+		t.Fatalf("welcome messages sent were %q", sent)
+	}
+	if requests[0] != "POST /api/v6/users/@me/channels" || requests[1] != "POST /api/v6/channels/dm-newbie/messages" {
+		t.Errorf("requests were %q", requests)
+	}
+
+	// Members of other guilds, bots, members the builder skips and members
+	// who do not accept DMs are not welcomed.
+	requests = nil
+	join(2, "other", "stranger", false)
+	join(3, "guild", "robot", true)
+	join(4, "guild", "quiet", false)
+	if len(requests) != 0 {
+		t.Errorf("skipped members caused requests %q", requests)
+	}
+
+	join(5, "guild", "closed", false)
+	if len(requests) != 2 || requests[1] != "POST /api/v6/channels/dm-closed/messages" {
+		t.Errorf("requests were %q, expected a single attempt to DM the member", requests)
+	}
+	if len(sent) != 1 {
+		t.Errorf("welcome messages sent were %q", sent)
+	}
+
+	remove()
+	requests = nil
+	join(6, "guild", "late", false)
+	if len(requests) != 0 {
+		t.Errorf("member was welcomed after the handler was removed, requests %q", requests)
+	}
+}
@@ -38,6 +38,10 @@ var (
 	EndpointCDNIcons        = EndpointCDN + "icons/"
 	EndpointCDNSplashes     = EndpointCDN + "splashes/"
 	EndpointCDNChannelIcons = EndpointCDN + "channel-icons/"
+	EndpointCDNBanners      = EndpointCDN + "banners/"
+	EndpointCDNGuilds       = EndpointCDN + "guilds/"
+	EndpointCDNDiscovery    = EndpointCDN + "discovery-splashes/"
+	EndpointCDNRoleIcons    = EndpointCDN + "role-icons/"
 
 	EndpointAuth           = EndpointAPI + "auth/"
 	EndpointLogin          = EndpointAuth + "login"
@@ -48,7 +52,7 @@ var (
 	EndpointResetPassword  = EndpointAuth + "reset"
 	EndpointRegister       = EndpointAuth + "register"
 
-	EndpointVoice        = EndpointAPI + "/voice/"
+	EndpointVoice        = EndpointAPI + "voice/"
 	EndpointVoiceRegions = EndpointVoice + "regions"
 	EndpointVoiceIce     = EndpointVoice + "ice"
 
@@ -82,6 +86,7 @@ var (
 	EndpointGuildMember          = func(gID, uID string) string { return EndpointGuilds + gID + "/members/" + uID }
 	EndpointGuildMemberRole      = func(gID, uID, rID string) string { return EndpointGuilds + gID + "/members/" + uID + "/roles/" + rID }
 	EndpointGuildBans            = func(gID string) string { return EndpointGuilds + gID + "/bans" }
+	EndpointGuildBulkBan         = func(gID string) string { return EndpointGuilds + gID + "/bulk-ban" }
 	EndpointGuildBan             = func(gID, uID string) string { return EndpointGuilds + gID + "/bans/" + uID }
 	EndpointGuildIntegrations    = func(gID string) string { return EndpointGuilds + gID + "/integrations" }
 	EndpointGuildIntegration     = func(gID, iID string) string { return EndpointGuilds + gID + "/integrations/" + iID }
@@ -92,11 +97,27 @@ var (
 	EndpointGuildEmbed           = func(gID string) string { return EndpointGuilds + gID + "/embed" }
 	EndpointGuildPrune           = func(gID string) string { return EndpointGuilds + gID + "/prune" }
 	EndpointGuildIcon            = func(gID, hash string) string { return EndpointCDNIcons + gID + "/" + hash + ".png" }
+	EndpointGuildIconAnimated    = func(gID, hash string) string { return EndpointCDNIcons + gID + "/" + hash + ".gif" }
 	EndpointGuildSplash          = func(gID, hash string) string { return EndpointCDNSplashes + gID + "/" + hash + ".png" }
+	EndpointGuildDiscoverySplash = func(gID, hash string) string { return EndpointCDNDiscovery + gID + "/" + hash + ".png" }
+	EndpointGuildBanner          = func(gID, hash string) string { return EndpointCDNBanners + gID + "/" + hash + ".png" }
+	EndpointGuildBannerAnimated  = func(gID, hash string) string { return EndpointCDNBanners + gID + "/" + hash + ".gif" }
 	EndpointGuildWebhooks        = func(gID string) string { return EndpointGuilds + gID + "/webhooks" }
 	EndpointGuildAuditLogs       = func(gID string) string { return EndpointGuilds + gID + "/audit-logs" }
 	EndpointGuildEmojis          = func(gID string) string { return EndpointGuilds + gID + "/emojis" }
 	EndpointGuildEmoji           = func(gID, eID string) string { return EndpointGuilds + gID + "/emojis/" + eID }
+	EndpointGuildThreadsActive   = func(gID string) string { return EndpointGuilds + gID + "/threads/active" }
+	EndpointGuildOnboarding      = func(gID string) string { return EndpointGuilds + gID + "/onboarding" }
+	EndpointGuildAutoModeration  = func(gID string) string { return EndpointGuilds + gID + "/auto-moderation" }
+	EndpointGuildAutoModRules    = func(gID string) string { return EndpointGuildAutoModeration(gID) + "/rules" }
+	EndpointGuildAutoModRule     = func(gID, rID string) string { return EndpointGuildAutoModRules(gID) + "/" + rID }
+
+	EndpointGuildMemberAvatar = func(gID, uID, aID string) string {
+		return EndpointCDNGuilds + gID + "/users/" + uID + "/avatars/" + aID + ".png"
+	}
+	EndpointGuildMemberAvatarAnimated = func(gID, uID, aID string) string {
+		return EndpointCDNGuilds + gID + "/users/" + uID + "/avatars/" + aID + ".gif"
+	}
 
 	EndpointChannel                   = func(cID string) string { return EndpointChannels + cID }
 	EndpointChannelPermissions        = func(cID string) string { return EndpointChannels + cID + "/permissions" }
@@ -110,11 +131,33 @@ var (
 	EndpointChannelMessagesPins       = func(cID string) string { return EndpointChannel(cID) + "/pins" }
 	EndpointChannelMessagePin         = func(cID, mID string) string { return EndpointChannel(cID) + "/pins/" + mID }
 
+	EndpointChannelThreads                = func(cID string) string { return EndpointChannel(cID) + "/threads" }
+	EndpointChannelPublicArchivedThreads  = func(cID string) string { return EndpointChannelThreads(cID) + "/archived/public" }
+	EndpointChannelPrivateArchivedThreads = func(cID string) string { return EndpointChannelThreads(cID) + "/archived/private" }
+
 	EndpointGroupIcon = func(cID, hash string) string { return EndpointCDNChannelIcons + cID + "/" + hash + ".png" }
+	EndpointRoleIcon  = func(rID, hash string) string { return EndpointCDNRoleIcons + rID + "/" + hash + ".png" }
 
 	EndpointChannelWebhooks = func(cID string) string { return EndpointChannel(cID) + "/webhooks" }
 	EndpointWebhook         = func(wID string) string { return EndpointWebhooks + wID }
 	EndpointWebhookToken    = func(wID, token string) string { return EndpointWebhooks + wID + "/" + token }
+	EndpointWebhookMessage  = func(wID, token, mID string) string { return EndpointWebhookToken(wID, token) + "/messages/" + mID }
+
+	EndpointInteractions               = EndpointAPI + "interactions/"
+	EndpointInteraction                = func(iID, token string) string { return EndpointInteractions + iID + "/" + token }
+	EndpointInteractionResponse        = func(iID, token string) string { return EndpointInteraction(iID, token) + "/callback" }
+	EndpointInteractionResponseActions = func(aID, token string) string { return EndpointWebhookMessage(aID, token, "@original") }
+
+	EndpointApplicationGuildCommands = func(aID, gID string) string {
+		return EndpointAPI + "applications/" + aID + "/guilds/" + gID + "/commands"
+	}
+	EndpointApplicationCommandPermissions = func(aID, gID, cID string) string {
+		return EndpointApplicationGuildCommands(aID, gID) + "/" + cID + "/permissions"
+	}
+
+	EndpointApplicationEntitlements = func(aID string) string { return EndpointAPI + "applications/" + aID + "/entitlements" }
+	EndpointApplicationEntitlement  = func(aID, eID string) string { return EndpointApplicationEntitlements(aID) + "/" + eID }
+	EndpointApplicationSKUs         = func(aID string) string { return EndpointAPI + "applications/" + aID + "/skus" }
 
 	EndpointMessageReactionsAll = func(cID, mID string) string {
 		return EndpointChannelMessage(cID, mID) + "/reactions"
@@ -126,6 +169,14 @@ var (
 		return EndpointMessageReactions(cID, mID, eID) + "/" + uID
 	}
 
+	EndpointPoll = func(cID, mID string) string {
+		return EndpointChannels + cID + "/polls/" + mID
+	}
+	EndpointPollAnswerVoters = func(cID, mID string, aID int) string {
+		return EndpointPoll(cID, mID) + "/answers/" + strconv.Itoa(aID)
+	}
+	EndpointPollExpire = func(cID, mID string) string { return EndpointPoll(cID, mID) + "/expire" }
+
 	EndpointRelationships       = func() string { return EndpointUsers + "@me" + "/relationships" }
 	EndpointRelationship        = func(uID string) string { return EndpointRelationships() + "/" + uID }
 	EndpointRelationshipsMutual = func(uID string) string { return EndpointUsers + uID + "/relationships" }
@@ -140,6 +191,7 @@ var (
 	EndpointEmojiAnimated = func(eID string) string { return EndpointAPI + "emojis/" + eID + ".gif" }
 
 	EndpointOauth2          = EndpointAPI + "oauth2/"
+	EndpointOauth2Authorize = EndpointOauth2 + "authorize"
 	EndpointApplications    = EndpointOauth2 + "applications"
 	EndpointApplication     = func(aID string) string { return EndpointApplications + "/" + aID }
 	EndpointApplicationsBot = func(aID string) string { return EndpointApplications + "/" + aID + "/bot" }
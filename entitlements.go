@@ -0,0 +1,220 @@
+// Discordgo - Discord bindings for Go
+// Available at https://github.com/bwmarrin/discordgo
+
+// Copyright 2015-2016 Bruce Marriner <bruce@sqls.net>.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains code related to the monetization of applications, such
+// as SKUs and entitlements
+
+package discordgo
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// SKUType is the type of an SKU
+type SKUType int
+
+// Block contains the valid known SKUType values
+const (
+	SKUTypeDurable           SKUType = 2
+	SKUTypeConsumable        SKUType = 3
+	SKUTypeSubscription      SKUType = 5
+	SKUTypeSubscriptionGroup SKUType = 6
+)
+
+// SKUFlags are the flags of an SKU
+type SKUFlags int
+
+// Block contains the valid known SKUFlags values
+const (
+	SKUFlagAvailable         SKUFlags = 1 << 2
+	SKUFlagGuildSubscription SKUFlags = 1 << 7
+	SKUFlagUserSubscription  SKUFlags = 1 << 8
+)
+
+// An SKU is a premium offering of an application, such as a subscription or
+// a one-time purchase.
+type SKU struct {
+	ID            string   `json:"id"`
+	Type          SKUType  `json:"type"`
+	ApplicationID string   `json:"application_id"`
+	Name          string   `json:"name"`
+	Slug          string   `json:"slug"`
+	Flags         SKUFlags `json:"flags"`
+}
+
+// EntitlementType is the type of an Entitlement, i.e. how it was acquired
+type EntitlementType int
+
+// Block contains the valid known EntitlementType values
+const (
+	EntitlementTypePurchase                EntitlementType = 1
+	EntitlementTypePremiumSubscription     EntitlementType = 2
+	EntitlementTypeDeveloperGift           EntitlementType = 3
+	EntitlementTypeTestModePurchase        EntitlementType = 4
+	EntitlementTypeFreePurchase            EntitlementType = 5
+	EntitlementTypeUserGift                EntitlementType = 6
+	EntitlementTypePremiumPurchase         EntitlementType = 7
+	EntitlementTypeApplicationSubscription EntitlementType = 8
+)
+
+// An Entitlement grants a user or a guild access to an SKU of an
+// application.
+type Entitlement struct {
+	ID            string          `json:"id"`
+	SKUID         string          `json:"sku_id"`
+	ApplicationID string          `json:"application_id"`
+	Type          EntitlementType `json:"type"`
+
+	// The user or the guild granted the entitlement. Only one of these is
+	// set.
+	UserID  string `json:"user_id"`
+	GuildID string `json:"guild_id"`
+
+	// Whether the entitlement was deleted, and whether a consumable was
+	// consumed.
+	Deleted  bool `json:"deleted"`
+	Consumed bool `json:"consumed"`
+
+	// The validity of the entitlement, empty for entitlements which do not
+	// expire, such as test entitlements.
+	StartsAt Timestamp `json:"starts_at"`
+	EndsAt   Timestamp `json:"ends_at"`
+}
+
+// An EntitlementFilter selects the entitlements returned by Entitlements.
+// Its zero value selects every entitlement, on Discord's default page.
+type EntitlementFilter struct {
+	// Only entitlements of this user or guild are returned if set.
+	UserID  string
+	GuildID string
+
+	// Only entitlements for these SKUs are returned if set.
+	SKUIDs []string
+
+	// Only entitlements with a lower or higher ID are returned if set.
+	BeforeID string
+	AfterID  string
+
+	// The max number of entitlements to return (max 100). Discord's default if 0.
+	Limit int
+
+	// Whether to leave out the entitlements which have ended.
+	ExcludeEnded bool
+}
+
+// Entitlements returns the entitlements of an application.
+// appID  : The ID of the application.
+// filter : The entitlements to return, or nil for every entitlement.
+func (s *Session) Entitlements(appID string, filter *EntitlementFilter) (st []*Entitlement, err error) {
+	uri := EndpointApplicationEntitlements(appID)
+
+	if filter != nil {
+		v := url.Values{}
+		if filter.UserID != "" {
+			v.Set("user_id", filter.UserID)
+		}
+		if filter.GuildID != "" {
+			v.Set("guild_id", filter.GuildID)
+		}
+		if len(filter.SKUIDs) > 0 {
+			v.Set("sku_ids", strings.Join(filter.SKUIDs, ","))
+		}
+		if filter.BeforeID != "" {
+			v.Set("before", filter.BeforeID)
+		}
+		if filter.AfterID != "" {
+			v.Set("after", filter.AfterID)
+		}
+		if filter.Limit > 0 {
+			v.Set("limit", strconv.Itoa(filter.Limit))
+		}
+		if filter.ExcludeEnded {
+			v.Set("exclude_ended", "true")
+		}
+
+		if len(v) > 0 {
+			uri += "?" + v.Encode()
+		}
+	}
+
+	body, err := s.RequestWithBucketID("GET", uri, nil, EndpointApplicationEntitlements(appID))
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// SKUs returns the SKUs of an application.
+// appID : The ID of the application.
+func (s *Session) SKUs(appID string) (st []*SKU, err error) {
+	endpoint := EndpointApplicationSKUs(appID)
+
+	body, err := s.RequestWithBucketID("GET", endpoint, nil, endpoint)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// EntitlementConsume marks a consumable entitlement as consumed, once its
+// purchase has been fulfilled.
+// appID         : The ID of the application.
+// entitlementID : The ID of the entitlement.
+func (s *Session) EntitlementConsume(appID, entitlementID string) (err error) {
+	endpoint := EndpointApplicationEntitlement(appID, entitlementID) + "/consume"
+
+	_, err = s.RequestWithBucketID("POST", endpoint, nil, EndpointApplicationEntitlement(appID, "")+"/consume")
+	return
+}
+
+// EntitlementOwnerType is the type of the owner of a test entitlement
+type EntitlementOwnerType int
+
+// Block contains the valid known EntitlementOwnerType values
+const (
+	EntitlementOwnerTypeGuild EntitlementOwnerType = 1
+	EntitlementOwnerTypeUser  EntitlementOwnerType = 2
+)
+
+// An EntitlementTest holds the data of a test entitlement created with
+// EntitlementTestCreate.
+type EntitlementTest struct {
+	SKUID     string               `json:"sku_id"`
+	OwnerID   string               `json:"owner_id"`
+	OwnerType EntitlementOwnerType `json:"owner_type"`
+}
+
+// EntitlementTestCreate creates a test entitlement, granting an SKU to a user
+// or a guild without a purchase, for testing the premium features of an
+// application.
+// appID : The ID of the application.
+// data  : The SKU and the owner of the entitlement.
+func (s *Session) EntitlementTestCreate(appID string, data *EntitlementTest) (st *Entitlement, err error) {
+	endpoint := EndpointApplicationEntitlements(appID)
+
+	body, err := s.RequestWithBucketID("POST", endpoint, data, endpoint)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// EntitlementTestDelete deletes a test entitlement.
+// appID         : The ID of the application.
+// entitlementID : The ID of the test entitlement.
+func (s *Session) EntitlementTestDelete(appID, entitlementID string) (err error) {
+	_, err = s.RequestWithBucketID("DELETE", EndpointApplicationEntitlement(appID, entitlementID), nil, EndpointApplicationEntitlement(appID, ""))
+	return
+}
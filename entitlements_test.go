@@ -0,0 +1,132 @@
+package discordgo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestEntitlements(t *testing.T) {
+	var query url.Values
+	var path string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.Query()
+		w.Write([]byte(`[
+			{"id":"1","sku_id":"premium","application_id":"app","user_id":"user","type":8,"deleted":false,"starts_at":"2024-01-01T00:00:00+00:00","ends_at":"2024-02-01T00:00:00+00:00"},
+			{"id":"2","sku_id":"coins","application_id":"app","guild_id":"guild","type":4,"consumed":true,"starts_at":null,"ends_at":null}
+		]`))
+	})
+
+	entitlements, err := s.Entitlements("app", &EntitlementFilter{
+		UserID:       "user",
+		SKUIDs:       []string{"premium", "coins"},
+		ExcludeEnded: true,
+	})
+	if err != nil {
+		t.Fatalf("Entitlements returned error: %+v", err)
+	}
+
+	if path != "/api/v6/applications/app/entitlements" {
+		t.Errorf("entitlements were requested from %s", path)
+	}
+	if query.Get("user_id") != "user" || query.Get("sku_ids") != "premium,coins" || query.Get("exclude_ended") != "true" {
+		t.Errorf("entitlements were requested with query %v", query)
+	}
+	if _, ok := query["guild_id"]; ok {
+		t.Errorf("entitlements were requested with an empty filter field, query %v", query)
+	}
+
+	if len(entitlements) != 2 {
+		t.Fatalf("Entitlements returned %d entitlements, expected 2", len(entitlements))
+	}
+	e := entitlements[0]
+	if e.ID != "1" || e.SKUID != "premium" || e.UserID != "user" || e.Type != EntitlementTypeApplicationSubscription {
+		t.Errorf("entitlement decoded as %+v", e)
+	}
+	if ends, err := e.EndsAt.Parse(); err != nil || ends.Month() != 2 {
+		t.Errorf("entitlement ends at %v, %v", ends, err)
+	}
+	e = entitlements[1]
+	if e.GuildID != "guild" || e.Type != EntitlementTypeTestModePurchase || !e.Consumed || e.EndsAt != "" {
+		t.Errorf("test entitlement decoded as %+v", e)
+	}
+
+	if _, err = s.Entitlements("app", nil); err != nil {
+		t.Fatalf("Entitlements returned error: %+v", err)
+	}
+	if len(query) != 0 {
+		t.Errorf("entitlements were requested without a filter with query %v", query)
+	}
+}
+
+func TestSKUs(t *testing.T) {
+	var path string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`[{"id":"premium","type":5,"application_id":"app","name":"Premium","slug":"premium","flags":256}]`))
+	})
+
+	skus, err := s.SKUs("app")
+	if err != nil {
+		t.Fatalf("SKUs returned error: %+v", err)
+	}
+	if path != "/api/v6/applications/app/skus" {
+		t.Errorf("SKUs were requested from %s", path)
+	}
+	if len(skus) != 1 || skus[0].Type != SKUTypeSubscription || skus[0].Name != "Premium" || skus[0].Flags&SKUFlagUserSubscription == 0 {
+		t.Errorf("SKUs decoded as %+v", skus)
+	}
+}
+
+func TestEntitlementConsume(t *testing.T) {
+	var requests []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := s.EntitlementConsume("app", "entitlement"); err != nil {
+		t.Fatalf("EntitlementConsume returned error: %+v", err)
+	}
+	if len(requests) != 1 || requests[0] != "POST /api/v6/applications/app/entitlements/entitlement/consume" {
+		t.Errorf("requests were %q", requests)
+	}
+}
+
+func TestEntitlementTest(t *testing.T) {
+	var requests []string
+	var created map[string]interface{}
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&created)
+		w.Write([]byte(`{"id":"entitlement","sku_id":"premium","application_id":"app","user_id":"user","type":4}`))
+	})
+
+	e, err := s.EntitlementTestCreate("app", &EntitlementTest{SKUID: "premium", OwnerID: "user", OwnerType: EntitlementOwnerTypeUser})
+	if err != nil {
+		t.Fatalf("EntitlementTestCreate returned error: %+v", err)
+	}
+	if created["sku_id"] != "premium" || created["owner_id"] != "user" || created["owner_type"] != 2.0 {
+		t.Errorf("test entitlement was created with %v", created)
+	}
+	if e.ID != "entitlement" || e.Type != EntitlementTypeTestModePurchase {
+		t.Errorf("EntitlementTestCreate returned %+v", e)
+	}
+
+	if err = s.EntitlementTestDelete("app", e.ID); err != nil {
+		t.Fatalf("EntitlementTestDelete returned error: %+v", err)
+	}
+
+	expected := []string{
+		"POST /api/v6/applications/app/entitlements",
+		"DELETE /api/v6/applications/app/entitlements/entitlement",
+	}
+	if len(requests) != 2 || requests[0] != expected[0] || requests[1] != expected[1] {
+		t.Errorf("requests were %q, expected %q", requests, expected)
+	}
+}
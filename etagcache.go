@@ -0,0 +1,77 @@
+// Discordgo - Discord bindings for Go
+// Available at https://github.com/bwmarrin/discordgo
+
+// Copyright 2015-2016 Bruce Marriner <bruce@sqls.net>.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains the cache of REST responses by ETag
+
+package discordgo
+
+import (
+	"container/list"
+	"sync"
+)
+
+// defaultETagCacheCount is the number of URLs the ETag cache keeps if
+// Session.MaxETagCacheCount is not positive.
+const defaultETagCacheCount = 1000
+
+// etagCacheEntry is a cached response body and the ETag it was served with.
+type etagCacheEntry struct {
+	url  string
+	etag string
+	body []byte
+}
+
+// etagCache holds the last ETag and response body of GET requests by URL.
+// The least recently used entries are evicted first.
+type etagCache struct {
+	sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// newETagCache returns a new, empty etagCache.
+func newETagCache() *etagCache {
+	return &etagCache{
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns the cached entry for urlStr, or nil if there is none.
+func (c *etagCache) get(urlStr string) *etagCacheEntry {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.entries[urlStr]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*etagCacheEntry)
+}
+
+// set stores the response body served for urlStr with the given ETag,
+// keeping at most max entries, or defaultETagCacheCount if max is not
+// positive.
+func (c *etagCache) set(urlStr, etag string, body []byte, max int) {
+	if max <= 0 {
+		max = defaultETagCacheCount
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.entries[urlStr]; ok {
+		c.lru.Remove(e)
+	}
+	c.entries[urlStr] = c.lru.PushFront(&etagCacheEntry{url: urlStr, etag: etag, body: body})
+
+	for c.lru.Len() > max {
+		e := c.lru.Remove(c.lru.Back()).(*etagCacheEntry)
+		delete(c.entries, e.url)
+	}
+}
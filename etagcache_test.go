@@ -0,0 +1,93 @@
+package discordgo
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestETagCache(t *testing.T) {
+	requests := 0
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			if r.Header.Get("If-None-Match") != "" {
+				t.Error("first request sent If-None-Match")
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"id":"1","name":"cached"}`))
+			return
+		}
+
+		if r.Header.Get("If-None-Match") != `"v1"` {
+			t.Errorf("If-None-Match is %q, expected %q", r.Header.Get("If-None-Match"), `"v1"`)
+		}
+		w.WriteHeader(http.StatusNotModified)
+	})
+	s.CacheETags = true
+
+	for i := 0; i < 2; i++ {
+		g, err := s.Guild("1")
+		if err != nil {
+			t.Fatalf("Guild() returned error on request %d: %+v", i+1, err)
+		}
+		if g.Name != "cached" {
+			t.Errorf("Guild() name is %q on request %d, expected %q", g.Name, i+1, "cached")
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("made %d requests, expected 2", requests)
+	}
+}
+
+func TestETagCacheDisabled(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Error("request sent If-None-Match with CacheETags disabled")
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"id":"1"}`))
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Guild("1"); err != nil {
+			t.Fatalf("Guild() returned error: %+v", err)
+		}
+	}
+}
+
+func TestETagCacheEviction(t *testing.T) {
+	c := newETagCache()
+	c.set("a", `"a"`, nil, 2)
+	c.set("b", `"b"`, nil, 2)
+
+	// Reading a makes b the least recently used entry.
+	c.get("a")
+	c.set("c", `"c"`, nil, 2)
+
+	if c.get("b") != nil {
+		t.Error("least recently used entry was not evicted")
+	}
+	for _, url := range []string{"a", "c"} {
+		if e := c.get(url); e == nil || e.etag != `"`+url+`"` {
+			t.Errorf("entry %s is %+v, expected it to be kept", url, e)
+		}
+	}
+
+	c.set("a", `"a2"`, nil, 2)
+	if e := c.get("a"); e == nil || e.etag != `"a2"` || len(c.entries) != 2 {
+		t.Errorf("entry a is %+v with %d entries, expected it to be replaced", e, len(c.entries))
+	}
+}
+
+func TestETagCacheNonPositiveMax(t *testing.T) {
+	for _, max := range []int{0, -1} {
+		c := newETagCache()
+		c.set("a", `"a"`, nil, max)
+		c.set("b", `"b"`, nil, max)
+
+		if c.get("a") == nil || c.get("b") == nil {
+			t.Errorf("entries were evicted with a max of %d, expected the default to be used", max)
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package discordgo
 
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
 // EventHandler is an interface for Discord events.
 type EventHandler interface {
 	// Type returns the type of event this handler belongs to.
@@ -39,6 +45,24 @@ func (eh interfaceEventHandler) Handle(s *Session, i interface{}) {
 	eh(s, i)
 }
 
+// rawEventType is the event handler type for raw gateway dispatches.
+const rawEventType = "__RAW__"
+
+// rawEventHandler is an event handler for raw gateway dispatches.
+type rawEventHandler func(*Session, *Event)
+
+// Type returns the event type for raw gateway dispatches.
+func (eh rawEventHandler) Type() string {
+	return rawEventType
+}
+
+// Handle is the handler for a raw gateway dispatch.
+func (eh rawEventHandler) Handle(s *Session, i interface{}) {
+	if t, ok := i.(*Event); ok {
+		eh(s, t)
+	}
+}
+
 var registeredInterfaceProviders = map[string]EventInterfaceProvider{}
 
 // registerInterfaceProvider registers a provider so that DiscordGo can
@@ -142,6 +166,88 @@ func (s *Session) AddHandlerOnce(handler interface{}) func() {
 	return s.addEventHandlerOnce(eh)
 }
 
+// AddHandlers adds several event handlers at once, as AddHandler does.
+// The return value of this method is a function, that when called will remove
+// all of the event handlers.
+func (s *Session) AddHandlers(handlers ...interface{}) (removeAll func()) {
+	removers := make([]func(), 0, len(handlers))
+	for _, handler := range handlers {
+		removers = append(removers, s.AddHandler(handler))
+	}
+
+	return func() {
+		for _, remove := range removers {
+			remove()
+		}
+	}
+}
+
+// OnMemberJoinDM adds a handler which sends a direct message to the members
+// joining a guild, e.g. to welcome them. Bots and the members who do not
+// accept direct messages from the guild are skipped.
+// The return value of this method is a function, that when called will remove
+// the handler.
+// guildID : The ID of the Guild.
+// build   : Returns the message sent to a new member, none is sent if nil.
+func (s *Session) OnMemberJoinDM(guildID string, build func(*Member) *MessageSend) func() {
+	return s.AddHandler(func(s *Session, m *GuildMemberAdd) {
+		if m.Member == nil || m.GuildID != guildID || m.User == nil || m.User.Bot {
+			return
+		}
+
+		data := build(m.Member)
+		if data == nil {
+			return
+		}
+
+		channel, err := s.UserChannelCreate(m.User.ID)
+		if err != nil {
+			s.log(LogError, "error creating DM channel with member %s, %s", m.User.ID, err)
+			return
+		}
+
+		_, err = s.ChannelMessageSendComplex(channel.ID, data)
+		if restErr, ok := err.(*RESTError); ok && restErr.Message != nil && restErr.Message.Code == ErrCodeCannotSendMessagesToThisUser {
+			s.log(LogInformational, "member %s does not accept direct messages, skipping", m.User.ID)
+			return
+		}
+		if err != nil {
+			s.log(LogError, "error sending DM to member %s, %s", m.User.ID, err)
+		}
+	})
+}
+
+// AddRawHandler adds a handler that is called for every gateway dispatch
+// with the undecoded event, before it is decoded and passed to any typed
+// handlers. Only the Operation, Sequence, Type and RawData fields of the
+// event are set.
+// This is useful for debugging, or for handling events which the library
+// does not have a type for yet.
+// Raw handlers are called synchronously from the gateway read loop, so they
+// should not block.
+//
+// The return value of this method is a function, that when called will remove the
+// raw handler.
+func (s *Session) AddRawHandler(handler func(*Session, *Event)) func() {
+	return s.addEventHandler(rawEventHandler(handler))
+}
+
+// handleRaw calls the raw handlers for a gateway dispatch.
+func (s *Session) handleRaw(e *Event) {
+	if !s.active.start() {
+		return
+	}
+	defer s.active.done()
+
+	s.handlersMu.RLock()
+	defer s.handlersMu.RUnlock()
+
+	for _, eh := range s.handlers[rawEventType] {
+		s.active.add()
+		s.runHandler(eh.eventHandler, e)
+	}
+}
+
 // removeEventHandler instance removes an event handler instance.
 func (s *Session) removeEventHandlerInstance(t string, ehi *eventHandlerInstance) {
 	s.handlersMu.Lock()
@@ -162,22 +268,31 @@ func (s *Session) removeEventHandlerInstance(t string, ehi *eventHandlerInstance
 	}
 }
 
+// runHandler calls an event handler, keeping track of it while it runs.
+func (s *Session) runHandler(eh EventHandler, i interface{}) {
+	defer s.active.done()
+	eh.Handle(s, i)
+}
+
 // Handles calling permanent and once handlers for an event type.
-func (s *Session) handle(t string, i interface{}) {
+// If sync is set, the handlers are called one after the other before it returns.
+func (s *Session) handle(t string, i interface{}, sync bool) {
 	for _, eh := range s.handlers[t] {
-		if s.SyncEvents {
-			eh.eventHandler.Handle(s, i)
+		s.active.add()
+		if sync {
+			s.runHandler(eh.eventHandler, i)
 		} else {
-			go eh.eventHandler.Handle(s, i)
+			go s.runHandler(eh.eventHandler, i)
 		}
 	}
 
 	if len(s.onceHandlers[t]) > 0 {
 		for _, eh := range s.onceHandlers[t] {
-			if s.SyncEvents {
-				eh.eventHandler.Handle(s, i)
+			s.active.add()
+			if sync {
+				s.runHandler(eh.eventHandler, i)
 			} else {
-				go eh.eventHandler.Handle(s, i)
+				go s.runHandler(eh.eventHandler, i)
 			}
 		}
 		s.onceHandlers[t] = nil
@@ -187,17 +302,365 @@ func (s *Session) handle(t string, i interface{}) {
 // Handles an event type by calling internal methods, firing handlers and firing the
 // interface{} event.
 func (s *Session) handleEvent(t string, i interface{}) {
+	s.dispatchEvent(t, i, s.SyncEvents || s.serializedEvent(t))
+}
+
+// hydratedReaction returns the reaction of the event i, and the message of
+// the event to set, if HydrateReactions is set and i is a reaction event.
+func (s *Session) hydratedReaction(i interface{}) (r *MessageReaction, m **Message) {
+	if !s.HydrateReactions {
+		return
+	}
+
+	switch e := i.(type) {
+	case *MessageReactionAdd:
+		r, m = e.MessageReaction, &e.Message
+	case *MessageReactionRemove:
+		r, m = e.MessageReaction, &e.Message
+	}
+	return
+}
+
+// hydrateReaction sets the message m of the reaction r, reading it from
+// State first. m is set to a copy, as the message in State is updated by
+// the next events while the handlers run.
+func (s *Session) hydrateReaction(r *MessageReaction, m **Message) {
+	message, err := s.ChannelMessageOrFetch(r.ChannelID, r.MessageID)
+	if err != nil {
+		s.log(LogWarning, "error getting message %s of reaction, %s", r.MessageID, err)
+		return
+	}
+
+	if s.State != nil {
+		s.State.RLock()
+		defer s.State.RUnlock()
+	}
+	copied := *message
+	*m = &copied
+}
+
+// serializedEvent returns whether the handlers of the event type t must
+// return before the next event is handled.
+func (s *Session) serializedEvent(t string) bool {
+	return s.SerializeReady && t == readyEventType
+}
+
+// dispatchEvent is handleEvent, calling the handlers synchronously if sync is set.
+func (s *Session) dispatchEvent(t string, i interface{}, sync bool) {
+	s.dispatch(t, i, sync, true)
+}
+
+// dispatchHandlers is dispatchEvent for an event which was already
+// dispatched internally, only calling its handlers.
+func (s *Session) dispatchHandlers(t string, i interface{}, sync bool) {
+	s.dispatch(t, i, sync, false)
+}
+
+// dispatch calls the handlers of the event i, dispatching it internally
+// first if internal is set.
+func (s *Session) dispatch(t string, i interface{}, sync, internal bool) {
+	// The dispatch counts as running, so that Shutdown also waits for
+	// the handlers it is about to start.
+	if !s.active.start() {
+		return
+	}
+
+	// All events are dispatched internally first, in the order they are
+	// received, even if their handlers wait for a message to be fetched.
+	if internal {
+		s.onInterface(i)
+	}
+
+	if r, m := s.hydratedReaction(i); r != nil {
+		// Unless the handlers are called synchronously, the message is
+		// fetched in a goroutine of its own, so that it does not delay
+		// the next events.
+		if !sync {
+			go func() {
+				defer s.active.done()
+				s.hydrateReaction(r, m)
+				s.callHandlers(t, i, sync)
+			}()
+			return
+		}
+		s.hydrateReaction(r, m)
+	}
+
+	defer s.active.done()
+	s.callHandlers(t, i, sync)
+}
+
+// callHandlers calls the handlers of the event i.
+func (s *Session) callHandlers(t string, i interface{}, sync bool) {
 	s.handlersMu.RLock()
 	defer s.handlersMu.RUnlock()
 
-	// All events are dispatched internally first.
+	// Events are dispatched to anyone handling interface{} events.
+	s.handle(interfaceEventType, i, sync)
+
+	// Then to any typed handlers.
+	s.handle(t, i, sync)
+}
+
+// queuedEvent is an event waiting in the event queue of a session.
+type queuedEvent struct {
+	t string
+	i interface{}
+}
+
+// eventQueue holds the gateway events waiting for an event handler worker.
+// The workers are started by the first queued event, and stopped by
+// Shutdown.
+type eventQueue struct {
+	sync.Mutex
+	events chan queuedEvent
+
+	// the queue of each worker, used instead of events with OrderedEvents
+	workers []chan queuedEvent
+
+	// closed to stop the workers, nil if they are not running
+	stop    chan struct{}
+	running *sync.WaitGroup
+}
+
+// queueEvent handles a gateway event. If EventHandlerWorkers is set, the
+// event is queued for the workers instead of being handled by the caller.
+// It blocks while the queue is full.
+func (s *Session) queueEvent(t string, i interface{}) {
+	if s.EventHandlerWorkers <= 0 || s.serializedEvent(t) {
+		s.handleEvent(t, i)
+		return
+	}
+
+	// Queued events count as running, so that Shutdown waits for them.
+	if !s.active.start() {
+		return
+	}
+
+	// Events are dispatched internally by the caller, so that State is
+	// updated in the order the events are received, and e.g. the session
+	// ID and State.User are set by the time Open returns. Only their
+	// handlers are queued.
 	s.onInterface(i)
 
-	// Then they are dispatched to anyone handling interface{} events.
-	s.handle(interfaceEventType, i)
+	events, stop := s.eventWorkerQueue(i)
+	select {
+	case events <- queuedEvent{t, i}:
+	case <-stop:
+		s.active.done()
+	}
+}
+
+// eventWorkerQueue returns the queue of the worker handling the event i,
+// starting the workers if they are not running, and the channel closed
+// when they are stopped.
+func (s *Session) eventWorkerQueue(i interface{}) (events chan queuedEvent, stop chan struct{}) {
+	q := &s.queue
+	q.Lock()
+	defer q.Unlock()
+
+	if q.stop == nil {
+		q.stop = make(chan struct{})
+		q.running = &sync.WaitGroup{}
+		q.running.Add(s.EventHandlerWorkers)
+
+		if s.OrderedEvents {
+			for n := 0; n < s.EventHandlerWorkers; n++ {
+				events := make(chan queuedEvent, s.EventQueueSize)
+				q.workers = append(q.workers, events)
+				go s.eventWorker(events, q.stop, q.running)
+			}
+		} else {
+			q.events = make(chan queuedEvent, s.EventQueueSize)
+			for n := 0; n < s.EventHandlerWorkers; n++ {
+				go s.eventWorker(q.events, q.stop, q.running)
+			}
+		}
+	}
 
-	// Finally they are dispatched to any typed handlers.
-	s.handle(t, i)
+	if q.workers != nil {
+		return q.workers[eventWorkerIndex(eventKey(i), len(q.workers))], q.stop
+	}
+	return q.events, q.stop
+}
+
+// eventWorker handles queued events, calling their handlers one after the
+// other, until stop is closed.
+func (s *Session) eventWorker(events chan queuedEvent, stop chan struct{}, running *sync.WaitGroup) {
+	defer running.Done()
+
+	for {
+		select {
+		case e := <-events:
+			s.dispatchHandlers(e.t, e.i, true)
+			s.active.done()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// stopEventWorkers stops the event handler workers, and waits for them to
+// return until ctx expires, unless it is called by a worker, which can not
+// return before. Events still queued are dropped.
+func (s *Session) stopEventWorkers(ctx context.Context, worker bool) {
+	q := &s.queue
+	q.Lock()
+	stop, running, queues := q.stop, q.running, q.workers
+	if q.events != nil {
+		queues = append(queues, q.events)
+	}
+	q.stop, q.running, q.events, q.workers = nil, nil, nil, nil
+	q.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+
+	if !worker {
+		stopped := make(chan struct{})
+		go func() {
+			running.Wait()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			s.log(LogWarning, "shutting down before event handler workers stopped, %s", ctx.Err())
+		}
+	}
+
+	for _, events := range queues {
+		for dropped := true; dropped; {
+			select {
+			case <-events:
+				s.active.done()
+			default:
+				dropped = false
+			}
+		}
+	}
+}
+
+// eventWorkerIndex returns the worker which handles the events with key.
+func eventWorkerIndex(key string, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
+
+// eventKey returns the ID of the guild an event happened in, or of the
+// channel for the events of channels outside of guilds, which OrderedEvents
+// keeps events in order by. Other events have an empty key.
+func eventKey(i interface{}) string {
+	switch t := i.(type) {
+	case *Event:
+		return eventKey(t.Struct)
+	case *MessageCreate:
+		if t.Message != nil {
+			return channelEventKey(t.GuildID, t.ChannelID)
+		}
+	case *MessageUpdate:
+		if t.Message != nil {
+			return channelEventKey(t.GuildID, t.ChannelID)
+		}
+	case *MessageDelete:
+		if t.Message != nil {
+			return channelEventKey(t.GuildID, t.ChannelID)
+		}
+	case *MessageDeleteBulk:
+		return channelEventKey(t.GuildID, t.ChannelID)
+	case *MessageReactionAdd:
+		if t.MessageReaction != nil {
+			return channelEventKey(t.GuildID, t.ChannelID)
+		}
+	case *MessageReactionRemove:
+		if t.MessageReaction != nil {
+			return channelEventKey(t.GuildID, t.ChannelID)
+		}
+	case *MessageReactionRemoveAll:
+		if t.MessageReaction != nil {
+			return channelEventKey(t.GuildID, t.ChannelID)
+		}
+	case *MessageReactionRemoveEmoji:
+		return channelEventKey(t.GuildID, t.ChannelID)
+	case *TypingStart:
+		return channelEventKey(t.GuildID, t.ChannelID)
+	case *ChannelPinsUpdate:
+		return channelEventKey(t.GuildID, t.ChannelID)
+	case *ChannelCreate:
+		if t.Channel != nil {
+			return channelEventKey(t.GuildID, t.ID)
+		}
+	case *ChannelUpdate:
+		if t.Channel != nil {
+			return channelEventKey(t.GuildID, t.ID)
+		}
+	case *ChannelDelete:
+		if t.Channel != nil {
+			return channelEventKey(t.GuildID, t.ID)
+		}
+	case *GuildCreate:
+		if t.Guild != nil {
+			return t.ID
+		}
+	case *GuildUpdate:
+		if t.Guild != nil {
+			return t.ID
+		}
+	case *GuildDelete:
+		if t.Guild != nil {
+			return t.ID
+		}
+	case *GuildMemberAdd:
+		if t.Member != nil {
+			return t.GuildID
+		}
+	case *GuildMemberUpdate:
+		if t.Member != nil {
+			return t.GuildID
+		}
+	case *GuildMemberRemove:
+		if t.Member != nil {
+			return t.GuildID
+		}
+	case *GuildRoleCreate:
+		if t.GuildRole != nil {
+			return t.GuildID
+		}
+	case *GuildRoleUpdate:
+		if t.GuildRole != nil {
+			return t.GuildID
+		}
+	case *GuildRoleDelete:
+		return t.GuildID
+	case *GuildBanAdd:
+		return t.GuildID
+	case *GuildBanRemove:
+		return t.GuildID
+	case *GuildEmojisUpdate:
+		return t.GuildID
+	case *GuildMembersChunk:
+		return t.GuildID
+	case *PresenceUpdate:
+		return t.GuildID
+	case *VoiceStateUpdate:
+		if t.VoiceState != nil {
+			return t.GuildID
+		}
+	}
+	return ""
+}
+
+// channelEventKey returns the key of the events in a channel: the ID of its
+// guild, so they are kept in order with the other events of the guild, or
+// of the channel itself if it is not in a guild.
+func channelEventKey(guildID, channelID string) string {
+	if guildID != "" {
+		return guildID
+	}
+	return channelID
 }
 
 // setGuildIds will set the GuildID on all the members of a guild.
@@ -224,8 +687,28 @@ func (s *Session) onInterface(i interface{}) {
 			setGuildIds(g)
 		}
 		s.onReady(t)
+		if s.ChunkGuildsOnStartup {
+			ids := make([]string, len(t.Guilds))
+			for i, g := range t.Guilds {
+				ids[i] = g.ID
+			}
+			// The dispatch of the event is still running, so Shutdown
+			// waits for the members to be requested too.
+			s.active.add()
+			go func() {
+				defer s.active.done()
+				s.chunkGuilds(ids)
+			}()
+		}
 	case *GuildCreate:
 		setGuildIds(t.Guild)
+		if !s.guildAllowed(t.ID) {
+			s.active.add()
+			go func() {
+				defer s.active.done()
+				s.leaveGuild(t.ID)
+			}()
+		}
 	case *GuildUpdate:
 		setGuildIds(t.Guild)
 	case *VoiceServerUpdate:
@@ -239,6 +722,30 @@ func (s *Session) onInterface(i interface{}) {
 	}
 }
 
+// guildAllowed returns whether the session may stay in a guild, according to
+// GuildAllowlist.
+func (s *Session) guildAllowed(guildID string) bool {
+	if len(s.GuildAllowlist) == 0 {
+		return true
+	}
+
+	for _, id := range s.GuildAllowlist {
+		if id == guildID {
+			return true
+		}
+	}
+	return false
+}
+
+// leaveGuild leaves a guild which is not on GuildAllowlist.
+func (s *Session) leaveGuild(guildID string) {
+	s.log(LogInformational, "leaving guild %s, which is not on the allowlist", guildID)
+
+	if err := s.GuildLeave(guildID); err != nil {
+		s.log(LogError, "error leaving guild %s, %s", guildID, err)
+	}
+}
+
 // onReady handles the ready event.
 func (s *Session) onReady(r *Ready) {
 
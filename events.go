@@ -15,7 +15,12 @@ type Connect struct{}
 
 // Disconnect is the data for a Disconnect event.
 // This is a sythetic event and is not dispatched by Discord.
-type Disconnect struct{}
+type Disconnect struct {
+	// The code the gateway closed the connection with, such as
+	// GatewayCloseSessionTimedOut, 1006 if the connection was lost, or 0
+	// if the session closed it, as with Close or after missed heartbeats.
+	Code int
+}
 
 // RateLimit is the data for a RateLimit event.
 // This is a sythetic event and is not dispatched by Discord.
@@ -73,6 +78,53 @@ type ChannelPinsUpdate struct {
 	GuildID          string `json:"guild_id,omitempty"`
 }
 
+// ThreadCreate is the data for a ThreadCreate event.
+type ThreadCreate struct {
+	*Channel
+	NewlyCreated bool `json:"newly_created"`
+}
+
+// ThreadUpdate is the data for a ThreadUpdate event.
+type ThreadUpdate struct {
+	*Channel
+}
+
+// ThreadDelete is the data for a ThreadDelete event.
+type ThreadDelete struct {
+	*Channel
+}
+
+// ThreadListSync is the data for a ThreadListSync event.
+type ThreadListSync struct {
+	GuildID string `json:"guild_id"`
+
+	// The parent channels whose threads are being synced. If empty, the
+	// threads of the whole guild are synced.
+	ChannelIDs []string        `json:"channel_ids"`
+	Threads    []*Channel      `json:"threads"`
+	Members    []*ThreadMember `json:"members"`
+}
+
+// ThreadMemberUpdate is the data for a ThreadMemberUpdate event.
+type ThreadMemberUpdate struct {
+	*ThreadMember
+	GuildID string `json:"guild_id"`
+}
+
+// ThreadMembersUpdate is the data for a ThreadMembersUpdate event.
+type ThreadMembersUpdate struct {
+	ID             string              `json:"id"`
+	GuildID        string              `json:"guild_id"`
+	MemberCount    int                 `json:"member_count"`
+	AddedMembers   []AddedThreadMember `json:"added_members"`
+	RemovedMembers []string            `json:"removed_member_ids"`
+}
+
+// InteractionCreate is the data for an InteractionCreate event.
+type InteractionCreate struct {
+	*Interaction
+}
+
 // GuildCreate is the data for a GuildCreate event.
 type GuildCreate struct {
 	*Guild
@@ -143,6 +195,68 @@ type GuildMembersChunk struct {
 	Members []*Member `json:"members"`
 }
 
+// GuildScheduledEventUserAdd is the data for a GuildScheduledEventUserAdd event.
+type GuildScheduledEventUserAdd struct {
+	GuildScheduledEventID string `json:"guild_scheduled_event_id"`
+	UserID                string `json:"user_id"`
+	GuildID               string `json:"guild_id"`
+}
+
+// GuildScheduledEventUserRemove is the data for a GuildScheduledEventUserRemove event.
+type GuildScheduledEventUserRemove struct {
+	GuildScheduledEventID string `json:"guild_scheduled_event_id"`
+	UserID                string `json:"user_id"`
+	GuildID               string `json:"guild_id"`
+}
+
+// AutoModerationRuleCreate is the data for an AutoModerationRuleCreate event.
+type AutoModerationRuleCreate struct {
+	*AutoModerationRule
+}
+
+// AutoModerationRuleUpdate is the data for an AutoModerationRuleUpdate event.
+type AutoModerationRuleUpdate struct {
+	*AutoModerationRule
+}
+
+// AutoModerationRuleDelete is the data for an AutoModerationRuleDelete event.
+type AutoModerationRuleDelete struct {
+	*AutoModerationRule
+}
+
+// AutoModerationActionExecution is the data for an AutoModerationActionExecution event.
+type AutoModerationActionExecution struct {
+	GuildID         string                        `json:"guild_id"`
+	Action          AutoModerationAction          `json:"action"`
+	RuleID          string                        `json:"rule_id"`
+	RuleTriggerType AutoModerationRuleTriggerType `json:"rule_trigger_type"`
+	UserID          string                        `json:"user_id"`
+	ChannelID       string                        `json:"channel_id"`
+	MessageID       string                        `json:"message_id"`
+
+	// The ID of the alert sent by a send alert action.
+	AlertSystemMessageID string `json:"alert_system_message_id"`
+
+	// The content of the message, and the part of it which triggered the rule.
+	Content        string `json:"content"`
+	MatchedKeyword string `json:"matched_keyword"`
+	MatchedContent string `json:"matched_content"`
+}
+
+// InviteCreate is the data for an InviteCreate event.
+type InviteCreate struct {
+	*Invite
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+}
+
+// InviteDelete is the data for an InviteDelete event.
+type InviteDelete struct {
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+	Code      string `json:"code"`
+}
+
 // GuildIntegrationsUpdate is the data for a GuildIntegrationsUpdate event.
 type GuildIntegrationsUpdate struct {
 	GuildID string `json:"guild_id"`
@@ -159,24 +273,48 @@ type MessageCreate struct {
 	*Message
 }
 
+// UnmarshalJSON is a helper function to unmarshal MessageCreate, as the
+// UnmarshalJSON of the embedded Message would otherwise be used.
+func (m *MessageCreate) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &m.Message)
+}
+
 // MessageUpdate is the data for a MessageUpdate event.
 type MessageUpdate struct {
 	*Message
 }
 
+// UnmarshalJSON is a helper function to unmarshal MessageUpdate, as the
+// UnmarshalJSON of the embedded Message would otherwise be used.
+func (m *MessageUpdate) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &m.Message)
+}
+
 // MessageDelete is the data for a MessageDelete event.
 type MessageDelete struct {
 	*Message
 }
 
+// UnmarshalJSON is a helper function to unmarshal MessageDelete, as the
+// UnmarshalJSON of the embedded Message would otherwise be used.
+func (m *MessageDelete) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &m.Message)
+}
+
 // MessageReactionAdd is the data for a MessageReactionAdd event.
 type MessageReactionAdd struct {
 	*MessageReaction
+
+	// The message reacted to, set only if Session.HydrateReactions is set.
+	Message *Message `json:"-"`
 }
 
 // MessageReactionRemove is the data for a MessageReactionRemove event.
 type MessageReactionRemove struct {
 	*MessageReaction
+
+	// The message reacted to, set only if Session.HydrateReactions is set.
+	Message *Message `json:"-"`
 }
 
 // MessageReactionRemoveAll is the data for a MessageReactionRemoveAll event.
@@ -184,6 +322,14 @@ type MessageReactionRemoveAll struct {
 	*MessageReaction
 }
 
+// MessageReactionRemoveEmoji is the data for a MessageReactionRemoveEmoji event.
+type MessageReactionRemoveEmoji struct {
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id,omitempty"`
+	MessageID string `json:"message_id"`
+	Emoji     *Emoji `json:"emoji"`
+}
+
 // PresencesReplace is the data for a PresencesReplace event.
 type PresencesReplace []*Presence
 
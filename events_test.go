@@ -0,0 +1,117 @@
+package discordgo
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// decodeEvent decodes data into the struct registered for the event t, as
+// onEvent does for dispatches from the gateway.
+func decodeEvent(t *testing.T, eventType, data string) interface{} {
+	eh, ok := registeredInterfaceProviders[eventType]
+	if !ok {
+		t.Fatalf("no struct is registered for %s", eventType)
+	}
+
+	e := eh.New()
+	if err := json.Unmarshal([]byte(data), e); err != nil {
+		t.Fatalf("error decoding %s, %s", eventType, err)
+	}
+	return e
+}
+
+func TestThreadEvents(t *testing.T) {
+	c := decodeEvent(t, "THREAD_CREATE", `{"id":"thread","type":11,"parent_id":"channel","newly_created":true,"thread_metadata":{"archived":false}}`).(*ThreadCreate)
+	if c.ID != "thread" || c.ParentID != "channel" || c.Type != ChannelTypeGuildPublicThread || !c.NewlyCreated || c.ThreadMetadata == nil {
+		t.Errorf("THREAD_CREATE decoded as %+v", c.Channel)
+	}
+
+	u := decodeEvent(t, "THREAD_UPDATE", `{"id":"thread","name":"renamed"}`).(*ThreadUpdate)
+	if u.ID != "thread" || u.Name != "renamed" {
+		t.Errorf("THREAD_UPDATE decoded as %+v", u.Channel)
+	}
+
+	d := decodeEvent(t, "THREAD_DELETE", `{"id":"thread","guild_id":"guild","parent_id":"channel","type":11}`).(*ThreadDelete)
+	if d.ID != "thread" || d.GuildID != "guild" {
+		t.Errorf("THREAD_DELETE decoded as %+v", d.Channel)
+	}
+
+	l := decodeEvent(t, "THREAD_LIST_SYNC", `{"guild_id":"guild","channel_ids":["channel"],"threads":[{"id":"thread"}],"members":[{"id":"thread","user_id":"user"}]}`).(*ThreadListSync)
+	if l.GuildID != "guild" || len(l.ChannelIDs) != 1 || len(l.Threads) != 1 || len(l.Members) != 1 || l.Members[0].UserID != "user" {
+		t.Errorf("THREAD_LIST_SYNC decoded as %+v", l)
+	}
+
+	mu := decodeEvent(t, "THREAD_MEMBER_UPDATE", `{"id":"thread","user_id":"user","guild_id":"guild","flags":1}`).(*ThreadMemberUpdate)
+	if mu.ID != "thread" || mu.UserID != "user" || mu.GuildID != "guild" {
+		t.Errorf("THREAD_MEMBER_UPDATE decoded as %+v", mu)
+	}
+
+	ms := decodeEvent(t, "THREAD_MEMBERS_UPDATE", `{"id":"thread","guild_id":"guild","member_count":2,"added_members":[{"id":"thread","user_id":"user","member":{"nick":"nick"}}],"removed_member_ids":["gone"]}`).(*ThreadMembersUpdate)
+	if ms.ID != "thread" || ms.MemberCount != 2 || len(ms.AddedMembers) != 1 || ms.AddedMembers[0].UserID != "user" || ms.AddedMembers[0].Member.Nick != "nick" {
+		t.Errorf("THREAD_MEMBERS_UPDATE decoded as %+v", ms)
+	}
+	if len(ms.RemovedMembers) != 1 || ms.RemovedMembers[0] != "gone" {
+		t.Errorf("THREAD_MEMBERS_UPDATE removed members decoded as %v", ms.RemovedMembers)
+	}
+}
+
+func TestAutoModerationEvents(t *testing.T) {
+	rule := `{"id":"rule","guild_id":"guild","name":"no spam","trigger_type":1,"enabled":true}`
+	for _, eventType := range []string{"AUTO_MODERATION_RULE_CREATE", "AUTO_MODERATION_RULE_UPDATE", "AUTO_MODERATION_RULE_DELETE"} {
+		var r *AutoModerationRule
+		switch e := decodeEvent(t, eventType, rule).(type) {
+		case *AutoModerationRuleCreate:
+			r = e.AutoModerationRule
+		case *AutoModerationRuleUpdate:
+			r = e.AutoModerationRule
+		case *AutoModerationRuleDelete:
+			r = e.AutoModerationRule
+		default:
+			t.Fatalf("%s decoded into %T", eventType, e)
+		}
+		if r.ID != "rule" || r.GuildID != "guild" || r.Name != "no spam" {
+			t.Errorf("%s decoded as %+v", eventType, r)
+		}
+	}
+
+	a := decodeEvent(t, "AUTO_MODERATION_ACTION_EXECUTION", `{"guild_id":"guild","action":{"type":1},"rule_id":"rule","rule_trigger_type":1,"user_id":"user","channel_id":"channel","message_id":"message","alert_system_message_id":"alert","content":"buy now","matched_keyword":"buy*","matched_content":"buy"}`).(*AutoModerationActionExecution)
+	if a.GuildID != "guild" || a.RuleID != "rule" || a.UserID != "user" || a.ChannelID != "channel" || a.MessageID != "message" || a.AlertSystemMessageID != "alert" {
+		t.Errorf("AUTO_MODERATION_ACTION_EXECUTION decoded as %+v", a)
+	}
+	if a.Content != "buy now" || a.MatchedKeyword != "buy*" || a.MatchedContent != "buy" || a.Action.Type != 1 || a.RuleTriggerType != 1 {
+		t.Errorf("AUTO_MODERATION_ACTION_EXECUTION decoded as %+v", a)
+	}
+}
+
+func TestInviteEvents(t *testing.T) {
+	c := decodeEvent(t, "INVITE_CREATE", `{"channel_id":"channel","guild_id":"guild","code":"abc","max_uses":5,"inviter":{"id":"user"}}`).(*InviteCreate)
+	if c.ChannelID != "channel" || c.GuildID != "guild" || c.Code != "abc" || c.MaxUses != 5 || c.Inviter == nil || c.Inviter.ID != "user" {
+		t.Errorf("INVITE_CREATE decoded as %+v", c)
+	}
+
+	d := decodeEvent(t, "INVITE_DELETE", `{"channel_id":"channel","guild_id":"guild","code":"abc"}`).(*InviteDelete)
+	if d.ChannelID != "channel" || d.GuildID != "guild" || d.Code != "abc" {
+		t.Errorf("INVITE_DELETE decoded as %+v", d)
+	}
+}
+
+func TestGuildScheduledEventUserEvents(t *testing.T) {
+	data := `{"guild_scheduled_event_id":"event","user_id":"user","guild_id":"guild"}`
+
+	a := decodeEvent(t, "GUILD_SCHEDULED_EVENT_USER_ADD", data).(*GuildScheduledEventUserAdd)
+	if a.GuildScheduledEventID != "event" || a.UserID != "user" || a.GuildID != "guild" {
+		t.Errorf("GUILD_SCHEDULED_EVENT_USER_ADD decoded as %+v", a)
+	}
+
+	r := decodeEvent(t, "GUILD_SCHEDULED_EVENT_USER_REMOVE", data).(*GuildScheduledEventUserRemove)
+	if r.GuildScheduledEventID != "event" || r.UserID != "user" || r.GuildID != "guild" {
+		t.Errorf("GUILD_SCHEDULED_EVENT_USER_REMOVE decoded as %+v", r)
+	}
+}
+
+func TestMessageReactionRemoveEmojiEvent(t *testing.T) {
+	e := decodeEvent(t, "MESSAGE_REACTION_REMOVE_EMOJI", `{"channel_id":"channel","guild_id":"guild","message_id":"message","emoji":{"id":"emoji","name":"blob"}}`).(*MessageReactionRemoveEmoji)
+	if e.ChannelID != "channel" || e.GuildID != "guild" || e.MessageID != "message" || e.Emoji == nil || e.Emoji.Name != "blob" {
+		t.Errorf("MESSAGE_REACTION_REMOVE_EMOJI decoded as %+v", e)
+	}
+}
@@ -1,5 +1,7 @@
 module github.com/bwmarrin/discordgo
 
+go 1.18
+
 require (
 	github.com/gorilla/websocket v1.4.0
 	golang.org/x/crypto v0.0.0-20181030102418-4d3f4d9ffa16
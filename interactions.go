@@ -0,0 +1,380 @@
+// Discordgo - Discord bindings for Go
+// Available at https://github.com/bwmarrin/discordgo
+
+// Copyright 2015-2016 Bruce Marriner <bruce@sqls.net>.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains code related to Discord interactions, such as slash
+// commands and message components
+
+package discordgo
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// InteractionType is the type of an Interaction
+type InteractionType int
+
+// Block contains the valid known InteractionType values
+const (
+	InteractionPing                           InteractionType = 1
+	InteractionApplicationCommand             InteractionType = 2
+	InteractionMessageComponent               InteractionType = 3
+	InteractionApplicationCommandAutocomplete InteractionType = 4
+	InteractionModalSubmit                    InteractionType = 5
+)
+
+// An Interaction is sent by Discord when a user invokes an application
+// command or uses a message component.
+type Interaction struct {
+	ID      string          `json:"id"`
+	AppID   string          `json:"application_id"`
+	Type    InteractionType `json:"type"`
+	GuildID string          `json:"guild_id"`
+
+	// The ID of the channel the interaction was sent from.
+	ChannelID string `json:"channel_id"`
+
+	// The data of the interaction, which depends on its type.
+	Data json.RawMessage `json:"data"`
+
+	// The message a component interaction was sent from.
+	Message *Message `json:"message"`
+
+	// The member who invoked the interaction in a guild, or the user who
+	// invoked it in a DM. Only one of these is set.
+	Member *Member `json:"member"`
+	User   *User   `json:"user"`
+
+	// The token used to respond to the interaction. It is valid for 15
+	// minutes, the first response must be sent within 3 seconds.
+	Token   string `json:"token"`
+	Version int    `json:"version"`
+}
+
+// InteractionResponseType is the type of an InteractionResponse
+type InteractionResponseType int
+
+// Block contains the valid known InteractionResponseType values
+const (
+	InteractionResponsePong                             InteractionResponseType = 1
+	InteractionResponseChannelMessageWithSource         InteractionResponseType = 4
+	InteractionResponseDeferredChannelMessageWithSource InteractionResponseType = 5
+	InteractionResponseDeferredMessageUpdate            InteractionResponseType = 6
+	InteractionResponseUpdateMessage                    InteractionResponseType = 7
+)
+
+// An InteractionResponse is the response sent to an Interaction.
+type InteractionResponse struct {
+	Type InteractionResponseType  `json:"type"`
+	Data *InteractionResponseData `json:"data,omitempty"`
+}
+
+// InteractionResponseData is the message sent in an InteractionResponse.
+type InteractionResponseData struct {
+	TTS     bool            `json:"tts,omitempty"`
+	Content string          `json:"content,omitempty"`
+	Embeds  []*MessageEmbed `json:"embeds,omitempty"`
+
+	// Message flags, such as MessageFlagsEphemeral to only show the
+	// message to the user.
+	Flags MessageFlags `json:"flags,omitempty"`
+
+	// The components of the message, such as action rows of buttons.
+	Components []MessageComponent `json:"components,omitempty"`
+
+	// The mentions allowed in the message, Session.DefaultAllowedMentions
+	// if nil.
+	AllowedMentions *MessageAllowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+// WebhookEdit holds the fields to edit in a message sent by a webhook or
+// in the response to an interaction. Nil fields are left unchanged.
+type WebhookEdit struct {
+	Content *string          `json:"content,omitempty"`
+	Embeds  *[]*MessageEmbed `json:"embeds,omitempty"`
+}
+
+// ApplicationCommandPermissionType is the type of the target of an
+// ApplicationCommandPermissions
+type ApplicationCommandPermissionType int
+
+// Block contains the valid known ApplicationCommandPermissionType values
+const (
+	ApplicationCommandPermissionTypeRole    ApplicationCommandPermissionType = 1
+	ApplicationCommandPermissionTypeUser    ApplicationCommandPermissionType = 2
+	ApplicationCommandPermissionTypeChannel ApplicationCommandPermissionType = 3
+)
+
+// ApplicationCommandPermissions allows or denies the use of a command to a
+// role, a user or in a channel.
+type ApplicationCommandPermissions struct {
+	// The ID of the role, user or channel. The ID of the guild targets
+	// @everyone, and the ID of the guild minus 1 targets all channels.
+	ID         string                           `json:"id"`
+	Type       ApplicationCommandPermissionType `json:"type"`
+	Permission bool                             `json:"permission"`
+}
+
+// ApplicationCommandPermissionsList is the list of permissions set with
+// ApplicationCommandPermissionsEdit.
+type ApplicationCommandPermissionsList struct {
+	Permissions []*ApplicationCommandPermissions `json:"permissions"`
+}
+
+// GuildApplicationCommandPermissions holds the permissions of a command in
+// a guild.
+type GuildApplicationCommandPermissions struct {
+	ID            string                           `json:"id"`
+	ApplicationID string                           `json:"application_id"`
+	GuildID       string                           `json:"guild_id"`
+	Permissions   []*ApplicationCommandPermissions `json:"permissions"`
+}
+
+// ApplicationCommandPermissions returns the permissions of a command in a guild.
+// appID   : The ID of the application.
+// guildID : The ID of the guild.
+// cmdID   : The ID of the command.
+func (s *Session) ApplicationCommandPermissions(appID, guildID, cmdID string) (st *GuildApplicationCommandPermissions, err error) {
+	endpoint := EndpointApplicationCommandPermissions(appID, guildID, cmdID)
+
+	body, err := s.RequestWithBucketID("GET", endpoint, nil, endpoint)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// ApplicationCommandPermissionsEdit replaces the permissions of a command in
+// a guild. Discord requires this to be done with the OAuth2 bearer token of
+// a user who can manage the guild, rather than a bot token.
+// appID       : The ID of the application.
+// guildID     : The ID of the guild.
+// cmdID       : The ID of the command.
+// permissions : The new permissions of the command.
+func (s *Session) ApplicationCommandPermissionsEdit(appID, guildID, cmdID string, permissions *ApplicationCommandPermissionsList) (st *GuildApplicationCommandPermissions, err error) {
+	endpoint := EndpointApplicationCommandPermissions(appID, guildID, cmdID)
+
+	body, err := s.RequestWithBucketID("PUT", endpoint, permissions, endpoint)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// InteractionRespond sends the initial response to an interaction.
+// interaction : The interaction to respond to.
+// resp        : The response to send.
+func (s *Session) InteractionRespond(interaction *Interaction, resp *InteractionResponse) (err error) {
+	endpoint := EndpointInteractionResponse(interaction.ID, interaction.Token)
+
+	if resp.Data != nil && resp.Data.AllowedMentions == nil && s.DefaultAllowedMentions != nil {
+		data := *resp.Data
+		data.AllowedMentions = s.DefaultAllowedMentions
+		resp = &InteractionResponse{Type: resp.Type, Data: &data}
+	}
+
+	_, err = s.RequestWithBucketID("POST", endpoint, resp, endpoint)
+	return
+}
+
+// InteractionResponseEdit edits the initial response to an interaction.
+// interaction : The interaction the response was sent to.
+// edit        : The fields of the response to edit.
+func (s *Session) InteractionResponseEdit(interaction *Interaction, edit *WebhookEdit) (st *Message, err error) {
+	endpoint := EndpointInteractionResponseActions(interaction.AppID, interaction.Token)
+
+	body, err := s.RequestWithBucketID("PATCH", endpoint, edit, endpoint)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// interactionErrorMessage is the response shown by InteractionDeferAndEdit
+// when its work fails.
+const interactionErrorMessage = "An error occurred."
+
+// InteractionDeferAndEdit acknowledges an interaction with a deferred
+// response, so that work can take longer than the 3 seconds Discord waits
+// for a response, then runs work and edits the response with its result.
+// If work returns an error, it is logged and returned, and the response is
+// edited to show a generic error message, as the error may hold details not
+// meant for users. The same goes for a nil edit, for which ErrEmptyMessage is
+// returned, as a deferred response can not be left without a message.
+// interaction : The interaction to respond to.
+// work        : The function producing the response.
+func (s *Session) InteractionDeferAndEdit(interaction *Interaction, work func() (*WebhookEdit, error)) (err error) {
+	err = s.InteractionRespond(interaction, &InteractionResponse{
+		Type: InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		return
+	}
+
+	edit, err := work()
+	if err == nil && edit == nil {
+		err = ErrEmptyMessage
+	}
+	if err != nil {
+		s.log(LogError, "error running interaction work, %s", err)
+
+		content := interactionErrorMessage
+		if _, err2 := s.InteractionResponseEdit(interaction, &WebhookEdit{Content: &content}); err2 != nil {
+			s.log(LogError, "error editing interaction response, %s", err2)
+		}
+		return
+	}
+
+	_, err = s.InteractionResponseEdit(interaction, edit)
+	return
+}
+
+// CollectorOptions configures a ComponentCollector.
+type CollectorOptions struct {
+	// How long to collect interactions for, no limit if 0.
+	Timeout time.Duration
+
+	// The number of interactions to collect before stopping, no limit if 0.
+	Max int
+
+	// The response sent to each collected interaction, such as
+	// InteractionResponseDeferredMessageUpdate to acknowledge it without
+	// changing the message. If 0, no response is sent and the interactions
+	// must be responded to within 3 seconds by the caller.
+	AutoResponse InteractionResponseType
+}
+
+// A ComponentCollector collects the component interactions on a message,
+// e.g. the button presses on a menu. Create one with NewComponentCollector.
+type ComponentCollector struct {
+	sync.Mutex
+
+	session   *Session
+	messageID string
+	filter    func(*Interaction) bool
+	opts      CollectorOptions
+
+	collected []*Interaction
+	remove    func()
+	timer     *time.Timer
+	stopped   bool
+	done      chan struct{}
+}
+
+// NewComponentCollector starts collecting the component interactions on a
+// message, until the timeout or the max number of interactions of opts is
+// reached, or Stop is called.
+// messageID : The ID of the message with the components.
+// filter    : Returns whether to collect an interaction, all are collected if nil.
+// opts      : The options of the collector.
+func (s *Session) NewComponentCollector(messageID string, filter func(*Interaction) bool, opts CollectorOptions) *ComponentCollector {
+	c := &ComponentCollector{
+		session:   s,
+		messageID: messageID,
+		filter:    filter,
+		opts:      opts,
+		done:      make(chan struct{}),
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.remove = s.AddHandler(c.onInteractionCreate)
+	if opts.Timeout > 0 {
+		c.timer = time.AfterFunc(opts.Timeout, c.Stop)
+	}
+
+	return c
+}
+
+// onInteractionCreate collects the interactions on the message of the
+// collector.
+func (c *ComponentCollector) onInteractionCreate(s *Session, i *InteractionCreate) {
+	if i.Interaction == nil || i.Type != InteractionMessageComponent || i.Message == nil || i.Message.ID != c.messageID {
+		return
+	}
+	if c.filter != nil && !c.filter(i.Interaction) {
+		return
+	}
+
+	c.Lock()
+	if c.stopped {
+		c.Unlock()
+		return
+	}
+	c.collected = append(c.collected, i.Interaction)
+
+	// The collector stops with the last interaction, so that interactions
+	// handled concurrently are not collected past Max.
+	full := c.opts.Max > 0 && len(c.collected) >= c.opts.Max
+	if full {
+		c.stopped = true
+	}
+	c.Unlock()
+
+	if c.opts.AutoResponse != 0 {
+		if err := s.InteractionRespond(i.Interaction, &InteractionResponse{Type: c.opts.AutoResponse}); err != nil {
+			s.log(LogError, "error responding to collected interaction, %s", err)
+		}
+	}
+
+	if full {
+		c.finish()
+	}
+}
+
+// Stop stops collecting interactions. It is safe to call more than once.
+func (c *ComponentCollector) Stop() {
+	c.Lock()
+	stopped := c.stopped
+	c.stopped = true
+	c.Unlock()
+
+	if !stopped {
+		c.finish()
+	}
+}
+
+// finish cleans up after the collector was marked as stopped.
+func (c *ComponentCollector) finish() {
+	// finish may be called by the handler itself, while the handlers are
+	// locked for dispatching, so the handler is removed in the background.
+	// It ignores interactions received until then.
+	go c.remove()
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	close(c.done)
+}
+
+// Done returns a channel which is closed once the collector stopped.
+func (c *ComponentCollector) Done() <-chan struct{} {
+	return c.done
+}
+
+// Collected returns the interactions collected so far, in the order they
+// were received.
+func (c *ComponentCollector) Collected() []*Interaction {
+	c.Lock()
+	defer c.Unlock()
+
+	return append([]*Interaction(nil), c.collected...)
+}
+
+// Wait blocks until the collector stopped, and returns the collected
+// interactions.
+func (c *ComponentCollector) Wait() []*Interaction {
+	<-c.done
+	return c.Collected()
+}
@@ -0,0 +1,249 @@
+package discordgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestInteractionDeferAndEdit(t *testing.T) {
+	var requests []string
+	var edit map[string]interface{}
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.Method {
+		case "POST":
+			var resp InteractionResponse
+			json.NewDecoder(r.Body).Decode(&resp)
+			if resp.Type != InteractionResponseDeferredChannelMessageWithSource {
+				t.Errorf("interaction was acknowledged with response type %d", resp.Type)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case "PATCH":
+			json.NewDecoder(r.Body).Decode(&edit)
+			w.Write([]byte(`{"id":"message"}`))
+		}
+	})
+
+	interaction := &Interaction{ID: "interaction", AppID: "app", Token: "token"}
+
+	content := "done"
+	err := s.InteractionDeferAndEdit(interaction, func() (*WebhookEdit, error) {
+		if len(requests) != 1 {
+			t.Errorf("work was run after %d requests, expected it to run after the deferred response", len(requests))
+		}
+		return &WebhookEdit{Content: &content}, nil
+	})
+	if err != nil {
+		t.Fatalf("InteractionDeferAndEdit returned error: %+v", err)
+	}
+
+	expected := []string{
+		"POST /api/v6/interactions/interaction/token/callback",
+		"PATCH /api/v6/webhooks/app/token/messages/@original",
+	}
+	if len(requests) != len(expected) || requests[0] != expected[0] || requests[1] != expected[1] {
+		t.Errorf("requests were %q, expected %q", requests, expected)
+	}
+	if edit["content"] != "done" {
+		t.Errorf("response was edited with %v", edit)
+	}
+}
+
+func TestInteractionDeferAndEditError(t *testing.T) {
+	var edit map[string]interface{}
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			json.NewDecoder(r.Body).Decode(&edit)
+		}
+		w.Write([]byte(`{}`))
+	})
+
+	workErr := errors.New("no such user")
+	err := s.InteractionDeferAndEdit(&Interaction{ID: "interaction", AppID: "app", Token: "token"}, func() (*WebhookEdit, error) {
+		return nil, workErr
+	})
+	if err != workErr {
+		t.Errorf("InteractionDeferAndEdit returned %v, expected the error of work", err)
+	}
+	if edit["content"] != "An error occurred." {
+		t.Errorf("response was edited with %v, expected a generic error message", edit)
+	}
+
+	edit = nil
+	err = s.InteractionDeferAndEdit(&Interaction{ID: "interaction", AppID: "app", Token: "token"}, func() (*WebhookEdit, error) {
+		return nil, nil
+	})
+	if err != ErrEmptyMessage {
+		t.Errorf("InteractionDeferAndEdit returned %v for a nil edit, expected ErrEmptyMessage", err)
+	}
+	if edit["content"] != "An error occurred." {
+		t.Errorf("response was edited with %v for a nil edit, expected a generic error message", edit)
+	}
+}
+
+func TestApplicationCommandPermissions(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v6/applications/app/guilds/guild/commands/command/permissions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		if r.Method == "PUT" {
+			var body map[string][]map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			expected := []map[string]interface{}{
+				{"id": "mods", "type": 1.0, "permission": true},
+				{"id": "guild", "type": 1.0, "permission": false},
+			}
+			if len(body["permissions"]) != len(expected) {
+				t.Fatalf("permissions were edited with %v", body)
+			}
+			for i, p := range expected {
+				for k, v := range p {
+					if body["permissions"][i][k] != v {
+						t.Errorf("permission %d %s is %v, expected %v", i, k, body["permissions"][i][k], v)
+					}
+				}
+			}
+		}
+
+		w.Write([]byte(`{"id":"command","application_id":"app","guild_id":"guild","permissions":[{"id":"mods","type":1,"permission":true},{"id":"user","type":2,"permission":false},{"id":"channel","type":3,"permission":true}]}`))
+	})
+
+	perms, err := s.ApplicationCommandPermissions("app", "guild", "command")
+	if err != nil {
+		t.Fatalf("ApplicationCommandPermissions returned error: %+v", err)
+	}
+	if perms.ID != "command" || perms.ApplicationID != "app" || perms.GuildID != "guild" || len(perms.Permissions) != 3 {
+		t.Fatalf("ApplicationCommandPermissions returned %+v", perms)
+	}
+	if p := perms.Permissions[1]; p.ID != "user" || p.Type != ApplicationCommandPermissionTypeUser || p.Permission {
+		t.Errorf("user permission decoded as %+v", p)
+	}
+	if p := perms.Permissions[2]; p.Type != ApplicationCommandPermissionTypeChannel || !p.Permission {
+		t.Errorf("channel permission decoded as %+v", p)
+	}
+
+	_, err = s.ApplicationCommandPermissionsEdit("app", "guild", "command", &ApplicationCommandPermissionsList{
+		Permissions: []*ApplicationCommandPermissions{
+			{ID: "mods", Type: ApplicationCommandPermissionTypeRole, Permission: true},
+			{ID: "guild", Type: ApplicationCommandPermissionTypeRole, Permission: false},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ApplicationCommandPermissionsEdit returned error: %+v", err)
+	}
+}
+
+func TestComponentCollector(t *testing.T) {
+	var mu sync.Mutex
+	var responses []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		var resp InteractionResponse
+		json.NewDecoder(r.Body).Decode(&resp)
+		if resp.Type != InteractionResponseDeferredMessageUpdate {
+			t.Errorf("interaction was acknowledged with response type %d", resp.Type)
+		}
+
+		mu.Lock()
+		responses = append(responses, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.SyncEvents = true
+
+	c := s.NewComponentCollector("menu", func(i *Interaction) bool {
+		return i.Member != nil && i.Member.User.ID == "user"
+	}, CollectorOptions{Max: 2, Timeout: 5 * time.Second, AutoResponse: InteractionResponseDeferredMessageUpdate})
+
+	press := func(id, messageID, userID string) {
+		payload := `{"op":0,"s":1,"t":"INTERACTION_CREATE","d":{"id":"` + id + `","type":3,"token":"token-` + id + `","message":{"id":"` + messageID + `"},"member":{"user":{"id":"` + userID + `"}},"data":{"custom_id":"next"}}}`
+		if _, err := s.onEvent(websocket.TextMessage, []byte(payload)); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+	}
+
+	press("1", "menu", "user")
+	press("2", "other", "user")
+	press("3", "menu", "someone")
+	press("4", "menu", "user")
+	press("5", "menu", "user")
+
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("collector did not stop after collecting 2 interactions")
+	}
+
+	collected := c.Wait()
+	if len(collected) != 2 || collected[0].ID != "1" || collected[1].ID != "4" {
+		t.Errorf("collector collected %+v, expected interactions 1 and 4", collected)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{"/api/v6/interactions/1/token-1/callback", "/api/v6/interactions/4/token-4/callback"}
+	if len(responses) != 2 || responses[0] != expected[0] || responses[1] != expected[1] {
+		t.Errorf("interactions were acknowledged at %q, expected %q", responses, expected)
+	}
+}
+
+func TestComponentCollectorConcurrentMax(t *testing.T) {
+	var responses int32
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&responses, 1)
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	c := s.NewComponentCollector("menu", nil, CollectorOptions{Max: 2, AutoResponse: InteractionResponseDeferredMessageUpdate})
+
+	// The handlers of the interactions run concurrently.
+	for n := 0; n < 20; n++ {
+		payload := fmt.Sprintf(`{"op":0,"s":%d,"t":"INTERACTION_CREATE","d":{"id":"%d","type":3,"token":"token","message":{"id":"menu"},"data":{"custom_id":"next"}}}`, n+1, n)
+		if _, err := s.onEvent(websocket.TextMessage, []byte(payload)); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+	}
+
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("collector did not stop after collecting 2 interactions")
+	}
+	// Let the handlers still running finish.
+	time.Sleep(50 * time.Millisecond)
+
+	if collected := c.Collected(); len(collected) != 2 {
+		t.Errorf("collector collected %d interactions, expected 2", len(collected))
+	}
+	if n := atomic.LoadInt32(&responses); n != 2 {
+		t.Errorf("%d interactions were acknowledged, expected 2", n)
+	}
+}
+
+func TestComponentCollectorTimeout(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+
+	c := s.NewComponentCollector("menu", nil, CollectorOptions{Timeout: 20 * time.Millisecond})
+
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("collector did not stop after its timeout")
+	}
+	if collected := c.Wait(); len(collected) != 0 {
+		t.Errorf("collector collected %+v", collected)
+	}
+	c.Stop()
+}
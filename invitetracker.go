@@ -0,0 +1,105 @@
+// Discordgo - Discord bindings for Go
+// Available at https://github.com/bwmarrin/discordgo
+
+// Copyright 2015-2016 Bruce Marriner <bruce@sqls.net>.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains code related to tracking guild invites, to identify
+// the invite a member joined with
+
+package discordgo
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrInviteUnknown is returned by GuildInviteUsed when no invite can be
+// identified as the one used.
+var ErrInviteUnknown = errors.New("could not identify the invite used")
+
+// inviteTracker holds the last known invites of every tracked guild.
+// The zero value is an empty inviteTracker, so that sessions which are not
+// created with New can track invites too.
+type inviteTracker struct {
+	sync.Mutex
+	guilds map[string]map[string]*Invite
+}
+
+// update replaces the snapshot of a guild's invites, and returns the invite
+// whose uses went up since the previous snapshot. The second value is false
+// when no previous snapshot existed.
+func (t *inviteTracker) update(guildID string, invites []*Invite) (used *Invite, ok bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	snapshot := make(map[string]*Invite, len(invites))
+	for _, i := range invites {
+		snapshot[i.Code] = i
+	}
+
+	if t.guilds == nil {
+		t.guilds = make(map[string]map[string]*Invite)
+	}
+
+	previous, ok := t.guilds[guildID]
+	t.guilds[guildID] = snapshot
+	if !ok {
+		return nil, false
+	}
+
+	for code, i := range snapshot {
+		old, existed := previous[code]
+		if existed && i.Uses > old.Uses || !existed && i.Uses > 0 {
+			return i, true
+		}
+	}
+
+	// Discord deletes an invite once it reaches its maximum uses, so an
+	// invite that was one use away from that and is now gone was most
+	// likely the one used.
+	for code, old := range previous {
+		if _, exists := snapshot[code]; !exists && old.MaxUses > 0 && old.Uses == old.MaxUses-1 {
+			return old, true
+		}
+	}
+
+	return nil, true
+}
+
+// GuildInvitesTrack stores the current invites of a guild, so a later call to
+// GuildInviteUsed can tell which invite was used.
+// guildID   : The ID of a Guild.
+func (s *Session) GuildInvitesTrack(guildID string) (err error) {
+	invites, err := s.GuildInvites(guildID)
+	if err != nil {
+		return
+	}
+
+	s.invites.update(guildID, invites)
+	return
+}
+
+// GuildInviteUsed refreshes the invites of a guild and returns the invite
+// whose use count went up since they were last fetched. It is meant to be
+// called from a GuildMemberAdd handler, after GuildInvitesTrack has been
+// called for the guild once, e.g. on GuildCreate. The first call for a guild
+// that is not tracked yet only stores its invites and returns ErrInviteUnknown.
+//
+// The result is a best guess: when several members join between two calls,
+// or an invite is deleted or expires in between, the wrong invite, or none,
+// may be returned. Calls for the same guild should not run concurrently.
+// guildID   : The ID of a Guild.
+func (s *Session) GuildInviteUsed(guildID string) (st *Invite, err error) {
+	invites, err := s.GuildInvites(guildID)
+	if err != nil {
+		return
+	}
+
+	st, _ = s.invites.update(guildID, invites)
+	if st == nil {
+		err = ErrInviteUnknown
+	}
+	return
+}
@@ -0,0 +1,61 @@
+package discordgo
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGuildInviteUsed(t *testing.T) {
+	snapshots := []string{
+		`[{"code":"a","uses":1},{"code":"b","uses":4}]`,
+		`[{"code":"a","uses":1},{"code":"b","uses":5}]`,
+	}
+
+	requests := 0
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(snapshots[requests]))
+		requests++
+	})
+
+	if err := s.GuildInvitesTrack("guild"); err != nil {
+		t.Fatalf("GuildInvitesTrack returned error: %+v", err)
+	}
+
+	invite, err := s.GuildInviteUsed("guild")
+	if err != nil {
+		t.Fatalf("GuildInviteUsed returned error: %+v", err)
+	}
+	if invite.Code != "b" {
+		t.Errorf("GuildInviteUsed returned invite %q, expected %q", invite.Code, "b")
+	}
+
+	// Sessions which are not created with New track invites too.
+	requests = 0
+	s = &Session{Client: s.Client, Ratelimiter: NewRatelimiter()}
+	if err := s.GuildInvitesTrack("guild"); err != nil {
+		t.Fatalf("GuildInvitesTrack returned error: %+v", err)
+	}
+	if invite, err = s.GuildInviteUsed("guild"); err != nil || invite.Code != "b" {
+		t.Errorf("GuildInviteUsed returned %+v, %v without New, expected invite %q", invite, err, "b")
+	}
+}
+
+func TestInviteTrackerUpdate(t *testing.T) {
+	var tracker inviteTracker
+
+	if _, ok := tracker.update("guild", []*Invite{{Code: "a", Uses: 1}, {Code: "last", Uses: 2, MaxUses: 3}}); ok {
+		t.Error("update reported a previous snapshot for a new guild")
+	}
+
+	if used, _ := tracker.update("guild", []*Invite{{Code: "a", Uses: 1}, {Code: "last", Uses: 2, MaxUses: 3}}); used != nil {
+		t.Errorf("update returned invite %q when no uses changed", used.Code)
+	}
+
+	if used, _ := tracker.update("guild", []*Invite{{Code: "a", Uses: 1}}); used == nil || used.Code != "last" {
+		t.Errorf("update did not return the invite deleted after reaching its max uses, got %+v", used)
+	}
+
+	if used, _ := tracker.update("guild", []*Invite{{Code: "a", Uses: 1}, {Code: "new", Uses: 1}}); used == nil || used.Code != "new" {
+		t.Errorf("update did not return the used invite created since the last snapshot, got %+v", used)
+	}
+}
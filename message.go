@@ -10,8 +10,13 @@
 package discordgo
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -28,6 +33,53 @@ const (
 	MessageTypeChannelIconChange
 	MessageTypeChannelPinnedMessage
 	MessageTypeGuildMemberJoin
+	MessageTypeUserPremiumGuildSubscription
+	MessageTypeUserPremiumGuildSubscriptionTierOne
+	MessageTypeUserPremiumGuildSubscriptionTierTwo
+	MessageTypeUserPremiumGuildSubscriptionTierThree
+	MessageTypeChannelFollowAdd
+	_
+	MessageTypeGuildDiscoveryDisqualified
+	MessageTypeGuildDiscoveryRequalified
+	MessageTypeGuildDiscoveryGracePeriodInitialWarning
+	MessageTypeGuildDiscoveryGracePeriodFinalWarning
+	MessageTypeThreadCreated
+	MessageTypeReply
+	MessageTypeChatInputCommand
+	MessageTypeThreadStarterMessage
+	MessageTypeGuildInviteReminder
+	MessageTypeContextMenuCommand
+	MessageTypeAutoModerationAction
+)
+
+// IsSystem returns whether messages of the type are sent by Discord, e.g.
+// when a member joins or a message is pinned, rather than by a user.
+func (t MessageType) IsSystem() bool {
+	switch t {
+	case MessageTypeDefault, MessageTypeReply, MessageTypeChatInputCommand, MessageTypeContextMenuCommand:
+		return false
+	}
+	return true
+}
+
+// MessageFlags are the flags of a Message
+type MessageFlags int
+
+// Block contains the valid known MessageFlags values
+const (
+	MessageFlagsCrossposted MessageFlags = 1 << iota
+	MessageFlagsIsCrosspost
+	MessageFlagsSuppressEmbeds
+	MessageFlagsSourceMessageDeleted
+	MessageFlagsUrgent
+	MessageFlagsHasThread
+	MessageFlagsEphemeral
+	MessageFlagsLoading
+	MessageFlagsFailedToMentionSomeRolesInThread
+	_
+	_
+	_
+	MessageFlagsSuppressNotifications
 )
 
 // A Message stores all data related to a specific Discord message.
@@ -85,6 +137,158 @@ type Message struct {
 
 	// The webhook ID of the message, if it was generated by a webhook
 	WebhookID string `json:"webhook_id"`
+
+	// The flags of the message, such as MessageFlagsCrossposted.
+	Flags MessageFlags `json:"flags"`
+
+	// The message this message replies to, crossposts or forwards, if any.
+	MessageReference *MessageReference `json:"message_reference,omitempty"`
+
+	// The poll of the message, if it has one.
+	Poll *Poll `json:"poll,omitempty"`
+
+	// The copies of the messages forwarded by this message, whose
+	// MessageReference has the type MessageReferenceTypeForward.
+	MessageSnapshots []*MessageSnapshot `json:"message_snapshots,omitempty"`
+
+	// The components of the message, such as action rows of buttons.
+	Components []MessageComponent `json:"components,omitempty"`
+}
+
+// UnmarshalJSON unmarshals a Message, decoding its components according to
+// their type
+func (m *Message) UnmarshalJSON(b []byte) error {
+	type message Message
+	var v struct {
+		message
+		Components []unmarshalableMessageComponent `json:"components"`
+	}
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+
+	*m = Message(v.message)
+	m.Components = unmarshalMessageComponents(v.Components)
+	return nil
+}
+
+// MessageReferenceType is the type of a MessageReference
+type MessageReferenceType int
+
+// Block contains the valid known MessageReferenceType values
+const (
+	MessageReferenceTypeDefault MessageReferenceType = 0
+	MessageReferenceTypeForward MessageReferenceType = 1
+)
+
+// MessageReference points to another message, e.g. the one a reply
+// answers.
+type MessageReference struct {
+	Type      MessageReferenceType `json:"type,omitempty"`
+	MessageID string               `json:"message_id"`
+	ChannelID string               `json:"channel_id,omitempty"`
+	GuildID   string               `json:"guild_id,omitempty"`
+}
+
+// A MessageSnapshot is a copy of a forwarded message, taken when it was
+// forwarded. Only the content of the message is included, e.g. its
+// content, embeds and attachments, but not its ID or author.
+type MessageSnapshot struct {
+	Message *Message `json:"message"`
+}
+
+// IsSystem returns whether the message was sent by Discord, e.g. when a
+// member joins or a message is pinned, rather than by a user.
+func (m *Message) IsSystem() bool {
+	return m.Type.IsSystem()
+}
+
+// IsFromBot returns whether the message was sent by a bot.
+func (m *Message) IsFromBot() bool {
+	return m.Author != nil && m.Author.Bot
+}
+
+// Reference returns a MessageReference pointing to the message, used to
+// reply to it.
+func (m *Message) Reference() *MessageReference {
+	return &MessageReference{
+		MessageID: m.ID,
+		ChannelID: m.ChannelID,
+		GuildID:   m.GuildID,
+	}
+}
+
+// PollLayoutType is the layout of a Poll
+type PollLayoutType int
+
+// Block contains the valid known PollLayoutType values
+const (
+	PollLayoutTypeDefault PollLayoutType = 1
+)
+
+// PollEmoji is the emoji shown with a poll answer, either a custom emoji by
+// ID or a unicode emoji by name.
+type PollEmoji struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Animated bool   `json:"animated,omitempty"`
+}
+
+// PollMedia is the question or an answer of a Poll. Questions only support
+// Text.
+type PollMedia struct {
+	Text  string     `json:"text,omitempty"`
+	Emoji *PollEmoji `json:"emoji,omitempty"`
+}
+
+// PollAnswer is an answer of a Poll. AnswerID is set by Discord, and is
+// used to get the voters of the answer with PollAnswerVoters.
+type PollAnswer struct {
+	AnswerID int        `json:"answer_id,omitempty"`
+	Media    *PollMedia `json:"poll_media"`
+}
+
+// PollCreate is a poll sent with MessageSend.
+type PollCreate struct {
+	Question PollMedia    `json:"question"`
+	Answers  []PollAnswer `json:"answers"`
+
+	// The number of hours the poll is open for, up to 32 days. Discord
+	// defaults to 24 hours.
+	Duration int `json:"duration,omitempty"`
+
+	AllowMultiselect bool           `json:"allow_multiselect"`
+	LayoutType       PollLayoutType `json:"layout_type,omitempty"`
+}
+
+// A Poll is a poll attached to a Message.
+type Poll struct {
+	Question PollMedia    `json:"question"`
+	Answers  []PollAnswer `json:"answers"`
+
+	// When the poll ends, empty for polls which don't expire.
+	Expiry Timestamp `json:"expiry"`
+
+	AllowMultiselect bool           `json:"allow_multiselect"`
+	LayoutType       PollLayoutType `json:"layout_type"`
+
+	// The vote counts of the poll. They are only finalized once the poll
+	// ended, and may be missing before.
+	Results *PollResults `json:"results"`
+}
+
+// PollResults are the vote counts of a Poll.
+type PollResults struct {
+	Finalized    bool               `json:"is_finalized"`
+	AnswerCounts []*PollAnswerCount `json:"answer_counts"`
+}
+
+// PollAnswerCount is the number of votes for an answer of a Poll.
+type PollAnswerCount struct {
+	ID      int  `json:"id"`
+	Count   int  `json:"count"`
+	MeVoted bool `json:"me_voted"`
 }
 
 // File stores info about files you e.g. send in messages.
@@ -92,6 +296,9 @@ type File struct {
 	Name        string
 	ContentType string
 	Reader      io.Reader
+
+	// The alt text of the file, shown by clients for accessibility.
+	Description string
 }
 
 // MessageSend stores all parameters you can send with ChannelMessageSendComplex.
@@ -101,10 +308,246 @@ type MessageSend struct {
 	Tts     bool          `json:"tts"`
 	Files   []*File       `json:"-"`
 
+	// Embeds sent along with Embed, up to 10 in total.
+	Embeds []*MessageEmbed `json:"embeds,omitempty"`
+
+	// The message to reply to, if the message is a reply.
+	Reference *MessageReference `json:"message_reference,omitempty"`
+
+	// The poll to create with the message.
+	Poll *PollCreate `json:"poll,omitempty"`
+
+	// The components of the message, such as action rows of buttons.
+	Components []MessageComponent `json:"components,omitempty"`
+
+	// The mentions allowed in the message, Session.DefaultAllowedMentions
+	// if nil.
+	AllowedMentions *MessageAllowedMentions `json:"allowed_mentions,omitempty"`
+
 	// TODO: Remove this when compatibility is not required.
 	File *File `json:"-"`
 }
 
+// fileAttachment describes a file sent with a message, which it matches by
+// the index of the file.
+type fileAttachment struct {
+	ID          int    `json:"id"`
+	Filename    string `json:"filename"`
+	Description string `json:"description,omitempty"`
+}
+
+// MarshalJSON marshals a MessageSend, describing its files in attachments
+// when any of them has a Description
+func (m MessageSend) MarshalJSON() ([]byte, error) {
+	type messageSend MessageSend
+
+	files := m.allFiles()
+
+	var attachments []fileAttachment
+	for _, file := range files {
+		if file.Description != "" {
+			attachments = make([]fileAttachment, len(files))
+			for i, file := range files {
+				attachments[i] = fileAttachment{ID: i, Filename: file.Name, Description: file.Description}
+			}
+			break
+		}
+	}
+
+	return json.Marshal(struct {
+		messageSend
+		Attachments []fileAttachment `json:"attachments,omitempty"`
+	}{messageSend(m), attachments})
+}
+
+// AllowedMentionType is a type of mention which can be allowed in a message
+type AllowedMentionType string
+
+// Block contains the valid known AllowedMentionType values
+const (
+	AllowedMentionTypeRoles    AllowedMentionType = "roles"
+	AllowedMentionTypeUsers    AllowedMentionType = "users"
+	AllowedMentionTypeEveryone AllowedMentionType = "everyone"
+)
+
+// MessageAllowedMentions restricts who is notified by the mentions in a
+// message. Mentions which are not allowed are still shown, but do not ping.
+type MessageAllowedMentions struct {
+	// The types of mentions allowed for anyone, none if empty. A type
+	// must not be included if IDs are given for it in Roles or Users.
+	Parse []AllowedMentionType `json:"parse"`
+
+	// The IDs of the roles and users whose mentions are allowed.
+	Roles []string `json:"roles,omitempty"`
+	Users []string `json:"users,omitempty"`
+
+	// Whether the author of the message replied to is pinged by a reply.
+	RepliedUser bool `json:"replied_user,omitempty"`
+}
+
+// Limits enforced by Discord on the messages and embeds sent by a bot.
+const (
+	messageContentLimit = 2000
+	messageEmbedsLimit  = 10
+
+	embedTitleLimit       = 256
+	embedDescriptionLimit = 4096
+	embedFieldsLimit      = 25
+	embedFieldNameLimit   = 256
+	embedFieldValueLimit  = 1024
+	embedFooterTextLimit  = 2048
+	embedAuthorNameLimit  = 256
+	embedTotalLimit       = 6000
+)
+
+// checkLength returns an error if s is longer than limit characters.
+func checkLength(name, s string, limit int) error {
+	if n := len([]rune(s)); n > limit {
+		return fmt.Errorf("%s is %d characters long, the limit is %d", name, n, limit)
+	}
+	return nil
+}
+
+// codeFence is the marker opening and closing a markdown code block.
+const codeFence = "```"
+
+// splitMessage splits content into chunks of at most limit characters,
+// preferring to split on newlines and then on spaces. A code block which is
+// split is closed at the end of the chunk and reopened, with the same
+// language, at the start of the next.
+func splitMessage(content string, limit int) (chunks []string) {
+	rest := []rune(content)
+
+	// The opening line of the code block the remaining content is in.
+	var fence string
+	for {
+		prefix := ""
+		if fence != "" {
+			prefix = fence + "\n"
+		}
+
+		if len([]rune(prefix))+len(rest) <= limit {
+			return append(chunks, prefix+string(rest))
+		}
+
+		// If the chunk ends inside a code block, it has to be cut shorter to
+		// leave room for closing the block.
+		var chunk string
+		var cut, skip int
+		for _, reserve := range []int{0, len("\n" + codeFence)} {
+			cut, skip = splitPoint(rest, limit-len([]rune(prefix))-reserve)
+			chunk = prefix + string(rest[:cut])
+			fence = openCodeFence(chunk)
+			if fence == "" || len([]rune(chunk))+len("\n"+codeFence) <= limit {
+				break
+			}
+		}
+		rest = rest[cut+skip:]
+
+		if fence != "" {
+			chunk += "\n" + codeFence
+		}
+		if len([]rune(fence)) > limit/2 {
+			// Only the language of a code block is worth repeating.
+			fence = codeFence
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+// splitPoint returns where to cut runes so the first part is at most budget
+// runes long, and how many separator runes to drop after the cut.
+func splitPoint(runes []rune, budget int) (cut, skip int) {
+	for _, sep := range []rune{'\n', ' '} {
+		if i := lastRuneIndex(runes[:budget+1], sep); i > 0 {
+			return i, 1
+		}
+	}
+	return budget, 0
+}
+
+// lastRuneIndex returns the index of the last r in runes, or -1.
+func lastRuneIndex(runes []rune, r rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// openCodeFence returns the opening line of the code block left open at the
+// end of s, or an empty string if every code block is closed.
+func openCodeFence(s string) (fence string) {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.Count(line, codeFence)%2 == 0 {
+			continue
+		}
+
+		if fence != "" {
+			fence = ""
+		} else if strings.HasPrefix(line, codeFence) {
+			fence = line
+		} else {
+			fence = codeFence
+		}
+	}
+	return
+}
+
+// allEmbeds returns Embed and Embeds together.
+func (m *MessageSend) allEmbeds() []*MessageEmbed {
+	if m.Embed == nil {
+		return m.Embeds
+	}
+	return append([]*MessageEmbed{m.Embed}, m.Embeds...)
+}
+
+// allFiles returns the files sent with the message, Files followed by File,
+// skipping nil entries. A file is sent as files[n] where n is its index.
+func (m *MessageSend) allFiles() []*File {
+	files := make([]*File, 0, len(m.Files)+1)
+	for _, file := range m.Files {
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+	if m.File != nil {
+		files = append(files, m.File)
+	}
+	return files
+}
+
+// Validate checks the message against the length limits enforced by Discord,
+// returning an error describing the first one which is exceeded.
+func (m *MessageSend) Validate() error {
+	if err := checkLength("message content", m.Content, messageContentLimit); err != nil {
+		return err
+	}
+
+	embeds := m.allEmbeds()
+	if len(embeds) > messageEmbedsLimit {
+		return fmt.Errorf("message has %d embeds, the limit is %d", len(embeds), messageEmbedsLimit)
+	}
+
+	total := 0
+	for i, e := range embeds {
+		if e == nil {
+			continue
+		}
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("embed %d: %s", i, err)
+		}
+		total += e.length()
+	}
+	if total > embedTotalLimit {
+		return fmt.Errorf("embeds are %d characters long in total, the limit is %d", total, embedTotalLimit)
+	}
+
+	return nil
+}
+
 // MessageEdit is used to chain parameters via ChannelMessageEditComplex, which
 // is also where you should get the instance from.
 type MessageEdit struct {
@@ -147,6 +590,34 @@ type MessageAttachment struct {
 	Width    int    `json:"width"`
 	Height   int    `json:"height"`
 	Size     int    `json:"size"`
+
+	// The alt text of the attachment.
+	Description string `json:"description"`
+}
+
+// Download fetches the contents of the attachment from the CDN, using the
+// HTTP client of the session. The caller must close the returned reader.
+// A response other than 200 OK is returned as a *RESTError.
+func (a *MessageAttachment) Download(s *Session) (r io.ReadCloser, err error) {
+	req, err := http.NewRequest("GET", a.URL, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", s.userAgent())
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		err = newRestError(req, resp, body)
+		return
+	}
+
+	return resp.Body, nil
 }
 
 // MessageEmbedFooter is a part of a MessageEmbed struct.
@@ -218,6 +689,135 @@ type MessageEmbed struct {
 	Fields      []*MessageEmbedField   `json:"fields,omitempty"`
 }
 
+// Block contains named colors for MessageEmbed.Color, including Discord's
+// brand colors
+const (
+	ColorDefault = 0x000000
+	ColorWhite   = 0xFFFFFF
+	ColorBlurple = 0x5865F2
+	ColorGreyple = 0x99AAB5
+	ColorGreen   = 0x57F287
+	ColorYellow  = 0xFEE75C
+	ColorFuchsia = 0xEB459E
+	ColorRed     = 0xED4245
+	ColorOrange  = 0xE67E22
+	ColorBlue    = 0x3498DB
+	ColorPurple  = 0x9B59B6
+	ColorGold    = 0xF1C40F
+
+	// Discord's dark theme background colors
+	ColorDarkButNotBlack = 0x2C2F33
+	ColorNotQuiteBlack   = 0x23272A
+)
+
+// ColorFromHex parses a color in the #RRGGBB or RRGGBB format, for use in
+// MessageEmbed.Color.
+func ColorFromHex(hex string) (color int, err error) {
+	h := strings.TrimPrefix(hex, "#")
+	if len(h) != 6 {
+		return 0, fmt.Errorf("invalid hex color %q, expected #RRGGBB", hex)
+	}
+
+	c, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hex color %q, expected #RRGGBB", hex)
+	}
+
+	return int(c), nil
+}
+
+// length returns the number of characters in the embed which count towards
+// the total limit of an embed.
+func (e *MessageEmbed) length() (n int) {
+	n = len([]rune(e.Title)) + len([]rune(e.Description))
+	for _, f := range e.Fields {
+		if f != nil {
+			n += len([]rune(f.Name)) + len([]rune(f.Value))
+		}
+	}
+	if e.Footer != nil {
+		n += len([]rune(e.Footer.Text))
+	}
+	if e.Author != nil {
+		n += len([]rune(e.Author.Name))
+	}
+	return
+}
+
+// Validate checks the embed against the length limits enforced by Discord,
+// returning an error describing the first one which is exceeded.
+func (e *MessageEmbed) Validate() error {
+	if err := checkLength("title", e.Title, embedTitleLimit); err != nil {
+		return err
+	}
+	if err := checkLength("description", e.Description, embedDescriptionLimit); err != nil {
+		return err
+	}
+
+	if len(e.Fields) > embedFieldsLimit {
+		return fmt.Errorf("embed has %d fields, the limit is %d", len(e.Fields), embedFieldsLimit)
+	}
+	for i, f := range e.Fields {
+		if f == nil {
+			continue
+		}
+		if err := checkLength(fmt.Sprintf("field %d name", i), f.Name, embedFieldNameLimit); err != nil {
+			return err
+		}
+		if err := checkLength(fmt.Sprintf("field %d value", i), f.Value, embedFieldValueLimit); err != nil {
+			return err
+		}
+	}
+
+	if e.Footer != nil {
+		if err := checkLength("footer text", e.Footer.Text, embedFooterTextLimit); err != nil {
+			return err
+		}
+	}
+	if e.Author != nil {
+		if err := checkLength("author name", e.Author.Name, embedAuthorNameLimit); err != nil {
+			return err
+		}
+	}
+
+	if n := e.length(); n > embedTotalLimit {
+		return fmt.Errorf("embed is %d characters long in total, the limit is %d", n, embedTotalLimit)
+	}
+
+	return nil
+}
+
+// ChunkEmbedFields distributes fields across as few embeds as possible, with
+// at most perEmbed fields in each, without exceeding the field count and
+// total length limits of an embed. A perEmbed of 0 or more than 25 is treated
+// as 25. Nil fields are skipped.
+// fields   : The fields to distribute, in order.
+// perEmbed : The maximum number of fields in each embed.
+func ChunkEmbedFields(fields []*MessageEmbedField, perEmbed int) (embeds []*MessageEmbed) {
+	if perEmbed <= 0 || perEmbed > embedFieldsLimit {
+		perEmbed = embedFieldsLimit
+	}
+
+	var current *MessageEmbed
+	length := 0
+	for _, f := range fields {
+		if f == nil {
+			continue
+		}
+
+		n := len([]rune(f.Name)) + len([]rune(f.Value))
+		if current == nil || len(current.Fields) == perEmbed || length+n > embedTotalLimit {
+			current = &MessageEmbed{}
+			embeds = append(embeds, current)
+			length = 0
+		}
+
+		current.Fields = append(current.Fields, f)
+		length += n
+	}
+	return
+}
+
 // MessageReactions holds a reactions object for a message.
 type MessageReactions struct {
 	Count int    `json:"count"`
@@ -225,6 +825,29 @@ type MessageReactions struct {
 	Emoji *Emoji `json:"emoji"`
 }
 
+// UserMention returns the markdown mentioning a user.
+func UserMention(userID string) string {
+	return "<@" + userID + ">"
+}
+
+// RoleMention returns the markdown mentioning a role.
+func RoleMention(roleID string) string {
+	return "<@&" + roleID + ">"
+}
+
+// ChannelMention returns the markdown mentioning a channel.
+func ChannelMention(channelID string) string {
+	return "<#" + channelID + ">"
+}
+
+// CommandMention returns the markdown mentioning a slash command, which is
+// shown as a link that fills in the command when it is clicked.
+// name      : The name of the command, followed by the subcommand, e.g. "config set".
+// commandID : The ID of the command.
+func CommandMention(name, commandID string) string {
+	return "</" + name + ":" + commandID + ">"
+}
+
 // ContentWithMentionsReplaced will replace all @<id> mentions with the
 // username of the mention.
 func (m *Message) ContentWithMentionsReplaced() (content string) {
@@ -289,3 +912,127 @@ func (m *Message) ContentWithMoreMentionsReplaced(s *Session) (content string, e
 	})
 	return
 }
+
+var patternCustomEmojis = regexp.MustCompile("<(a?):([A-Za-z0-9_]+):([0-9]+)>")
+
+// CustomEmojis returns the custom emojis used in the content of the message,
+// in the order they appear. Only the ID, Name and Animated fields of the
+// emojis are set.
+func (m *Message) CustomEmojis() []*Emoji {
+	var emojis []*Emoji
+
+	for _, match := range patternCustomEmojis.FindAllStringSubmatch(m.Content, -1) {
+		emojis = append(emojis, &Emoji{
+			ID:       match[3],
+			Name:     match[2],
+			Animated: match[1] == "a",
+		})
+	}
+
+	return emojis
+}
+
+// MessageIterDirection is the direction in which a MessageIterator walks
+// the history of a channel.
+type MessageIterDirection int
+
+// Block contains the valid MessageIterDirection values
+const (
+	// MessageIterBefore walks from the newest message to the oldest.
+	MessageIterBefore MessageIterDirection = iota
+	// MessageIterAfter walks from the oldest message to the newest.
+	MessageIterAfter
+)
+
+// messageIterPageSize is the number of messages requested per page.
+const messageIterPageSize = 100
+
+// A MessageIterator walks the message history of a channel one message at a
+// time, fetching pages from Discord as they are needed.
+type MessageIterator struct {
+	session   *Session
+	channelID string
+	direction MessageIterDirection
+
+	// The ID of the last message fetched, used as the cursor for the next page.
+	cursor string
+
+	// Messages fetched but not yet returned by Next.
+	page []*Message
+
+	// Whether the last page has been fetched.
+	done bool
+}
+
+// MessagesIter returns a MessageIterator over the messages of the channel.
+// s         : The Session used to fetch the messages.
+// direction : The direction to walk the history in.
+func (c *Channel) MessagesIter(s *Session, direction MessageIterDirection) *MessageIterator {
+	it := &MessageIterator{
+		session:   s,
+		channelID: c.ID,
+		direction: direction,
+	}
+
+	if direction == MessageIterAfter {
+		// Every snowflake is after 0, so the first page holds the
+		// oldest messages of the channel.
+		it.cursor = "0"
+	}
+
+	return it
+}
+
+// Next returns the next message of the channel. Once every message has been
+// returned, Next returns io.EOF.
+func (it *MessageIterator) Next() (m *Message, err error) {
+	if len(it.page) == 0 {
+		if it.done {
+			return nil, io.EOF
+		}
+
+		err = it.fetch()
+		if err != nil {
+			return
+		}
+
+		if len(it.page) == 0 {
+			return nil, io.EOF
+		}
+	}
+
+	m = it.page[0]
+	it.page = it.page[1:]
+	return
+}
+
+// fetch requests the next page of messages.
+func (it *MessageIterator) fetch() (err error) {
+	var page []*Message
+	if it.direction == MessageIterAfter {
+		page, err = it.session.ChannelMessages(it.channelID, messageIterPageSize, "", it.cursor, "")
+	} else {
+		page, err = it.session.ChannelMessages(it.channelID, messageIterPageSize, it.cursor, "", "")
+	}
+	if err != nil {
+		return
+	}
+
+	if len(page) < messageIterPageSize {
+		it.done = true
+	}
+	if len(page) == 0 {
+		return
+	}
+
+	// Discord returns every page newest first.
+	if it.direction == MessageIterAfter {
+		for i, j := 0, len(page)-1; i < j; i, j = i+1, j-1 {
+			page[i], page[j] = page[j], page[i]
+		}
+	}
+
+	it.cursor = page[len(page)-1].ID
+	it.page = page
+	return
+}
@@ -1,6 +1,12 @@
 package discordgo
 
 import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -38,3 +44,512 @@ func TestContentWithMoreMentionsReplaced(t *testing.T) {
 		t.Error(result)
 	}
 }
+
+// newTestHistorySession returns a Session serving a channel history of count
+// messages with IDs 1 to count, paged the way Discord pages them.
+func newTestHistorySession(count int, requests *int) *Session {
+	return newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		q := r.URL.Query()
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		before, _ := strconv.Atoi(q.Get("before"))
+		after, _ := strconv.Atoi(q.Get("after"))
+		if before == 0 {
+			before = count + 1
+		}
+
+		var page []*Message
+		if q.Get("after") != "" {
+			for id := after + 1; id < after+1+limit && id <= count; id++ {
+				page = append([]*Message{{ID: strconv.Itoa(id)}}, page...)
+			}
+		} else {
+			for id := before - 1; id > before-1-limit && id > 0; id-- {
+				page = append(page, &Message{ID: strconv.Itoa(id)})
+			}
+		}
+
+		json.NewEncoder(w).Encode(page)
+	})
+}
+
+func TestMessagesIter(t *testing.T) {
+	tests := []struct {
+		direction MessageIterDirection
+		first     int
+		step      int
+	}{
+		{MessageIterBefore, 150, -1},
+		{MessageIterAfter, 1, 1},
+	}
+
+	for _, tt := range tests {
+		requests := 0
+		s := newTestHistorySession(150, &requests)
+		it := (&Channel{ID: "channel"}).MessagesIter(s, tt.direction)
+
+		id := tt.first
+		for {
+			m, err := it.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Next() returned error: %+v", err)
+			}
+			if m.ID != strconv.Itoa(id) {
+				t.Fatalf("direction %d: Next() returned message %s, expected %d", tt.direction, m.ID, id)
+			}
+			id += tt.step
+		}
+
+		if seen := (id - tt.first) * tt.step; seen != 150 {
+			t.Errorf("direction %d: iterated %d messages, expected 150", tt.direction, seen)
+		}
+		if requests != 2 {
+			t.Errorf("direction %d: made %d requests, expected 2", tt.direction, requests)
+		}
+
+		if _, err := it.Next(); err != io.EOF {
+			t.Errorf("direction %d: Next() after the end returned %v, expected io.EOF", tt.direction, err)
+		}
+	}
+}
+
+func TestMessageCustomEmojis(t *testing.T) {
+	m := &Message{Content: "<@user> in <#channel> for <@&role>: <:blob:1234> <a:party_parrot:5678> <:broken:> :plain:"}
+
+	emojis := m.CustomEmojis()
+	if len(emojis) != 2 {
+		t.Fatalf("CustomEmojis returned %d emojis, expected 2", len(emojis))
+	}
+	if e := emojis[0]; e.ID != "1234" || e.Name != "blob" || e.Animated {
+		t.Errorf("first emoji is %+v, expected blob 1234", e)
+	}
+	if e := emojis[1]; e.ID != "5678" || e.Name != "party_parrot" || !e.Animated {
+		t.Errorf("second emoji is %+v, expected animated party_parrot 5678", e)
+	}
+	if emojis[1].MessageFormat() != "<a:party_parrot:5678>" {
+		t.Errorf("MessageFormat is %q", emojis[1].MessageFormat())
+	}
+
+	// Replacing mentions leaves custom emojis untouched.
+	s := &Session{StateEnabled: true, State: NewState()}
+	s.State.GuildAdd(&Guild{ID: "guild"})
+	s.State.RoleAdd("guild", &Role{ID: "role", Name: "Role Name", Mentionable: true})
+	s.State.ChannelAdd(&Channel{ID: "channel", GuildID: "guild", Name: "Channel Name"})
+
+	m.ChannelID = "channel"
+	m.MentionRoles = []string{"role"}
+	m.Mentions = []*User{{ID: "user", Username: "User Name"}}
+
+	expected := "@User Name in #Channel Name for @Role Name: <:blob:1234> <a:party_parrot:5678> <:broken:> :plain:"
+	if result, _ := m.ContentWithMoreMentionsReplaced(s); result != expected {
+		t.Errorf("ContentWithMoreMentionsReplaced returned %q, expected %q", result, expected)
+	}
+}
+
+func TestMessageSendValidate(t *testing.T) {
+	field := func(value string) *MessageEmbedField {
+		return &MessageEmbedField{Name: "name", Value: value}
+	}
+
+	tests := []struct {
+		name string
+		data *MessageSend
+		err  string
+	}{
+		{"valid", &MessageSend{Content: strings.Repeat("a", 2000), Embed: &MessageEmbed{Title: "title", Fields: []*MessageEmbedField{field("value")}}}, ""},
+		{"multibyte content", &MessageSend{Content: strings.Repeat("é", 2000)}, ""},
+		{"content too long", &MessageSend{Content: strings.Repeat("a", 2001)}, "message content is 2001 characters long, the limit is 2000"},
+		{"too many embeds", &MessageSend{Embed: &MessageEmbed{}, Embeds: make([]*MessageEmbed, 10)}, "message has 11 embeds, the limit is 10"},
+		{"field value too long", &MessageSend{Embeds: []*MessageEmbed{{}, {Fields: []*MessageEmbedField{field("ok"), field(strings.Repeat("a", 1025))}}}}, "embed 1: field 1 value is 1025 characters long, the limit is 1024"},
+		{"embeds too long in total", &MessageSend{Embeds: []*MessageEmbed{{Description: strings.Repeat("a", 4000)}, {Description: strings.Repeat("a", 2001)}}}, "embeds are 6001 characters long in total, the limit is 6000"},
+	}
+
+	for _, tt := range tests {
+		err := tt.data.Validate()
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: Validate() returned error: %+v", tt.name, err)
+		}
+		if tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s: Validate() returned %v, expected %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestMessageEmbedValidate(t *testing.T) {
+	e := &MessageEmbed{Fields: make([]*MessageEmbedField, 26)}
+	if err := e.Validate(); err == nil || err.Error() != "embed has 26 fields, the limit is 25" {
+		t.Errorf("Validate() returned %v for 26 fields", err)
+	}
+
+	e = &MessageEmbed{Title: strings.Repeat("a", 256), Footer: &MessageEmbedFooter{Text: strings.Repeat("a", 2049)}}
+	if err := e.Validate(); err == nil || err.Error() != "footer text is 2049 characters long, the limit is 2048" {
+		t.Errorf("Validate() returned %v for a long footer", err)
+	}
+}
+
+func TestValidatePayloads(t *testing.T) {
+	requests := 0
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"id":"message"}`))
+	})
+
+	long := strings.Repeat("a", 2001)
+
+	// Without the flag, the message is sent and left for Discord to reject.
+	if _, err := s.ChannelMessageSend("channel", long); err != nil {
+		t.Fatalf("ChannelMessageSend returned error: %+v", err)
+	}
+
+	s.ValidatePayloads = true
+	if _, err := s.ChannelMessageSend("channel", long); err == nil {
+		t.Error("ChannelMessageSend did not return an error for over-length content")
+	}
+	if _, err := s.ChannelMessageEdit("channel", "message", long); err == nil {
+		t.Error("ChannelMessageEdit did not return an error for over-length content")
+	}
+
+	if requests != 1 {
+		t.Errorf("%d requests were made, expected 1", requests)
+	}
+}
+
+func TestChannelMessageSendSplit(t *testing.T) {
+	var sent []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		var data MessageSend
+		json.NewDecoder(r.Body).Decode(&data)
+		sent = append(sent, data.Content)
+		w.Write([]byte(`{"id":"` + strconv.Itoa(len(sent)) + `"}`))
+	})
+
+	var code []string
+	for i := 0; i < 120; i++ {
+		code = append(code, `fmt.Println("this is line `+strconv.Itoa(i)+`")`)
+	}
+	content := "Here is the output:\n```go\n" + strings.Join(code, "\n") + "\n```\nThat's all."
+
+	messages, err := s.ChannelMessageSendSplit("channel", content)
+	if err != nil {
+		t.Fatalf("ChannelMessageSendSplit returned error: %+v", err)
+	}
+	if len(messages) != 2 || len(sent) != 2 || messages[0].ID != "1" || messages[1].ID != "2" {
+		t.Fatalf("ChannelMessageSendSplit sent %d messages, returned %d, expected 2", len(sent), len(messages))
+	}
+
+	for i, m := range sent {
+		if n := len([]rune(m)); n > 2000 {
+			t.Errorf("message %d is %d characters long", i, n)
+		}
+		if strings.Count(m, "```")%2 != 0 {
+			t.Errorf("message %d leaves a code block open: %q", i, m)
+		}
+	}
+
+	first := strings.Split(sent[0], "\n")
+	second := strings.Split(sent[1], "\n")
+	if last := first[len(first)-1]; last != "```" {
+		t.Errorf("first message ends with %q, expected the code block to be closed", last)
+	}
+	if second[0] != "```go" {
+		t.Errorf("second message starts with %q, expected the code block to be reopened", second[0])
+	}
+
+	// Stripping the added fences gives back the original lines.
+	joined := append(first[:len(first)-1], second[1:]...)
+	if strings.Join(joined, "\n") != content {
+		t.Errorf("split messages do not add up to the content")
+	}
+
+	// Nothing is sent when there is nothing to split.
+	sent = nil
+	for _, content := range []string{"", " \n "} {
+		if messages, err = s.ChannelMessageSendSplit("channel", content); err != ErrEmptyMessage || len(messages) != 0 {
+			t.Errorf("ChannelMessageSendSplit(%q) returned %d messages and %v, expected ErrEmptyMessage", content, len(messages), err)
+		}
+	}
+	if len(sent) != 0 {
+		t.Errorf("ChannelMessageSendSplit sent %q for empty content", sent)
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	chunks := splitMessage("aaaa bbbb cccc", 10)
+	if len(chunks) != 2 || chunks[0] != "aaaa bbbb" || chunks[1] != "cccc" {
+		t.Errorf("splitMessage on spaces = %q", chunks)
+	}
+
+	chunks = splitMessage(strings.Repeat("a", 25), 10)
+	if len(chunks) != 3 || chunks[0] != strings.Repeat("a", 10) {
+		t.Errorf("splitMessage without separators = %q", chunks)
+	}
+	if strings.Join(chunks, "") != strings.Repeat("a", 25) {
+		t.Errorf("splitMessage lost content: %q", chunks)
+	}
+
+	chunks = splitMessage("short", 10)
+	if len(chunks) != 1 || chunks[0] != "short" {
+		t.Errorf("splitMessage on short content = %q", chunks)
+	}
+}
+
+func TestChunkEmbedFields(t *testing.T) {
+	var fields []*MessageEmbedField
+	for i := 0; i < 60; i++ {
+		fields = append(fields, &MessageEmbedField{Name: "field " + strconv.Itoa(i), Value: "value"})
+	}
+
+	embeds := ChunkEmbedFields(fields, 0)
+	if len(embeds) != 3 || len(embeds[0].Fields) != 25 || len(embeds[1].Fields) != 25 || len(embeds[2].Fields) != 10 {
+		t.Fatalf("ChunkEmbedFields split 60 fields into %d embeds", len(embeds))
+	}
+	if embeds[1].Fields[0] != fields[25] || embeds[2].Fields[9] != fields[59] {
+		t.Error("ChunkEmbedFields did not keep the fields in order")
+	}
+	for i, e := range embeds {
+		if err := e.Validate(); err != nil {
+			t.Errorf("embed %d is invalid: %+v", i, err)
+		}
+	}
+
+	if embeds = ChunkEmbedFields(fields, 10); len(embeds) != 6 {
+		t.Errorf("ChunkEmbedFields with 10 per embed returned %d embeds, expected 6", len(embeds))
+	}
+
+	// Long fields are split by the total length limit before the field limit.
+	for _, f := range fields {
+		f.Value = strings.Repeat("a", 1000)
+	}
+	embeds = ChunkEmbedFields(fields, 25)
+	if len(embeds) != 12 || len(embeds[0].Fields) != 5 {
+		t.Errorf("ChunkEmbedFields split 60 long fields into %d embeds, expected 12", len(embeds))
+	}
+	for i, e := range embeds {
+		if err := e.Validate(); err != nil {
+			t.Errorf("embed %d is invalid: %+v", i, err)
+		}
+	}
+
+	embeds = ChunkEmbedFields([]*MessageEmbedField{nil, {Name: "a", Value: "a"}, nil}, 25)
+	if len(embeds) != 1 || len(embeds[0].Fields) != 1 || embeds[0].Fields[0].Name != "a" {
+		t.Errorf("ChunkEmbedFields with nil fields returned %+v, expected one embed with field a", embeds)
+	}
+}
+
+func TestMessageAttachmentDownload(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("authorization") != "" {
+			t.Error("attachment was requested with the session token")
+		}
+
+		switch r.URL.Path {
+		case "/attachments/channel/file/log.txt":
+			w.Write([]byte("attachment contents"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}
+	})
+
+	a := &MessageAttachment{URL: "https://cdn.discordapp.com/attachments/channel/file/log.txt"}
+	r, err := a.Download(s)
+	if err != nil {
+		t.Fatalf("Download returned error: %+v", err)
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil || string(data) != "attachment contents" {
+		t.Errorf("Download returned %q, %v", data, err)
+	}
+
+	a = &MessageAttachment{URL: "https://cdn.discordapp.com/attachments/channel/file/missing.txt"}
+	if _, err = a.Download(s); err == nil {
+		t.Fatal("Download did not return an error for a missing attachment")
+	}
+	if restErr, ok := err.(*RESTError); !ok || restErr.Response.StatusCode != http.StatusNotFound {
+		t.Errorf("Download returned %v, expected a 404 RESTError", err)
+	}
+}
+
+func TestMessageIsSystemIsFromBot(t *testing.T) {
+	var join, bot *Message
+	json.Unmarshal([]byte(`{"type":7,"author":{"id":"user"}}`), &join)
+	json.Unmarshal([]byte(`{"type":19,"author":{"id":"bot","bot":true},"flags":4}`), &bot)
+
+	if join.Type != MessageTypeGuildMemberJoin || !join.IsSystem() || join.IsFromBot() {
+		t.Errorf("join message: type %d, IsSystem %t, IsFromBot %t", join.Type, join.IsSystem(), join.IsFromBot())
+	}
+	if bot.Type != MessageTypeReply || bot.IsSystem() || !bot.IsFromBot() {
+		t.Errorf("bot reply: type %d, IsSystem %t, IsFromBot %t", bot.Type, bot.IsSystem(), bot.IsFromBot())
+	}
+	if bot.Flags&MessageFlagsSuppressEmbeds == 0 {
+		t.Errorf("bot reply flags are %d, expected embeds to be suppressed", bot.Flags)
+	}
+
+	if (&Message{}).IsFromBot() {
+		t.Error("message without an author is from a bot")
+	}
+	for _, typ := range []MessageType{MessageTypeChannelPinnedMessage, MessageTypeThreadCreated, MessageTypeAutoModerationAction} {
+		if !typ.IsSystem() {
+			t.Errorf("message type %d is not a system message", typ)
+		}
+	}
+	if MessageTypeAutoModerationAction != 24 || MessageFlagsEphemeral != 64 || MessageFlagsSuppressNotifications != 4096 {
+		t.Error("message type or flag constants have the wrong values")
+	}
+}
+
+func TestMessageSnapshots(t *testing.T) {
+	var m Message
+	err := json.Unmarshal([]byte(`{
+		"id":"forward","channel_id":"channel","content":"",
+		"message_reference":{"type":1,"message_id":"original","channel_id":"other","guild_id":"guild"},
+		"message_snapshots":[{"message":{"type":0,"content":"look at this","embeds":[{"title":"news"}],"attachments":[{"id":"file","filename":"cat.png"}],"timestamp":"2024-01-01T00:00:00Z","flags":0}}]
+	}`), &m)
+	if err != nil {
+		t.Fatalf("error decoding message, %s", err)
+	}
+
+	if m.MessageReference == nil || m.MessageReference.Type != MessageReferenceTypeForward || m.MessageReference.MessageID != "original" {
+		t.Errorf("forward reference decoded as %+v", m.MessageReference)
+	}
+	if len(m.MessageSnapshots) != 1 || m.MessageSnapshots[0].Message == nil {
+		t.Fatalf("message snapshots decoded as %+v", m.MessageSnapshots)
+	}
+
+	forwarded := m.MessageSnapshots[0].Message
+	if forwarded.Content != "look at this" || len(forwarded.Embeds) != 1 || forwarded.Embeds[0].Title != "news" || len(forwarded.Attachments) != 1 || forwarded.Attachments[0].Filename != "cat.png" {
+		t.Errorf("forwarded message decoded as %+v", forwarded)
+	}
+}
+
+func TestPollCreateSerialization(t *testing.T) {
+	data, err := json.Marshal(&MessageSend{
+		Poll: &PollCreate{
+			Question: PollMedia{Text: "Pizza or pasta?"},
+			Answers: []PollAnswer{
+				{Media: &PollMedia{Text: "Pizza", Emoji: &PollEmoji{Name: "🍕"}}},
+				{Media: &PollMedia{Text: "Pasta", Emoji: &PollEmoji{ID: "emoji"}}},
+			},
+			Duration:         48,
+			AllowMultiselect: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("error encoding message, %s", err)
+	}
+
+	var m struct {
+		Poll map[string]interface{} `json:"poll"`
+	}
+	json.Unmarshal(data, &m)
+
+	expected := `{"allow_multiselect":true,"answers":[{"poll_media":{"emoji":{"name":"🍕"},"text":"Pizza"}},{"poll_media":{"emoji":{"id":"emoji"},"text":"Pasta"}}],"duration":48,"question":{"text":"Pizza or pasta?"}}`
+	if poll, _ := json.Marshal(m.Poll); string(poll) != expected {
+		t.Errorf("poll was encoded as %s, expected %s", poll, expected)
+	}
+}
+
+func TestPollAnswerVoters(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v6/channels/channel/polls/message/answers/2":
+			w.Write([]byte(`{"users":[{"id":"a"},{"id":"b"}]}`))
+		case "POST /api/v6/channels/channel/polls/message/expire":
+			w.Write([]byte(`{"id":"message","poll":{"question":{"text":"?"},"answers":[{"answer_id":1,"poll_media":{"text":"yes"}}],"results":{"is_finalized":true,"answer_counts":[{"id":1,"count":3,"me_voted":true}]}}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	voters, err := s.PollAnswerVoters("channel", "message", 2)
+	if err != nil {
+		t.Fatalf("PollAnswerVoters returned error: %+v", err)
+	}
+	if len(voters) != 2 || voters[0].ID != "a" || voters[1].ID != "b" {
+		t.Errorf("PollAnswerVoters returned %+v", voters)
+	}
+
+	m, err := s.PollExpire("channel", "message")
+	if err != nil {
+		t.Fatalf("PollExpire returned error: %+v", err)
+	}
+	if m.Poll == nil || len(m.Poll.Answers) != 1 || m.Poll.Answers[0].AnswerID != 1 || m.Poll.Results == nil || !m.Poll.Results.Finalized {
+		t.Fatalf("PollExpire returned poll %+v", m.Poll)
+	}
+	if c := m.Poll.Results.AnswerCounts[0]; c.ID != 1 || c.Count != 3 || !c.MeVoted {
+		t.Errorf("poll answer count decoded as %+v", c)
+	}
+}
+
+func TestColorFromHex(t *testing.T) {
+	tests := []struct {
+		hex      string
+		expected int
+	}{
+		{"#5865F2", ColorBlurple},
+		{"5865f2", ColorBlurple},
+		{"#ED4245", ColorRed},
+		{"#000000", ColorDefault},
+		{"FFFFFF", ColorWhite},
+	}
+	for _, tt := range tests {
+		color, err := ColorFromHex(tt.hex)
+		if err != nil {
+			t.Errorf("ColorFromHex(%q) returned error: %+v", tt.hex, err)
+		}
+		if color != tt.expected {
+			t.Errorf("ColorFromHex(%q) = %#x, expected %#x", tt.hex, color, tt.expected)
+		}
+	}
+
+	for _, hex := range []string{"", "#", "#FFF", "FFFFFFF", "##FFFFFF", "#GGGGGG", "+12345", "0x1234"} {
+		if _, err := ColorFromHex(hex); err == nil {
+			t.Errorf("ColorFromHex(%q) returned no error", hex)
+		}
+	}
+}
+
+func TestColorConstants(t *testing.T) {
+	colors := map[string]int{
+		"blurple": ColorBlurple,
+		"green":   ColorGreen,
+		"yellow":  ColorYellow,
+		"fuchsia": ColorFuchsia,
+		"red":     ColorRed,
+	}
+	expected := map[string]int{
+		"blurple": 0x5865F2,
+		"green":   0x57F287,
+		"yellow":  0xFEE75C,
+		"fuchsia": 0xEB459E,
+		"red":     0xED4245,
+	}
+	for name, c := range colors {
+		if c != expected[name] {
+			t.Errorf("color %s is %#x, expected %#x", name, c, expected[name])
+		}
+	}
+}
+
+func TestMentionHelpers(t *testing.T) {
+	tests := []struct {
+		mention, expected string
+	}{
+		{UserMention("80351110224678912"), "<@80351110224678912>"},
+		{RoleMention("165511591545143296"), "<@&165511591545143296>"},
+		{ChannelMention("103735883630395392"), "<#103735883630395392>"},
+		{CommandMention("ping", "1234"), "</ping:1234>"},
+		{CommandMention("config set", "1234"), "</config set:1234>"},
+	}
+	for _, tt := range tests {
+		if tt.mention != tt.expected {
+			t.Errorf("mention is %s, expected %s", tt.mention, tt.expected)
+		}
+	}
+}
@@ -9,6 +9,12 @@
 
 package discordgo
 
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
 // ------------------------------------------------------------------------------------------------
 // Code specific to Discord OAuth2 Applications
 // ------------------------------------------------------------------------------------------------
@@ -27,10 +33,24 @@ type Application struct {
 	Flags               int       `json:"flags,omitempty"`
 	Owner               *User     `json:"owner"`
 	Bot                 *User     `json:"bot"`
+
+	// The tags describing the application, and the settings of the
+	// default in-app authorization link.
+	Tags             []string                  `json:"tags,omitempty"`
+	InstallParams    *ApplicationInstallParams `json:"install_params,omitempty"`
+	CustomInstallURL string                    `json:"custom_install_url,omitempty"`
+}
+
+// ApplicationInstallParams holds the scopes and permissions an Application
+// is added to a guild with by its default authorization link.
+type ApplicationInstallParams struct {
+	Scopes      []string `json:"scopes"`
+	Permissions int64    `json:"permissions,string"`
 }
 
 // Application returns an Application structure of a specific Application
-//   appID : The ID of an Application
+//   appID : The ID of an Application, or "@me" for the application of the
+//           current bot
 func (s *Session) Application(appID string) (st *Application, err error) {
 
 	body, err := s.RequestWithBucketID("GET", EndpointApplication(appID), nil, EndpointApplication(""))
@@ -105,6 +125,27 @@ func (s *Session) ApplicationDelete(appID string) (err error) {
 	return
 }
 
+// InviteURL returns an OAuth2 authorization URL that adds a bot to a guild.
+//   clientID    : The client ID of the Application of the bot
+//   permissions : The permissions the bot is granted, such as PermissionSendMessages
+//   scopes      : The scopes to authorize, "bot" if empty
+//   guildID     : The ID of the guild selected by default, may be empty
+func InviteURL(clientID string, permissions int64, scopes []string, guildID string) string {
+	if len(scopes) == 0 {
+		scopes = []string{"bot"}
+	}
+
+	v := url.Values{}
+	v.Set("client_id", clientID)
+	v.Set("permissions", strconv.FormatInt(permissions, 10))
+	v.Set("scope", strings.Join(scopes, " "))
+	if guildID != "" {
+		v.Set("guild_id", guildID)
+	}
+
+	return EndpointOauth2Authorize + "?" + v.Encode()
+}
+
 // ------------------------------------------------------------------------------------------------
 // Code specific to Discord OAuth2 Application Bots
 // ------------------------------------------------------------------------------------------------
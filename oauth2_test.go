@@ -2,9 +2,11 @@ package discordgo_test
 
 import (
 	"log"
+	"net/url"
 	"os"
+	"testing"
 
-	"github.com/polarbirds/discordgo"
+	"github.com/bwmarrin/discordgo"
 )
 
 func ExampleApplication() {
@@ -55,3 +57,42 @@ func ExampleApplication() {
 
 	return
 }
+
+func TestInviteURL(t *testing.T) {
+	u, err := url.Parse(discordgo.InviteURL("client", discordgo.PermissionSendMessages|discordgo.PermissionManageRoles, []string{"bot", "applications.commands"}, "guild"))
+	if err != nil {
+		t.Fatalf("InviteURL returned an invalid URL: %+v", err)
+	}
+	if u.Scheme+"://"+u.Host+u.Path != discordgo.EndpointOauth2Authorize {
+		t.Errorf("InviteURL points at %s", u)
+	}
+
+	q := u.Query()
+	expected := map[string]string{
+		"client_id":   "client",
+		"permissions": "268437504",
+		"scope":       "bot applications.commands",
+		"guild_id":    "guild",
+	}
+	for k, v := range expected {
+		if q.Get(k) != v {
+			t.Errorf("InviteURL query %s is %q, expected %q", k, q.Get(k), v)
+		}
+	}
+
+	q = mustQuery(t, discordgo.InviteURL("client", 0, nil, ""))
+	if q.Get("scope") != "bot" || q.Get("permissions") != "0" {
+		t.Errorf("InviteURL without scopes has query %v", q)
+	}
+	if _, ok := q["guild_id"]; ok {
+		t.Errorf("InviteURL without a guild has query %v", q)
+	}
+}
+
+func mustQuery(t *testing.T, rawURL string) url.Values {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("invalid URL %s: %+v", rawURL, err)
+	}
+	return u.Query()
+}
@@ -16,6 +16,7 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	_ "image/gif"  // For GIF decoding
 	_ "image/jpeg" // For JPEG decoding
 	_ "image/png"  // For PNG decoding
 	"io"
@@ -25,8 +26,11 @@ import (
 	"net/http"
 	"net/textproto"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -38,12 +42,106 @@ var (
 	ErrPruneDaysBounds         = errors.New("the number of days should be more than or equal to 1")
 	ErrGuildNoIcon             = errors.New("guild does not have an icon set")
 	ErrGuildNoSplash           = errors.New("guild does not have a splash set")
+	ErrNilReference            = errors.New("reply attempted with a nil message reference")
+	ErrNilForumThread          = errors.New("forum thread started with nil params")
+	ErrUnsupportedImageFormat  = errors.New("image is not in a supported format, expected PNG, JPEG or GIF")
+	ErrInvalidWebhookURL       = errors.New("URL is not a Discord webhook URL")
+	ErrNotBanned               = errors.New("user is not banned from the guild")
+	ErrEmptyMessage            = errors.New("cannot send an empty message")
 	ErrUnauthorized            = errors.New("HTTP request was unauthorized. This could be because the provided token was not a bot token. Please add \"Bot \" to the start of your token. https://discordapp.com/developers/docs/reference#authentication-example-bot-token-authorization-header")
 )
 
-// Request is the same as RequestWithBucketID but the bucket id is the same as the urlStr
-func (s *Session) Request(method, urlStr string, data interface{}) (response []byte, err error) {
-	return s.RequestWithBucketID(method, urlStr, data, strings.SplitN(urlStr, "?", 2)[0])
+// DefaultUserAgent is the User-Agent sent to Discord when Session.UserAgent
+// is not set.
+var DefaultUserAgent = "DiscordBot (https://github.com/polarbirds/discordgo, v" + VERSION + ")"
+
+// userAgent returns the User-Agent the session sends to Discord.
+func (s *Session) userAgent() string {
+	if s.UserAgent != "" {
+		return s.UserAgent
+	}
+	return DefaultUserAgent
+}
+
+// A RequestOption changes a request made with Request.
+type RequestOption func(*requestOptions)
+
+// requestOptions holds the options of a request made with Request.
+type requestOptions struct {
+	bucketID string
+	header   http.Header
+}
+
+// WithHeader sets a header of the request.
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		if o.header == nil {
+			o.header = http.Header{}
+		}
+		o.header.Set(key, value)
+	}
+}
+
+// WithAuditLogReason records reason in the audit log of the guild.
+func WithAuditLogReason(reason string) RequestOption {
+	return WithHeader("X-Audit-Log-Reason", url.PathEscape(reason))
+}
+
+// WithBucketID sets the rate limit bucket of the request, which is the URL
+// without its query by default.
+func WithBucketID(bucketID string) RequestOption {
+	return func(o *requestOptions) {
+		o.bucketID = bucketID
+	}
+}
+
+// Request makes a (GET/POST/...) request to the Discord REST API with data
+// encoded as JSON, and returns the raw response. It can be used for endpoints
+// not covered by this package, the request is authenticated and rate limited
+// like the others.
+// method : The HTTP method of the request.
+// urlStr : The URL of the endpoint, or its path relative to EndpointAPI, e.g. "users/@me".
+// data   : The data sent as JSON, none if nil.
+// opts   : The options of the request, such as WithAuditLogReason.
+func (s *Session) Request(method, urlStr string, data interface{}, opts ...RequestOption) (response []byte, err error) {
+	o := &requestOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if !strings.Contains(urlStr, "://") {
+		urlStr = EndpointAPI + strings.TrimPrefix(urlStr, "/")
+	}
+
+	b := &requestBody{header: o.header}
+	if data != nil {
+		b.data, err = json.Marshal(data)
+		if err != nil {
+			return
+		}
+	}
+
+	bucketID := o.bucketID
+	if bucketID == "" {
+		bucketID = strings.SplitN(urlStr, "?", 2)[0]
+	}
+	return s.requestWithLockedBucket(method, urlStr, "application/json", b, s.Ratelimiter.LockBucket(bucketID), 0)
+}
+
+// RequestJSON makes a request like Request, and decodes the JSON response
+// into a new T, e.g. RequestJSON[User](s, "GET", "users/@me", nil).
+// method : The HTTP method of the request.
+// urlStr : The URL of the endpoint, or its path relative to EndpointAPI.
+// data   : The data sent as JSON, none if nil.
+// opts   : The options of the request, such as WithAuditLogReason.
+func RequestJSON[T any](s *Session, method, urlStr string, data interface{}, opts ...RequestOption) (st *T, err error) {
+	body, err := s.Request(method, urlStr, data, opts...)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
 }
 
 // RequestWithBucketID makes a (GET/POST/...) Requests to Discord REST API with JSON data.
@@ -71,17 +169,62 @@ func (s *Session) request(method, urlStr, contentType string, b []byte, bucketID
 
 // RequestWithLockedBucket makes a request using a bucket that's already been locked
 func (s *Session) RequestWithLockedBucket(method, urlStr, contentType string, b []byte, bucket *Bucket, sequence int) (response []byte, err error) {
+	return s.requestWithLockedBucket(method, urlStr, contentType, &requestBody{data: b}, bucket, sequence)
+}
+
+// requestBody is the body of a REST request. It is either held in memory, or
+// produced by stream every time the request is sent, so large uploads are
+// not buffered and can still be retried.
+type requestBody struct {
+	data   []byte
+	stream func() (io.Reader, error)
+
+	// Additional headers sent with the request.
+	header http.Header
+}
+
+// reader returns a reader for a new copy of the body.
+func (b *requestBody) reader() (io.Reader, error) {
+	if b.stream != nil {
+		return b.stream()
+	}
+	return bytes.NewReader(b.data), nil
+}
+
+// String returns the body for debug logging.
+func (b *requestBody) String() string {
+	if b.stream != nil {
+		return "<streamed>"
+	}
+	return string(b.data)
+}
+
+// requestWithLockedBucket makes a request with the given body using a bucket
+// that's already been locked.
+func (s *Session) requestWithLockedBucket(method, urlStr, contentType string, b *requestBody, bucket *Bucket, sequence int) (response []byte, err error) {
+	s.active.add()
+	defer s.active.done()
+
 	if s.Debug {
 		log.Printf("API REQUEST %8s :: %s\n", method, urlStr)
-		log.Printf("API REQUEST  PAYLOAD :: [%s]\n", string(b))
+		log.Printf("API REQUEST  PAYLOAD :: [%s]\n", b)
 	}
 
-	req, err := http.NewRequest(method, urlStr, bytes.NewBuffer(b))
+	body, err := b.reader()
 	if err != nil {
 		bucket.Release(nil)
 		return
 	}
 
+	req, err := http.NewRequest(method, urlStr, body)
+	if err != nil {
+		if c, ok := body.(io.Closer); ok {
+			c.Close()
+		}
+		bucket.Release(nil)
+		return
+	}
+
 	// Not used on initial login..
 	// TODO: Verify if a login, otherwise complain about no-token
 	if s.Token != "" {
@@ -89,8 +232,20 @@ func (s *Session) RequestWithLockedBucket(method, urlStr, contentType string, b
 	}
 
 	req.Header.Set("Content-Type", contentType)
-	// TODO: Make a configurable static variable.
-	req.Header.Set("User-Agent", "DiscordBot (https://github.com/polarbirds/discordgo, v"+VERSION+")")
+	req.Header.Set("User-Agent", s.userAgent())
+
+	for k, v := range b.header {
+		req.Header[k] = v
+	}
+
+	// Ask Discord to skip the body if it has not changed since it was cached.
+	var cached *etagCacheEntry
+	if s.CacheETags && s.etags != nil && method == "GET" {
+		cached = s.etags.get(urlStr)
+		if cached != nil {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+	}
 
 	if s.Debug {
 		for k, v := range req.Header {
@@ -98,6 +253,8 @@ func (s *Session) RequestWithLockedBucket(method, urlStr, contentType string, b
 		}
 	}
 
+	countKey(&s.stats.requests, method)
+
 	resp, err := s.Client.Do(req)
 	if err != nil {
 		bucket.Release(nil)
@@ -129,8 +286,17 @@ func (s *Session) RequestWithLockedBucket(method, urlStr, contentType string, b
 		log.Printf("API RESPONSE    BODY :: [%s]\n\n\n", response)
 	}
 
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		return cached.body, nil
+	}
+
 	switch resp.StatusCode {
 	case http.StatusOK:
+		if s.CacheETags && s.etags != nil && method == "GET" {
+			if etag := resp.Header.Get("ETag"); etag != "" {
+				s.etags.set(urlStr, etag, response, s.MaxETagCacheCount)
+			}
+		}
 	case http.StatusCreated:
 	case http.StatusNoContent:
 	case http.StatusBadGateway:
@@ -138,7 +304,7 @@ func (s *Session) RequestWithLockedBucket(method, urlStr, contentType string, b
 		if sequence < s.MaxRestRetries {
 
 			s.log(LogInformational, "%s Failed (%s), Retrying...", urlStr, resp.Status)
-			response, err = s.RequestWithLockedBucket(method, urlStr, contentType, b, s.Ratelimiter.LockBucketObject(bucket), sequence+1)
+			response, err = s.requestWithLockedBucket(method, urlStr, contentType, b, s.Ratelimiter.LockBucketObject(bucket), sequence+1)
 		} else {
 			err = fmt.Errorf("Exceeded Max retries HTTP %s, %s", resp.Status, response)
 		}
@@ -150,16 +316,17 @@ func (s *Session) RequestWithLockedBucket(method, urlStr, contentType string, b
 			return
 		}
 		s.log(LogInformational, "Rate Limiting %s, retry in %d", urlStr, rl.RetryAfter)
+		atomic.AddInt64(&s.stats.rateLimits, 1)
 		s.handleEvent(rateLimitEventType, RateLimit{TooManyRequests: &rl, URL: urlStr})
 
 		time.Sleep(rl.RetryAfter * time.Millisecond)
 		// we can make the above smarter
 		// this method can cause longer delays than required
 
-		response, err = s.RequestWithLockedBucket(method, urlStr, contentType, b, s.Ratelimiter.LockBucketObject(bucket), sequence)
+		response, err = s.requestWithLockedBucket(method, urlStr, contentType, b, s.Ratelimiter.LockBucketObject(bucket), sequence)
 	case http.StatusUnauthorized:
 		if strings.Index(s.Token, "Bot ") != 0 {
-			s.log(LogInformational, ErrUnauthorized.Error())
+			s.log(LogInformational, "%s", ErrUnauthorized.Error())
 			err = ErrUnauthorized
 		}
 		fallthrough
@@ -170,6 +337,141 @@ func (s *Session) RequestWithLockedBucket(method, urlStr, contentType string, b
 	return
 }
 
+// ErrFileNotSeekable is returned when an upload has to be sent again, e.g.
+// after being rate limited, but one of its files can not be rewound.
+var ErrFileNotSeekable = errors.New("file reader was already read and does not implement io.Seeker")
+
+// errRequestRetried stops the body of a multipart request which is retried.
+var errRequestRetried = errors.New("request retried")
+
+// multipartBody returns the content type and body of a multipart request
+// holding data as its JSON payload and the given files. The files are not
+// read until the request is sent, and are then streamed to Discord.
+func multipartBody(data interface{}, files []*File) (contentType string, body *requestBody, err error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	// Each attempt gets a new writer, the boundary must stay the same
+	// as it is part of the content type.
+	boundary := multipart.NewWriter(nil).Boundary()
+
+	// The pipe and the end of the writer of the previous attempt, which
+	// must stop reading the files before they are rewound.
+	var pr *io.PipeReader
+	var done chan struct{}
+
+	// The offset of each file when it was first read, which it is rewound
+	// to, or -1 if it cannot be rewound.
+	offsets := make([]int64, len(files))
+
+	body = &requestBody{stream: func() (io.Reader, error) {
+		if pr == nil {
+			for i, file := range files {
+				offsets[i] = -1
+				if seeker, ok := file.Reader.(io.Seeker); ok {
+					if offset, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+						offsets[i] = offset
+					}
+				}
+			}
+		} else {
+			pr.CloseWithError(errRequestRetried)
+			<-done
+
+			for i, file := range files {
+				if offsets[i] < 0 {
+					return nil, ErrFileNotSeekable
+				}
+				if _, err := file.Reader.(io.Seeker).Seek(offsets[i], io.SeekStart); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		var pw *io.PipeWriter
+		pr, pw = io.Pipe()
+		done = make(chan struct{})
+		go func(done chan struct{}) {
+			defer close(done)
+			pw.CloseWithError(writeMultipart(pw, boundary, payload, files))
+		}(done)
+		return pr, nil
+	}}
+
+	contentType = "multipart/form-data; boundary=" + boundary
+	return
+}
+
+// writeMultipart writes a multipart body with the given boundary to w.
+func writeMultipart(w io.Writer, boundary string, payload []byte, files []*File) (err error) {
+	bodywriter := multipart.NewWriter(w)
+	if err = bodywriter.SetBoundary(boundary); err != nil {
+		return
+	}
+
+	var p io.Writer
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="payload_json"`)
+	h.Set("Content-Type", "application/json")
+
+	p, err = bodywriter.CreatePart(h)
+	if err != nil {
+		return
+	}
+
+	if _, err = p.Write(payload); err != nil {
+		return
+	}
+
+	for i, file := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files[%d]"; filename="%s"`, i, quoteEscaper.Replace(file.Name)))
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h.Set("Content-Type", contentType)
+
+		p, err = bodywriter.CreatePart(h)
+		if err != nil {
+			return
+		}
+
+		if _, err = io.Copy(p, file.Reader); err != nil {
+			return
+		}
+	}
+
+	return bodywriter.Close()
+}
+
+// requestMultipart makes a multipart request with data as its JSON payload
+// and the given files.
+func (s *Session) requestMultipart(method, urlStr string, data interface{}, files []*File, bucketID string) (response []byte, err error) {
+	contentType, body, err := multipartBody(data, files)
+	if err != nil {
+		return
+	}
+
+	if bucketID == "" {
+		bucketID = strings.SplitN(urlStr, "?", 2)[0]
+	}
+	return s.requestWithLockedBucket(method, urlStr, contentType, body, s.Ratelimiter.LockBucket(bucketID), 0)
+}
+
+// requestWithReason makes a JSON request like RequestWithBucketID, recording
+// reason in the audit log of the guild if it is not empty.
+func (s *Session) requestWithReason(method, urlStr string, data interface{}, bucketID, reason string) (response []byte, err error) {
+	opts := []RequestOption{WithBucketID(bucketID)}
+	if reason != "" {
+		opts = append(opts, WithAuditLogReason(reason))
+	}
+	return s.Request(method, urlStr, data, opts...)
+}
+
 func unmarshal(data []byte, v interface{}) error {
 	err := json.Unmarshal(data, v)
 	if err != nil {
@@ -292,15 +594,22 @@ func (s *Session) UserAvatar(userID string) (img image.Image, err error) {
 	return
 }
 
-// UserAvatarDecode returns an image.Image of a user's Avatar
+// UserAvatarDecode returns an image.Image of a user's Avatar. Animated
+// avatars are decoded as their first frame, and users without an avatar
+// get the default avatar.
+// ErrUnsupportedImageFormat is returned if the avatar is not a PNG, JPEG or
+// GIF image.
 // user : The user which avatar should be retrieved
 func (s *Session) UserAvatarDecode(u *User) (img image.Image, err error) {
-	body, err := s.RequestWithBucketID("GET", EndpointUserAvatar(u.ID, u.Avatar), nil, EndpointUserAvatar("", ""))
+	body, err := s.RequestWithBucketID("GET", u.AvatarURL(""), nil, EndpointUserAvatar("", ""))
 	if err != nil {
 		return
 	}
 
 	img, _, err = image.Decode(bytes.NewReader(body))
+	if err == image.ErrFormat {
+		err = ErrUnsupportedImageFormat
+	}
 	return
 }
 
@@ -461,14 +770,18 @@ func (s *Session) UserGuildSettingsEdit(guildID string, settings *UserGuildSetti
 // NOTE: This function is now deprecated and will be removed in the future.
 // Please see the same function inside state.go
 func (s *Session) UserChannelPermissions(userID, channelID string) (apermissions int, err error) {
+	// Without state tracking, State is empty, so everything is fetched.
+	// State methods handle a nil State.
+	state, _ := s.state()
+
 	// Try to just get permissions from state.
-	apermissions, err = s.State.UserChannelPermissions(userID, channelID)
+	apermissions, err = state.UserChannelPermissions(userID, channelID)
 	if err == nil {
 		return
 	}
 
 	// Otherwise try get as much data from state as possible, falling back to the network.
-	channel, err := s.State.Channel(channelID)
+	channel, err := state.Channel(channelID)
 	if err != nil || channel == nil {
 		channel, err = s.Channel(channelID)
 		if err != nil {
@@ -476,7 +789,7 @@ func (s *Session) UserChannelPermissions(userID, channelID string) (apermissions
 		}
 	}
 
-	guild, err := s.State.Guild(channel.GuildID)
+	guild, err := state.Guild(channel.GuildID)
 	if err != nil || guild == nil {
 		guild, err = s.Guild(channel.GuildID)
 		if err != nil {
@@ -489,7 +802,7 @@ func (s *Session) UserChannelPermissions(userID, channelID string) (apermissions
 		return
 	}
 
-	member, err := s.State.Member(guild.ID, userID)
+	member, err := state.Member(guild.ID, userID)
 	if err != nil || member == nil {
 		member, err = s.GuildMember(guild.ID, userID)
 		if err != nil {
@@ -500,6 +813,71 @@ func (s *Session) UserChannelPermissions(userID, channelID string) (apermissions
 	return memberPermissions(guild, channel, member), nil
 }
 
+// CanActOn returns whether a member is above another in the role hierarchy of
+// a guild, and can therefore e.g. kick, ban or edit the roles of them. The
+// guild owner is above every other member, and otherwise the member whose
+// highest role is positioned higher wins. Members whose highest roles are
+// equal can not act on each other. The guild and members are read from State,
+// ErrStateDisabled is returned when state tracking is disabled.
+// guildID   : The ID of a Guild.
+// actorID   : The ID of the user who wants to act.
+// targetID  : The ID of the user who is acted on.
+func (s *Session) CanActOn(guildID, actorID, targetID string) (can bool, err error) {
+	state, err := s.state()
+	if err != nil {
+		return
+	}
+
+	guild, err := state.Guild(guildID)
+	if err != nil {
+		return
+	}
+
+	if targetID == guild.OwnerID {
+		return false, nil
+	}
+	if actorID == guild.OwnerID {
+		return true, nil
+	}
+
+	actor, err := state.Member(guildID, actorID)
+	if err != nil {
+		return
+	}
+
+	target, err := state.Member(guildID, targetID)
+	if err != nil {
+		return
+	}
+
+	state.RLock()
+	defer state.RUnlock()
+
+	return rolePosition(memberHighestRole(guild, actor)) > rolePosition(memberHighestRole(guild, target)), nil
+}
+
+// memberHighestRole returns the highest positioned role of a member, or nil
+// if they have no roles besides @everyone.
+func memberHighestRole(guild *Guild, member *Member) (highest *Role) {
+	for _, role := range guild.Roles {
+		for _, roleID := range member.Roles {
+			if role.ID == roleID && (highest == nil || role.Position > highest.Position) {
+				highest = role
+			}
+		}
+	}
+
+	return
+}
+
+// rolePosition returns the position of a role, where nil is @everyone.
+func rolePosition(role *Role) int {
+	if role == nil {
+		return 0
+	}
+	return role.Position
+}
+
 // Calculates the permissions for a member.
 // https://support.discordapp.com/hc/en-us/articles/206141927-How-is-the-permission-hierarchy-structured-
 func memberPermissions(guild *Guild, channel *Channel, member *Member) (apermissions int) {
@@ -595,6 +973,21 @@ func (s *Session) Guild(guildID string) (st *Guild, err error) {
 	return
 }
 
+// GuildWithCounts returns a Guild structure of a specific Guild, with its
+// approximate member and presence counts. The guild is always fetched from
+// Discord, as the counts are not kept in State.
+// guildID   : The ID of a Guild
+func (s *Session) GuildWithCounts(guildID string) (st *Guild, err error) {
+
+	body, err := s.RequestWithBucketID("GET", EndpointGuild(guildID)+"?with_counts=true", nil, EndpointGuild(guildID))
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
 // GuildCreate creates a new Guild
 // name      : A name for the Guild (2-100 characters)
 func (s *Session) GuildCreate(name string) (st *Guild, err error) {
@@ -676,11 +1069,38 @@ func (s *Session) GuildLeave(guildID string) (err error) {
 }
 
 // GuildBans returns an array of GuildBan structures for all bans of a
-// given guild.
+// given guild. Discord returns at most 1000 bans, use GuildBansPage to
+// page through the bans of larger guilds.
 // guildID   : The ID of a Guild.
 func (s *Session) GuildBans(guildID string) (st []*GuildBan, err error) {
+	return s.GuildBansPage(guildID, "", "", 0)
+}
+
+// GuildBansPage returns a page of the bans of a guild, sorted by user ID.
+// guildID   : The ID of a Guild.
+// beforeID  : If provided, only bans of users with a lower ID are returned.
+// afterID   : If provided, only bans of users with a higher ID are returned.
+// limit     : The max number of bans to return (max 1000), Discord's default if 0.
+func (s *Session) GuildBansPage(guildID, beforeID, afterID string, limit int) (st []*GuildBan, err error) {
+
+	uri := EndpointGuildBans(guildID)
+
+	v := url.Values{}
+	if beforeID != "" {
+		v.Set("before", beforeID)
+	}
+	if afterID != "" {
+		v.Set("after", afterID)
+	}
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
 
-	body, err := s.RequestWithBucketID("GET", EndpointGuildBans(guildID), nil, EndpointGuildBans(guildID))
+	if len(v) > 0 {
+		uri += "?" + v.Encode()
+	}
+
+	body, err := s.RequestWithBucketID("GET", uri, nil, EndpointGuildBans(guildID))
 	if err != nil {
 		return
 	}
@@ -690,6 +1110,24 @@ func (s *Session) GuildBans(guildID string) (st []*GuildBan, err error) {
 	return
 }
 
+// GuildBan returns the ban of a user in a guild. ErrNotBanned is returned
+// if the user is not banned.
+// guildID   : The ID of a Guild.
+// userID    : The ID of a User
+func (s *Session) GuildBan(guildID, userID string) (st *GuildBan, err error) {
+
+	body, err := s.RequestWithBucketID("GET", EndpointGuildBan(guildID, userID), nil, EndpointGuildBan(guildID, ""))
+	if err != nil {
+		if restErr, ok := err.(*RESTError); ok && restErr.Message != nil && restErr.Message.Code == ErrCodeUnknownBan {
+			err = ErrNotBanned
+		}
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
 // GuildBanCreate bans the given user from the given guild.
 // guildID   : The ID of a Guild.
 // userID    : The ID of a User
@@ -723,6 +1161,37 @@ func (s *Session) GuildBanCreateWithReason(guildID, userID, reason string, days
 	return
 }
 
+// GuildBulkBan bans up to 200 users from the given guild at once, returning
+// the IDs of the users who were banned and of those who could not be.
+// guildID              : The ID of a Guild.
+// userIDs              : The IDs of the users to ban (max 200).
+// deleteMessageSeconds : The number of seconds of previous messages to delete (max 604800).
+// reason               : The reason shown in the audit log, or empty for none.
+func (s *Session) GuildBulkBan(guildID string, userIDs []string, deleteMessageSeconds int, reason string) (banned, failed []string, err error) {
+	if len(userIDs) > 200 {
+		err = fmt.Errorf("cannot ban more than 200 users at once, got %d", len(userIDs))
+		return
+	}
+
+	data := struct {
+		UserIDs              []string `json:"user_ids"`
+		DeleteMessageSeconds int      `json:"delete_message_seconds,omitempty"`
+	}{userIDs, deleteMessageSeconds}
+
+	body, err := s.requestWithReason("POST", EndpointGuildBulkBan(guildID), data, EndpointGuildBulkBan(guildID), reason)
+	if err != nil {
+		return
+	}
+
+	var st struct {
+		BannedUsers []string `json:"banned_users"`
+		FailedUsers []string `json:"failed_users"`
+	}
+
+	err = unmarshal(body, &st)
+	return st.BannedUsers, st.FailedUsers, err
+}
+
 // GuildBanDelete removes the given user from the guild bans
 // guildID   : The ID of a Guild.
 // userID    : The ID of a User
@@ -814,15 +1283,10 @@ func (s *Session) GuildMemberDelete(guildID, userID string) (err error) {
 // GuildMemberDeleteWithReason removes the given user from the given guild.
 // guildID   : The ID of a Guild.
 // userID    : The ID of a User
-// reason    : The reason for the kick
+// reason    : The reason for the kick, shown in the audit log
 func (s *Session) GuildMemberDeleteWithReason(guildID, userID, reason string) (err error) {
 
-	uri := EndpointGuildMember(guildID, userID)
-	if reason != "" {
-		uri += "?reason=" + url.QueryEscape(reason)
-	}
-
-	_, err = s.RequestWithBucketID("DELETE", uri, nil, EndpointGuildMember(guildID, ""))
+	_, err = s.requestWithReason("DELETE", EndpointGuildMember(guildID, userID), nil, EndpointGuildMember(guildID, ""), reason)
 	return
 }
 
@@ -864,6 +1328,34 @@ func (s *Session) GuildMemberMove(guildID, userID, channelID string) (err error)
 	return
 }
 
+// GuildMemberMute server mutes or unmutes a guild member in voice channels
+// guildID   : The ID of a Guild.
+// userID    : The ID of a User.
+// mute      : Whether the member should be muted.
+func (s *Session) GuildMemberMute(guildID, userID string, mute bool) (err error) {
+
+	data := struct {
+		Mute bool `json:"mute"`
+	}{mute}
+
+	_, err = s.RequestWithBucketID("PATCH", EndpointGuildMember(guildID, userID), data, EndpointGuildMember(guildID, ""))
+	return
+}
+
+// GuildMemberDeafen server deafens or undeafens a guild member in voice channels
+// guildID   : The ID of a Guild.
+// userID    : The ID of a User.
+// deaf      : Whether the member should be deafened.
+func (s *Session) GuildMemberDeafen(guildID, userID string, deaf bool) (err error) {
+
+	data := struct {
+		Deaf bool `json:"deaf"`
+	}{deaf}
+
+	_, err = s.RequestWithBucketID("PATCH", EndpointGuildMember(guildID, userID), data, EndpointGuildMember(guildID, ""))
+	return
+}
+
 // GuildMemberNickname updates the nickname of a guild member
 // guildID   : The ID of a guild
 // userID    : The ID of a user
@@ -893,6 +1385,47 @@ func (s *Session) GuildMemberRoleAdd(guildID, userID, roleID string) (err error)
 	return
 }
 
+// GuildMembersAddRole adds the specified role to many members, making at most
+// concurrency requests at a time. The returned map holds the error of every
+// member the role could not be added to, and is empty if all succeeded.
+// The requests still share one rate limit bucket, so concurrency bounds how
+// many goroutines wait on it rather than speeding up the requests themselves.
+// guildID     : The ID of a Guild.
+// roleID      : The ID of a Role to be assigned to the users.
+// userIDs     : The IDs of the Users.
+// concurrency : The maximum number of requests in flight, at least 1.
+func (s *Session) GuildMembersAddRole(guildID, roleID string, userIDs []string, concurrency int) map[string]error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var mu sync.Mutex
+	errs := make(map[string]error)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for _, userID := range userIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(userID string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := s.GuildMemberRoleAdd(guildID, userID, roleID); err != nil {
+				mu.Lock()
+				errs[userID] = err
+				mu.Unlock()
+			}
+		}(userID)
+	}
+	wg.Wait()
+
+	return errs
+}
+
 // GuildMemberRoleRemove removes the specified role to a given member
 //  guildID   : The ID of a Guild.
 //  userID    : The ID of a User.
@@ -919,6 +1452,81 @@ func (s *Session) GuildChannels(guildID string) (st []*Channel, err error) {
 	return
 }
 
+// GuildChannelsSorted returns the channels of a given guild in the order
+// Discord displays them: channels without a category first, then every
+// category by position, each followed by its own channels.
+// guildID   : The ID of a Guild.
+func (s *Session) GuildChannelsSorted(guildID string) (st []*Channel, err error) {
+	st, err = s.GuildChannels(guildID)
+	if err != nil {
+		return
+	}
+
+	st = sortChannels(st)
+	return
+}
+
+// sortChannels orders channels the way the Discord client lists them.
+// Within a category, text channels are listed before voice channels, and
+// channels are then ordered by position and finally by ID.
+func sortChannels(channels []*Channel) []*Channel {
+	less := func(a, b *Channel) bool {
+		if av, bv := a.Type == ChannelTypeGuildVoice, b.Type == ChannelTypeGuildVoice; av != bv {
+			return bv
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		// IDs are snowflakes, so a shorter ID is always the older one.
+		if len(a.ID) != len(b.ID) {
+			return len(a.ID) < len(b.ID)
+		}
+		return a.ID < b.ID
+	}
+
+	var categories []*Channel
+	children := make(map[string][]*Channel)
+	for _, c := range channels {
+		if c.Type == ChannelTypeGuildCategory {
+			categories = append(categories, c)
+		} else {
+			children[c.ParentID] = append(children[c.ParentID], c)
+		}
+	}
+
+	sortBy := func(list []*Channel) {
+		sort.SliceStable(list, func(i, j int) bool { return less(list[i], list[j]) })
+	}
+
+	sortBy(categories)
+
+	sorted := make([]*Channel, 0, len(channels))
+	sortBy(children[""])
+	sorted = append(sorted, children[""]...)
+	delete(children, "")
+
+	for _, category := range categories {
+		sortBy(children[category.ID])
+		sorted = append(sorted, category)
+		sorted = append(sorted, children[category.ID]...)
+		delete(children, category.ID)
+	}
+
+	// Channels whose category is unknown are kept at the end.
+	var orphans []*Channel
+	for _, c := range channels {
+		if c.Type != ChannelTypeGuildCategory {
+			if _, ok := children[c.ParentID]; ok {
+				orphans = append(orphans, c)
+			}
+		}
+	}
+	sortBy(orphans)
+	sorted = append(sorted, orphans...)
+
+	return sorted
+}
+
 // GuildChannelCreateData is provided to GuildChannelCreateComplex
 type GuildChannelCreateData struct {
 	Name                 string                 `json:"name"`
@@ -926,6 +1534,8 @@ type GuildChannelCreateData struct {
 	Topic                string                 `json:"topic,omitempty"`
 	Bitrate              int                    `json:"bitrate,omitempty"`
 	UserLimit            int                    `json:"user_limit,omitempty"`
+	RateLimitPerUser     int                    `json:"rate_limit_per_user,omitempty"`
+	Position             *int                   `json:"position,omitempty"` // placed by Discord if nil
 	PermissionOverwrites []*PermissionOverwrite `json:"permission_overwrites,omitempty"`
 	ParentID             string                 `json:"parent_id,omitempty"`
 	NSFW                 bool                   `json:"nsfw,omitempty"`
@@ -986,31 +1596,75 @@ func (s *Session) GuildInvites(guildID string) (st []*Invite, err error) {
 	return
 }
 
-// GuildRoles returns all roles for a given guild.
+// GuildRoles returns all roles for a given guild.
+// guildID   : The ID of a Guild.
+func (s *Session) GuildRoles(guildID string) (st []*Role, err error) {
+
+	body, err := s.RequestWithBucketID("GET", EndpointGuildRoles(guildID), nil, EndpointGuildRoles(guildID))
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+
+	return // TODO return pointer
+}
+
+// GuildRoleCreate returns a new Guild Role.
+// guildID: The ID of a Guild.
+func (s *Session) GuildRoleCreate(guildID string) (st *Role, err error) {
+
+	body, err := s.RequestWithBucketID("POST", EndpointGuildRoles(guildID), nil, EndpointGuildRoles(guildID))
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+
+	return
+}
+
+// GuildRoleCreateComplex creates a new Guild Role with the given settings
 // guildID   : The ID of a Guild.
-func (s *Session) GuildRoles(guildID string) (st []*Role, err error) {
+// data      : The settings of the Role, the defaults of Discord are used if nil.
+func (s *Session) GuildRoleCreateComplex(guildID string, data *RoleParams) (st *Role, err error) {
+	if data == nil {
+		data = &RoleParams{}
+	}
+	if data.Color != nil && *data.Color > 0xFFFFFF {
+		err = fmt.Errorf("color value cannot be larger than 0xFFFFFF")
+		return
+	}
 
-	body, err := s.RequestWithBucketID("GET", EndpointGuildRoles(guildID), nil, EndpointGuildRoles(guildID))
+	body, err := s.RequestWithBucketID("POST", EndpointGuildRoles(guildID), data, EndpointGuildRoles(guildID))
 	if err != nil {
 		return
 	}
 
 	err = unmarshal(body, &st)
-
-	return // TODO return pointer
+	return
 }
 
-// GuildRoleCreate returns a new Guild Role.
-// guildID: The ID of a Guild.
-func (s *Session) GuildRoleCreate(guildID string) (st *Role, err error) {
+// GuildRoleEditComplex updates an existing Guild Role, leaving the settings
+// which are nil in data unchanged
+// guildID   : The ID of a Guild.
+// roleID    : The ID of a Role.
+// data      : The settings to change, none are if nil.
+func (s *Session) GuildRoleEditComplex(guildID, roleID string, data *RoleParams) (st *Role, err error) {
+	if data == nil {
+		data = &RoleParams{}
+	}
+	if data.Color != nil && *data.Color > 0xFFFFFF {
+		err = fmt.Errorf("color value cannot be larger than 0xFFFFFF")
+		return
+	}
 
-	body, err := s.RequestWithBucketID("POST", EndpointGuildRoles(guildID), nil, EndpointGuildRoles(guildID))
+	body, err := s.RequestWithBucketID("PATCH", EndpointGuildRole(guildID, roleID), data, EndpointGuildRole(guildID, ""))
 	if err != nil {
 		return
 	}
 
 	err = unmarshal(body, &st)
-
 	return
 }
 
@@ -1362,6 +2016,114 @@ func (s *Session) GuildEmojiDelete(guildID, emojiID string) (err error) {
 	return
 }
 
+// GuildThreadsActive returns all active threads of a guild, along with the
+// thread members of the current user for them.
+// guildID   : The ID of a Guild.
+func (s *Session) GuildThreadsActive(guildID string) (st *ThreadsList, err error) {
+	endpoint := EndpointGuildThreadsActive(guildID)
+
+	body, err := s.RequestWithBucketID("GET", endpoint, nil, endpoint)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// GuildOnboarding returns the onboarding configuration of a guild.
+// guildID   : The ID of a Guild.
+func (s *Session) GuildOnboarding(guildID string) (st *GuildOnboarding, err error) {
+	endpoint := EndpointGuildOnboarding(guildID)
+
+	body, err := s.RequestWithBucketID("GET", endpoint, nil, endpoint)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// GuildOnboardingEdit edits the onboarding configuration of a guild.
+// guildID   : The ID of a Guild.
+// o         : The fields of the configuration to update.
+func (s *Session) GuildOnboardingEdit(guildID string, o *GuildOnboarding) (st *GuildOnboarding, err error) {
+	endpoint := EndpointGuildOnboarding(guildID)
+
+	body, err := s.RequestWithBucketID("PUT", endpoint, o, endpoint)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// AutoModerationRuleList returns all auto moderation rules of a guild.
+// guildID   : The ID of a Guild.
+func (s *Session) AutoModerationRuleList(guildID string) (st []*AutoModerationRule, err error) {
+	endpoint := EndpointGuildAutoModRules(guildID)
+
+	body, err := s.RequestWithBucketID("GET", endpoint, nil, endpoint)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// AutoModerationRule returns a single auto moderation rule of a guild.
+// guildID   : The ID of a Guild.
+// ruleID    : The ID of an auto moderation rule.
+func (s *Session) AutoModerationRule(guildID, ruleID string) (st *AutoModerationRule, err error) {
+	body, err := s.RequestWithBucketID("GET", EndpointGuildAutoModRule(guildID, ruleID), nil, EndpointGuildAutoModRule(guildID, ""))
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// AutoModerationRuleCreate creates an auto moderation rule in a guild.
+// guildID   : The ID of a Guild.
+// rule      : The rule to create, Name, EventType, TriggerType and Actions are required.
+func (s *Session) AutoModerationRuleCreate(guildID string, rule *AutoModerationRule) (st *AutoModerationRule, err error) {
+	endpoint := EndpointGuildAutoModRules(guildID)
+
+	body, err := s.RequestWithBucketID("POST", endpoint, rule, endpoint)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// AutoModerationRuleEdit edits an auto moderation rule of a guild.
+// guildID   : The ID of a Guild.
+// ruleID    : The ID of an auto moderation rule.
+// rule      : The fields of the rule to update, unset fields are left unchanged.
+func (s *Session) AutoModerationRuleEdit(guildID, ruleID string, rule *AutoModerationRule) (st *AutoModerationRule, err error) {
+	body, err := s.RequestWithBucketID("PATCH", EndpointGuildAutoModRule(guildID, ruleID), rule, EndpointGuildAutoModRule(guildID, ""))
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// AutoModerationRuleDelete deletes an auto moderation rule of a guild.
+// guildID   : The ID of a Guild.
+// ruleID    : The ID of an auto moderation rule.
+func (s *Session) AutoModerationRuleDelete(guildID, ruleID string) (err error) {
+	_, err = s.RequestWithBucketID("DELETE", EndpointGuildAutoModRule(guildID, ruleID), nil, EndpointGuildAutoModRule(guildID, ""))
+	return
+}
+
 // ------------------------------------------------------------------------------------------------
 // Functions specific to Discord Channels
 // ------------------------------------------------------------------------------------------------
@@ -1403,8 +2165,16 @@ func (s *Session) ChannelEditComplex(channelID string, data *ChannelEdit) (st *C
 // ChannelDelete deletes the given channel
 // channelID  : The ID of a Channel
 func (s *Session) ChannelDelete(channelID string) (st *Channel, err error) {
+	return s.ChannelDeleteWithReason(channelID, "")
+}
+
+// ChannelDeleteWithReason deletes the given channel, with a reason shown in
+// the audit log of the guild
+// channelID  : The ID of a Channel
+// reason     : The reason for deleting the channel
+func (s *Session) ChannelDeleteWithReason(channelID, reason string) (st *Channel, err error) {
 
-	body, err := s.RequestWithBucketID("DELETE", EndpointChannel(channelID), nil, EndpointChannel(channelID))
+	body, err := s.requestWithReason("DELETE", EndpointChannel(channelID), nil, EndpointChannel(channelID), reason)
 	if err != nil {
 		return
 	}
@@ -1473,6 +2243,29 @@ func (s *Session) ChannelMessage(channelID, messageID string) (st *Message, err
 	return
 }
 
+// ChannelMessageOrFetch returns a message from State if it is cached there,
+// and otherwise fetches it like ChannelMessage and caches it. Messages are
+// only cached when State.MaxMessageCount is set and the channel is in State.
+// channelID : The ID of a Channel
+// messageID : The ID of a Message
+func (s *Session) ChannelMessageOrFetch(channelID, messageID string) (st *Message, err error) {
+	// State methods handle a nil State.
+	state, _ := s.state()
+	if st, err = state.Message(channelID, messageID); err == nil {
+		return
+	}
+
+	st, err = s.ChannelMessage(channelID, messageID)
+	if err != nil {
+		return
+	}
+
+	if state != nil && state.MaxMessageCount != 0 {
+		state.MessageAdd(st)
+	}
+	return
+}
+
 // ChannelMessageAck acknowledges and marks the given message as read
 // channeld  : The ID of a Channel
 // messageID : the ID of a Message
@@ -1497,82 +2290,121 @@ func (s *Session) ChannelMessageSend(channelID string, content string) (*Message
 	})
 }
 
+// ChannelMessageSendSplit sends content to the given channel, split into as
+// many messages as needed to stay within the message length limit.
+// Content is split on newlines where possible, and otherwise on spaces. Code
+// blocks which span several messages are closed at the end of one message and
+// reopened at the start of the next.
+// The messages sent so far are returned along with any error, and
+// ErrEmptyMessage is returned if there is nothing to send.
+// channelID : The ID of a Channel.
+// content   : The message to send.
+func (s *Session) ChannelMessageSendSplit(channelID, content string) (st []*Message, err error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrEmptyMessage
+	}
+
+	for _, chunk := range splitMessage(content, messageContentLimit) {
+		var m *Message
+		m, err = s.ChannelMessageSend(channelID, chunk)
+		if err != nil {
+			return
+		}
+		st = append(st, m)
+	}
+	return
+}
+
 var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
 
 // ChannelMessageSendComplex sends a message to the given channel.
 // channelID : The ID of a Channel.
 // data      : The message struct to send.
 func (s *Session) ChannelMessageSendComplex(channelID string, data *MessageSend) (st *Message, err error) {
-	if data.Embed != nil && data.Embed.Type == "" {
-		data.Embed.Type = "rich"
+	data, err = s.prepareMessageSend(data)
+	if err != nil {
+		return
 	}
 
 	endpoint := EndpointChannelMessages(channelID)
 
 	// TODO: Remove this when compatibility is not required.
-	files := data.Files
-	if data.File != nil {
-		if files == nil {
-			files = []*File{data.File}
-		} else {
-			err = fmt.Errorf("cannot specify both File and Files")
-			return
-		}
+	if data.File != nil && data.Files != nil {
+		err = fmt.Errorf("cannot specify both File and Files")
+		return
 	}
+	files := data.allFiles()
 
 	var response []byte
 	if len(files) > 0 {
-		body := &bytes.Buffer{}
-		bodywriter := multipart.NewWriter(body)
-
-		var payload []byte
-		payload, err = json.Marshal(data)
-		if err != nil {
-			return
-		}
-
-		var p io.Writer
+		response, err = s.requestMultipart("POST", endpoint, data, files, endpoint)
+	} else {
+		response, err = s.RequestWithBucketID("POST", endpoint, data, endpoint)
+	}
+	if err != nil {
+		return
+	}
 
-		h := make(textproto.MIMEHeader)
-		h.Set("Content-Disposition", `form-data; name="payload_json"`)
-		h.Set("Content-Type", "application/json")
+	err = unmarshal(response, &st)
+	return
+}
 
-		p, err = bodywriter.CreatePart(h)
-		if err != nil {
-			return
+// prepareMessageSend sets the type of the embeds of a message, validates it
+// if ValidatePayloads is set, and returns it with DefaultAllowedMentions
+// unless it has allowed mentions of its own.
+func (s *Session) prepareMessageSend(data *MessageSend) (*MessageSend, error) {
+	for _, embed := range data.allEmbeds() {
+		if embed != nil && embed.Type == "" {
+			embed.Type = "rich"
 		}
+	}
 
-		if _, err = p.Write(payload); err != nil {
-			return
+	if s.ValidatePayloads {
+		if err := data.Validate(); err != nil {
+			return nil, err
 		}
+	}
 
-		for i, file := range files {
-			h := make(textproto.MIMEHeader)
-			h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file%d"; filename="%s"`, i, quoteEscaper.Replace(file.Name)))
-			contentType := file.ContentType
-			if contentType == "" {
-				contentType = "application/octet-stream"
-			}
-			h.Set("Content-Type", contentType)
+	if data.AllowedMentions == nil && s.DefaultAllowedMentions != nil {
+		d := *data
+		d.AllowedMentions = s.DefaultAllowedMentions
+		data = &d
+	}
 
-			p, err = bodywriter.CreatePart(h)
-			if err != nil {
-				return
-			}
+	return data, nil
+}
 
-			if _, err = io.Copy(p, file.Reader); err != nil {
-				return
-			}
-		}
+// ForumThreadStart creates a thread with a starter message in a forum channel.
+// ErrNilForumThread is returned if params is nil.
+// channelID : The ID of a forum Channel.
+// params    : The thread and its starter message.
+func (s *Session) ForumThreadStart(channelID string, params *ForumThreadStart) (st *Channel, err error) {
+	if params == nil {
+		return nil, ErrNilForumThread
+	}
+
+	endpoint := EndpointChannelThreads(channelID)
 
-		err = bodywriter.Close()
+	var files []*File
+	if params.Message != nil {
+		message, err := s.prepareMessageSend(params.Message)
 		if err != nil {
-			return
+			return nil, err
+		}
+		if message != params.Message {
+			p := *params
+			p.Message = message
+			params = &p
 		}
 
-		response, err = s.request("POST", endpoint, bodywriter.FormDataContentType(), body.Bytes(), endpoint, 0)
+		files = message.allFiles()
+	}
+
+	var response []byte
+	if len(files) > 0 {
+		response, err = s.requestMultipart("POST", endpoint, params, files, endpoint)
 	} else {
-		response, err = s.RequestWithBucketID("POST", endpoint, data, endpoint)
+		response, err = s.RequestWithBucketID("POST", endpoint, params, endpoint)
 	}
 	if err != nil {
 		return
@@ -1582,6 +2414,50 @@ func (s *Session) ChannelMessageSendComplex(channelID string, data *MessageSend)
 	return
 }
 
+// threadsArchived returns a page of archived threads from the given endpoint.
+func (s *Session) threadsArchived(endpoint, before string, limit int) (st *ThreadsList, err error) {
+	uri := endpoint
+
+	v := url.Values{}
+	if before != "" {
+		v.Set("before", before)
+	}
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	if len(v) > 0 {
+		uri += "?" + v.Encode()
+	}
+
+	body, err := s.RequestWithBucketID("GET", uri, nil, endpoint)
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
+// ThreadsArchivedPublic returns archived public threads of a channel, most
+// recently archived first. Use HasMore and the ArchiveTimestamp of the last
+// thread as the next before to page through all of them.
+// channelID : The ID of a Channel.
+// before    : If provided, only threads archived before this ISO8601 timestamp are returned.
+// limit     : The number of threads to return.
+func (s *Session) ThreadsArchivedPublic(channelID, before string, limit int) (st *ThreadsList, err error) {
+	return s.threadsArchived(EndpointChannelPublicArchivedThreads(channelID), before, limit)
+}
+
+// ThreadsArchivedPrivate returns archived private threads of a channel, most
+// recently archived first. Use HasMore and the ArchiveTimestamp of the last
+// thread as the next before to page through all of them.
+// channelID : The ID of a Channel.
+// before    : If provided, only threads archived before this ISO8601 timestamp are returned.
+// limit     : The number of threads to return.
+func (s *Session) ThreadsArchivedPrivate(channelID, before string, limit int) (st *ThreadsList, err error) {
+	return s.threadsArchived(EndpointChannelPrivateArchivedThreads(channelID), before, limit)
+}
+
 // ChannelMessageSendTTS sends a message to the given channel with Text to Speech.
 // channelID : The ID of a Channel.
 // content   : The message to send.
@@ -1601,6 +2477,37 @@ func (s *Session) ChannelMessageSendEmbed(channelID string, embed *MessageEmbed)
 	})
 }
 
+// ChannelMessageSendReply sends a message replying to another message.
+// channelID : The ID of a Channel.
+// content   : The message to send.
+// reference : The message to reply to.
+func (s *Session) ChannelMessageSendReply(channelID string, content string, reference *MessageReference) (*Message, error) {
+	if reference == nil {
+		return nil, ErrNilReference
+	}
+
+	return s.ChannelMessageSendComplex(channelID, &MessageSend{
+		Content:   content,
+		Reference: reference,
+	})
+}
+
+// ChannelMessageSendEmbedReply sends a message with an embed, replying to
+// another message.
+// channelID : The ID of a Channel.
+// embed     : The embed data to send.
+// reference : The message to reply to.
+func (s *Session) ChannelMessageSendEmbedReply(channelID string, embed *MessageEmbed, reference *MessageReference) (*Message, error) {
+	if reference == nil {
+		return nil, ErrNilReference
+	}
+
+	return s.ChannelMessageSendComplex(channelID, &MessageSend{
+		Embed:     embed,
+		Reference: reference,
+	})
+}
+
 // ChannelMessageEdit edits an existing message, replacing it entirely with
 // the given content.
 // channelID  : The ID of a Channel
@@ -1617,6 +2524,19 @@ func (s *Session) ChannelMessageEditComplex(m *MessageEdit) (st *Message, err er
 		m.Embed.Type = "rich"
 	}
 
+	if s.ValidatePayloads {
+		if m.Content != nil {
+			if err = checkLength("message content", *m.Content, messageContentLimit); err != nil {
+				return
+			}
+		}
+		if m.Embed != nil {
+			if err = m.Embed.Validate(); err != nil {
+				return
+			}
+		}
+	}
+
 	response, err := s.RequestWithBucketID("PATCH", EndpointChannelMessage(m.Channel, m.ID), m, EndpointChannelMessage(m.Channel, ""))
 	if err != nil {
 		return
@@ -2074,11 +2994,48 @@ func (s *Session) WebhookExecute(webhookID, token string, wait bool, data *Webho
 		uri += "?wait=true"
 	}
 
+	if data != nil && data.AllowedMentions == nil && s.DefaultAllowedMentions != nil {
+		d := *data
+		d.AllowedMentions = s.DefaultAllowedMentions
+		data = &d
+	}
+
 	_, err = s.RequestWithBucketID("POST", uri, data, EndpointWebhookToken("", ""))
 
 	return
 }
 
+// WebhookExecuteAs executes a webhook with the given username and avatar,
+// instead of the ones set on the webhook, and returns the message it sent.
+// data is not modified.
+// webhookID: The ID of a webhook.
+// token    : The auth token for the webhook
+// username : The username to post as, or empty to keep the one in data
+// avatarURL: The URL of the avatar to post with, or empty to keep the one in data
+func (s *Session) WebhookExecuteAs(webhookID, token, username, avatarURL string, data *WebhookParams) (st *Message, err error) {
+	params := WebhookParams{}
+	if data != nil {
+		params = *data
+	}
+	if username != "" {
+		params.Username = username
+	}
+	if avatarURL != "" {
+		params.AvatarURL = avatarURL
+	}
+	if params.AllowedMentions == nil {
+		params.AllowedMentions = s.DefaultAllowedMentions
+	}
+
+	body, err := s.RequestWithBucketID("POST", EndpointWebhookToken(webhookID, token)+"?wait=true", params, EndpointWebhookToken("", ""))
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
 // MessageReactionAdd creates an emoji reaction to a message.
 // channelID : The channel ID.
 // messageID : The message ID.
@@ -2139,6 +3096,46 @@ func (s *Session) MessageReactions(channelID, messageID, emojiID string, limit i
 	return
 }
 
+// ------------------------------------------------------------------------------------------------
+// Functions specific to polls
+// ------------------------------------------------------------------------------------------------
+
+// PollAnswerVoters returns the users who voted for an answer of a poll.
+// channelID : The ID of the channel of the poll.
+// messageID : The ID of the message of the poll.
+// answerID  : The AnswerID of the answer.
+func (s *Session) PollAnswerVoters(channelID, messageID string, answerID int) (st []*User, err error) {
+	endpoint := EndpointPollAnswerVoters(channelID, messageID, answerID)
+
+	body, err := s.RequestWithBucketID("GET", endpoint, nil, EndpointPoll(channelID, ""))
+	if err != nil {
+		return
+	}
+
+	var r struct {
+		Users []*User `json:"users"`
+	}
+
+	err = unmarshal(body, &r)
+	st = r.Users
+	return
+}
+
+// PollExpire ends a poll early, and returns the message of the poll.
+// channelID : The ID of the channel of the poll.
+// messageID : The ID of the message of the poll.
+func (s *Session) PollExpire(channelID, messageID string) (st *Message, err error) {
+	endpoint := EndpointPollExpire(channelID, messageID)
+
+	body, err := s.RequestWithBucketID("POST", endpoint, nil, EndpointPoll(channelID, ""))
+	if err != nil {
+		return
+	}
+
+	err = unmarshal(body, &st)
+	return
+}
+
 // ------------------------------------------------------------------------------------------------
 // Functions specific to user notes
 // ------------------------------------------------------------------------------------------------
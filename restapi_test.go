@@ -1,7 +1,24 @@
 package discordgo
 
 import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 //////////////////////////////////////////////////////////////////////////////
@@ -42,7 +59,7 @@ func TestUserAvatar(t *testing.T) {
 		if err.Error() == `HTTP 404 NOT FOUND, {"code": 0, "message": "404: Not Found"}` {
 			t.Skip("Skipped, @me doesn't have an Avatar")
 		}
-		t.Errorf(err.Error())
+		t.Error(err.Error())
 	}
 
 	if a == nil {
@@ -59,7 +76,7 @@ func TestUserUpdate(t *testing.T) {
 
 	u, err := dg.User("@me")
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err.Error())
 	}
 
 	s, err := dg.UserUpdate(envEmail, envPassword, "testname", u.Avatar, "")
@@ -92,7 +109,7 @@ func TestUserChannelCreate(t *testing.T) {
 
 	_, err := dg.UserChannelCreate(envAdmin)
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err.Error())
 	}
 
 	// TODO make sure the channel was added
@@ -105,7 +122,7 @@ func TestUserChannels(t *testing.T) {
 
 	_, err := dg.UserChannels()
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err.Error())
 	}
 }
 
@@ -116,7 +133,7 @@ func TestUserGuilds(t *testing.T) {
 
 	_, err := dg.UserGuilds(10, "", "")
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err.Error())
 	}
 }
 
@@ -127,7 +144,7 @@ func TestUserSettings(t *testing.T) {
 
 	_, err := dg.UserSettings()
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err.Error())
 	}
 }
 
@@ -138,7 +155,7 @@ func TestUserUpdateStatus(t *testing.T) {
 
 	_, err := dg.UserUpdateStatus(StatusDoNotDisturb)
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err.Error())
 	}
 }
 
@@ -285,3 +302,1449 @@ func TestGuildPrune(t *testing.T) {
 	}
 }
 */
+
+// handlerTransport is a http.RoundTripper which serves requests with an
+// http.Handler instead of sending them to Discord.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.handler.ServeHTTP(rec, r)
+
+	resp := rec.Result()
+	resp.Request = r
+	return resp, nil
+}
+
+// newTestSession returns a Session whose REST requests are served by handler.
+func newTestSession(handler http.HandlerFunc) *Session {
+	s, _ := New("Bot test")
+	s.Client = &http.Client{Transport: handlerTransport{handler}}
+	return s
+}
+
+func TestGuildChannelsSorted(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"id":"20","name":"voice-b","type":2,"position":0,"parent_id":"2"},
+			{"id":"2","name":"category-b","type":4,"position":1},
+			{"id":"21","name":"text-b","type":0,"position":5,"parent_id":"2"},
+			{"id":"11","name":"text-a2","type":0,"position":2,"parent_id":"1"},
+			{"id":"3","name":"general","type":0,"position":3},
+			{"id":"1","name":"category-a","type":4,"position":0},
+			{"id":"10","name":"text-a1","type":0,"position":1,"parent_id":"1"},
+			{"id":"4","name":"rules","type":0,"position":0},
+			{"id":"30","name":"orphan","type":0,"position":0,"parent_id":"99"}
+		]`))
+	})
+
+	channels, err := s.GuildChannelsSorted("guild")
+	if err != nil {
+		t.Fatalf("GuildChannelsSorted returned error: %+v", err)
+	}
+
+	expected := []string{"rules", "general", "category-a", "text-a1", "text-a2", "category-b", "text-b", "voice-b", "orphan"}
+	if len(channels) != len(expected) {
+		t.Fatalf("GuildChannelsSorted returned %d channels, expected %d", len(channels), len(expected))
+	}
+	for i, c := range channels {
+		if c.Name != expected[i] {
+			t.Errorf("channel %d is %q, expected %q", i, c.Name, expected[i])
+		}
+	}
+}
+
+func TestChannelInviteCreate(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v6/channels/channel/invites" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		expected := map[string]interface{}{"max_age": 3600.0, "max_uses": 5.0, "temporary": true, "unique": true}
+		for k, v := range expected {
+			if body[k] != v {
+				t.Errorf("body %s is %v, expected %v", k, body[k], v)
+			}
+		}
+
+		w.Write([]byte(`{"code":"abc","max_age":3600,"max_uses":5,"temporary":true}`))
+	})
+
+	c := &Channel{ID: "channel"}
+	invite, err := c.InviteCreate(s, &InviteParams{MaxAge: 3600, MaxUses: 5, Temporary: true, Unique: true})
+	if err != nil {
+		t.Fatalf("InviteCreate returned error: %+v", err)
+	}
+	if invite.Code != "abc" {
+		t.Errorf("invite code is %q, expected %q", invite.Code, "abc")
+	}
+}
+
+func TestChannelDelete(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || r.URL.Path != "/api/v6/channels/channel" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if reason := r.Header.Get("X-Audit-Log-Reason"); reason != "cleaning%20up%20%C3%A9" {
+			t.Errorf("audit log reason is %q", reason)
+		}
+
+		w.Write([]byte(`{"id":"channel","name":"old-channel","guild_id":"guild"}`))
+	})
+
+	c := &Channel{ID: "channel"}
+	deleted, err := c.Delete(s, "cleaning up é")
+	if err != nil {
+		t.Fatalf("Delete returned error: %+v", err)
+	}
+	if deleted.ID != "channel" || deleted.Name != "old-channel" || deleted.GuildID != "guild" {
+		t.Errorf("Delete returned %+v", deleted)
+	}
+
+	s.Client.Transport = handlerTransport{http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Header["X-Audit-Log-Reason"]; ok {
+			t.Error("audit log reason was sent without a reason")
+		}
+		w.Write([]byte(`{"id":"channel"}`))
+	})}
+	if _, err = c.Delete(s, ""); err != nil {
+		t.Fatalf("Delete returned error: %+v", err)
+	}
+}
+
+func TestGuildChannelCreateComplex(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v6/guilds/guild/channels" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		expected := map[string]interface{}{"name": "staff", "type": 0.0, "topic": "Staff only", "rate_limit_per_user": 10.0, "position": 0.0, "parent_id": "category", "nsfw": true}
+		for k, v := range expected {
+			if body[k] != v {
+				t.Errorf("body %s is %v, expected %v", k, body[k], v)
+			}
+		}
+
+		overwrites, _ := body["permission_overwrites"].([]interface{})
+		if len(overwrites) != 1 {
+			t.Fatalf("body has %d permission overwrites, expected 1", len(overwrites))
+		}
+		overwrite := overwrites[0].(map[string]interface{})
+		if overwrite["id"] != "role" || overwrite["type"] != "role" || overwrite["allow"] != "1024" || overwrite["deny"] != "2048" {
+			t.Errorf("permission overwrite is serialized as %v", overwrite)
+		}
+
+		w.Write([]byte(`{"id":"channel","name":"staff","permission_overwrites":[{"id":"role","type":"role","allow":1024,"deny":"2048"}]}`))
+	})
+
+	// The first position is sent, rather than omitted.
+	position := 0
+	c, err := s.GuildChannelCreateComplex("guild", GuildChannelCreateData{
+		Name:             "staff",
+		Type:             ChannelTypeGuildText,
+		Topic:            "Staff only",
+		RateLimitPerUser: 10,
+		Position:         &position,
+		ParentID:         "category",
+		NSFW:             true,
+		PermissionOverwrites: []*PermissionOverwrite{
+			{ID: "role", Type: "role", Allow: PermissionReadMessages, Deny: PermissionSendMessages},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GuildChannelCreateComplex returned error: %+v", err)
+	}
+
+	// Permissions are decoded whether they are sent as numbers or strings.
+	if len(c.PermissionOverwrites) != 1 || c.PermissionOverwrites[0].Allow != 1024 || c.PermissionOverwrites[0].Deny != 2048 {
+		t.Errorf("permission overwrites decoded as %+v", c.PermissionOverwrites)
+	}
+}
+
+func TestChannelWebhooks(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v6/channels/channel/webhooks" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		switch r.Method {
+		case "POST":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["name"] != "logger" || body["avatar"] != "data:image/png;base64,AAAA" {
+				t.Errorf("webhook was created with %v", body)
+			}
+			w.Write([]byte(`{"id":"webhook","channel_id":"channel","name":"logger","token":"token"}`))
+		case "GET":
+			w.Write([]byte(`[{"id":"webhook","channel_id":"channel","name":"logger"},{"id":"other","channel_id":"channel","name":"other"}]`))
+		}
+	})
+
+	c := &Channel{ID: "channel"}
+	webhook, err := c.WebhookCreate(s, "logger", "data:image/png;base64,AAAA")
+	if err != nil {
+		t.Fatalf("WebhookCreate returned error: %+v", err)
+	}
+	if webhook.ID != "webhook" || webhook.Token != "token" {
+		t.Errorf("WebhookCreate returned %+v", webhook)
+	}
+
+	webhooks, err := c.Webhooks(s)
+	if err != nil {
+		t.Fatalf("Webhooks returned error: %+v", err)
+	}
+	if len(webhooks) != 2 || webhooks[0].Name != "logger" || webhooks[1].ID != "other" {
+		t.Errorf("Webhooks returned %+v", webhooks)
+	}
+}
+
+func TestWebhookExecuteAs(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v6/webhooks/webhook/token" || r.URL.Query().Get("wait") != "true" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["username"] != "Status" || body["avatar_url"] != "https://example.com/status.png" || body["content"] != "All systems operational" {
+			t.Errorf("webhook was executed with %v", body)
+		}
+
+		w.Write([]byte(`{"id":"message","content":"All systems operational"}`))
+	})
+
+	data := &WebhookParams{Content: "All systems operational", Username: "Default"}
+	m, err := s.WebhookExecuteAs("webhook", "token", "Status", "https://example.com/status.png", data)
+	if err != nil {
+		t.Fatalf("WebhookExecuteAs returned error: %+v", err)
+	}
+	if m.ID != "message" {
+		t.Errorf("WebhookExecuteAs returned %+v", m)
+	}
+	if data.Username != "Default" || data.AvatarURL != "" {
+		t.Errorf("WebhookExecuteAs modified the params to %+v", data)
+	}
+}
+
+func TestApplication(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/v6/oauth2/applications/@me" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"id":"app","name":"Helper","description":"Helps","bot_public":true,"owner":{"id":"owner"},"tags":["utility"],"install_params":{"scopes":["bot","applications.commands"],"permissions":"2147483648"}}`))
+	})
+
+	app, err := s.Application("@me")
+	if err != nil {
+		t.Fatalf("Application returned error: %+v", err)
+	}
+	if app.ID != "app" || app.Name != "Helper" || app.Description != "Helps" || !app.BotPublic || app.Owner == nil || app.Owner.ID != "owner" {
+		t.Errorf("Application decoded as %+v", app)
+	}
+	if app.InstallParams == nil || len(app.InstallParams.Scopes) != 2 || app.InstallParams.Scopes[1] != "applications.commands" || app.InstallParams.Permissions != 1<<31 {
+		t.Errorf("Application install params decoded as %+v", app.InstallParams)
+	}
+}
+
+func TestGuildIntegrations(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v6/guilds/guild/integrations":
+			w.Write([]byte(`[
+				{"id":"twitch","name":"streamer","type":"twitch","enabled":true,"role_id":"subs","expire_behavior":1,"expire_grace_period":7,"subscriber_count":12,"account":{"id":"acct","name":"streamer"}},
+				{"id":"bot","name":"Helper","type":"discord","enabled":true,"account":{"id":"app","name":"Helper"},"application":{"id":"app","name":"Helper","description":"Helps","bot":{"id":"app","bot":true}}}
+			]`))
+		case "DELETE /api/v6/guilds/guild/integrations/twitch":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	integrations, err := s.GuildIntegrations("guild")
+	if err != nil {
+		t.Fatalf("GuildIntegrations returned error: %+v", err)
+	}
+	if len(integrations) != 2 {
+		t.Fatalf("GuildIntegrations returned %d integrations, expected 2", len(integrations))
+	}
+
+	twitch := integrations[0]
+	if twitch.ExpireBehavior != IntegrationExpireBehaviorKick || twitch.ExpireGracePeriod != 7 || twitch.SubscriberCount != 12 || twitch.Account.Name != "streamer" || twitch.Application != nil {
+		t.Errorf("twitch integration decoded as %+v", twitch)
+	}
+
+	bot := integrations[1]
+	if bot.Application == nil || bot.Application.ID != "app" || bot.Application.Description != "Helps" || bot.Application.Bot == nil || !bot.Application.Bot.Bot {
+		t.Errorf("bot integration application decoded as %+v", bot.Application)
+	}
+
+	if err = s.GuildIntegrationDelete("guild", "twitch"); err != nil {
+		t.Fatalf("GuildIntegrationDelete returned error: %+v", err)
+	}
+}
+
+func TestInviteWithCounts(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("with_counts") != "true" {
+			t.Errorf("with_counts is %q, expected true", r.URL.Query().Get("with_counts"))
+		}
+
+		w.Write([]byte(`{"code":"abc","approximate_member_count":42,"approximate_presence_count":7}`))
+	})
+
+	invite, err := s.InviteWithCounts("abc")
+	if err != nil {
+		t.Fatalf("InviteWithCounts returned error: %+v", err)
+	}
+	if invite.ApproximateMemberCount != 42 || invite.ApproximatePresenceCount != 7 {
+		t.Errorf("counts are %d members and %d present, expected 42 and 7", invite.ApproximateMemberCount, invite.ApproximatePresenceCount)
+	}
+}
+
+func TestGuildWithCounts(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v6/guilds/guild" || r.URL.Query().Get("with_counts") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+
+		w.Write([]byte(`{"id":"guild","approximate_member_count":1200,"approximate_presence_count":340}`))
+	})
+
+	// The counts are fetched even if the guild is in State.
+	s.State.GuildAdd(&Guild{ID: "guild"})
+
+	g, err := s.GuildWithCounts("guild")
+	if err != nil {
+		t.Fatalf("GuildWithCounts returned error: %+v", err)
+	}
+	if g.ApproximateMemberCount != 1200 || g.ApproximatePresenceCount != 340 {
+		t.Errorf("counts are %d members and %d present, expected 1200 and 340", g.ApproximateMemberCount, g.ApproximatePresenceCount)
+	}
+}
+
+func TestGuildMemberMuteDeafen(t *testing.T) {
+	var body map[string]interface{}
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" || r.URL.Path != "/api/v6/guilds/guild/members/user" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		call     func() error
+		expected map[string]interface{}
+	}{
+		{func() error { return s.GuildMemberMute("guild", "user", true) }, map[string]interface{}{"mute": true}},
+		{func() error { return s.GuildMemberMute("guild", "user", false) }, map[string]interface{}{"mute": false}},
+		{func() error { return s.GuildMemberDeafen("guild", "user", true) }, map[string]interface{}{"deaf": true}},
+		{func() error { return s.GuildMemberDeafen("guild", "user", false) }, map[string]interface{}{"deaf": false}},
+	}
+
+	for i, tt := range tests {
+		if err := tt.call(); err != nil {
+			t.Fatalf("call %d returned error: %+v", i, err)
+		}
+		if len(body) != len(tt.expected) {
+			t.Errorf("call %d sent %v, expected %v", i, body, tt.expected)
+		}
+		for k, v := range tt.expected {
+			if body[k] != v {
+				t.Errorf("call %d sent %s = %v, expected %v", i, k, body[k], v)
+			}
+		}
+	}
+}
+
+func TestUserAvatarDecode(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(1, 1, color.RGBA{R: 255, A: 255})
+
+	var pngData, gifData bytes.Buffer
+	png.Encode(&pngData, src)
+	gif.Encode(&gifData, src, nil)
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/avatars/user/hash.png":
+			w.Write(pngData.Bytes())
+		case "/avatars/user/a_hash.gif":
+			w.Write(gifData.Bytes())
+		case "/avatars/user/bad.png":
+			w.Write([]byte("not an image"))
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	img, err := s.UserAvatarDecode(&User{ID: "user", Avatar: "hash"})
+	if err != nil {
+		t.Fatalf("UserAvatarDecode returned error: %+v", err)
+	}
+	if img.Bounds().Dx() != 2 || img.Bounds().Dy() != 2 {
+		t.Errorf("decoded avatar is %s, expected 2x2", img.Bounds())
+	}
+	if r, _, _, _ := img.At(1, 1).RGBA(); r != 0xffff {
+		t.Errorf("decoded avatar pixel is %v, expected red", img.At(1, 1))
+	}
+
+	if _, err = s.UserAvatarDecode(&User{ID: "user", Avatar: "a_hash"}); err != nil {
+		t.Errorf("UserAvatarDecode returned error for an animated avatar: %+v", err)
+	}
+
+	if _, err = s.UserAvatarDecode(&User{ID: "user", Avatar: "bad"}); err != ErrUnsupportedImageFormat {
+		t.Errorf("UserAvatarDecode returned %v, expected ErrUnsupportedImageFormat", err)
+	}
+}
+
+func TestGuildLeave(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || r.URL.Path != "/api/v6/users/@me/guilds/guild" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := s.GuildLeave("guild"); err != nil {
+		t.Fatalf("GuildLeave returned error: %+v", err)
+	}
+}
+
+func TestGuildMemberDeleteWithReason(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || r.URL.Path != "/api/v6/guilds/guild/members/user" || r.URL.RawQuery != "" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		if reason := r.Header.Get("X-Audit-Log-Reason"); reason != "spamming%20links" {
+			t.Errorf("audit log reason is %q", reason)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := s.GuildMemberDeleteWithReason("guild", "user", "spamming links"); err != nil {
+		t.Fatalf("GuildMemberDeleteWithReason returned error: %+v", err)
+	}
+}
+
+func TestAutoModerationRuleCreate(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v6/guilds/guild/auto-moderation/rules" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		expected := `{"actions":[{"metadata":{"custom_message":"Watch your language"},"type":1}],"enabled":true,` +
+			`"event_type":1,"exempt_roles":["mods"],"name":"No swearing","trigger_metadata":{"keyword_filter":["darn"]},"trigger_type":1}`
+		if b, _ := json.Marshal(body); string(b) != expected {
+			t.Errorf("create body is %s, expected %s", b, expected)
+		}
+
+		w.Write([]byte(`{"id":"rule","guild_id":"guild","name":"No swearing","trigger_type":1,"actions":[{"type":1}]}`))
+	})
+
+	enabled := true
+	rule, err := s.AutoModerationRuleCreate("guild", &AutoModerationRule{
+		Name:        "No swearing",
+		EventType:   AutoModerationEventMessageSend,
+		TriggerType: AutoModerationEventTriggerKeyword,
+		TriggerMetadata: &AutoModerationTriggerMetadata{
+			KeywordFilter: []string{"darn"},
+		},
+		Actions: []AutoModerationAction{{
+			Type:     AutoModerationRuleActionBlockMessage,
+			Metadata: &AutoModerationActionMetadata{CustomMessage: "Watch your language"},
+		}},
+		Enabled:     &enabled,
+		ExemptRoles: &[]string{"mods"},
+	})
+	if err != nil {
+		t.Fatalf("AutoModerationRuleCreate returned error: %+v", err)
+	}
+	if rule.ID != "rule" || len(rule.Actions) != 1 || rule.Actions[0].Type != AutoModerationRuleActionBlockMessage {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+}
+
+func TestGuildOnboarding(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"guild_id":"guild",
+			"prompts":[{
+				"id":"prompt","type":1,"title":"What brings you here?","single_select":true,"required":false,"in_onboarding":true,
+				"options":[{"id":"option","title":"Games","description":"","channel_ids":["games"],"role_ids":["gamer"],"emoji":{"id":null,"name":"🎮"}}]
+			}],
+			"default_channel_ids":["general","rules"],
+			"enabled":true,
+			"mode":1
+		}`))
+	})
+
+	o, err := s.GuildOnboarding("guild")
+	if err != nil {
+		t.Fatalf("GuildOnboarding returned error: %+v", err)
+	}
+
+	if o.Enabled == nil || !*o.Enabled || o.Mode == nil || *o.Mode != GuildOnboardingModeAdvanced {
+		t.Errorf("unexpected enabled %v and mode %v", o.Enabled, o.Mode)
+	}
+	if len(o.DefaultChannelIDs) != 2 || o.DefaultChannelIDs[1] != "rules" {
+		t.Errorf("unexpected default channels %v", o.DefaultChannelIDs)
+	}
+	if o.Prompts == nil || len(*o.Prompts) != 1 {
+		t.Fatalf("unexpected prompts %v", o.Prompts)
+	}
+	prompt := (*o.Prompts)[0]
+	if prompt.Type != GuildOnboardingPromptTypeDropdown || !prompt.SingleSelect || len(prompt.Options) != 1 {
+		t.Fatalf("unexpected prompt %+v", prompt)
+	}
+	if option := prompt.Options[0]; option.RoleIDs[0] != "gamer" || option.Emoji == nil || option.Emoji.Name != "🎮" {
+		t.Errorf("unexpected option %+v", option)
+	}
+}
+
+func TestGuildOnboardingEdit(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/api/v6/guilds/guild/onboarding" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		expected := `{"default_channel_ids":["general"],"enabled":false,"prompts":[]}`
+		if b, _ := json.Marshal(body); string(b) != expected {
+			t.Errorf("edit body is %s, expected %s", b, expected)
+		}
+
+		w.Write([]byte(`{"guild_id":"guild","enabled":false}`))
+	})
+
+	enabled := false
+	_, err := s.GuildOnboardingEdit("guild", &GuildOnboarding{
+		Prompts:           &[]GuildOnboardingPrompt{},
+		DefaultChannelIDs: []string{"general"},
+		Enabled:           &enabled,
+	})
+	if err != nil {
+		t.Fatalf("GuildOnboardingEdit returned error: %+v", err)
+	}
+}
+
+func TestForumThreadStart(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v6/channels/forum/threads" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		expected := `{"applied_tags":["bug","urgent"],"auto_archive_duration":1440,"message":{"content":"It crashed","tts":false},"name":"Help"}`
+		if b, _ := json.Marshal(body); string(b) != expected {
+			t.Errorf("thread body is %s, expected %s", b, expected)
+		}
+
+		w.Write([]byte(`{"id":"thread","type":11,"parent_id":"forum","name":"Help","applied_tags":["bug","urgent"]}`))
+	})
+
+	thread, err := s.ForumThreadStart("forum", &ForumThreadStart{
+		Name:                "Help",
+		AutoArchiveDuration: 1440,
+		AppliedTags:         []string{"bug", "urgent"},
+		Message:             &MessageSend{Content: "It crashed"},
+	})
+	if err != nil {
+		t.Fatalf("ForumThreadStart returned error: %+v", err)
+	}
+	if thread.Type != ChannelTypeGuildPublicThread || len(thread.AppliedTags) != 2 {
+		t.Errorf("unexpected thread %+v", thread)
+	}
+
+	if _, err = s.ForumThreadStart("forum", nil); err != ErrNilForumThread {
+		t.Errorf("ForumThreadStart returned %v for nil params, expected ErrNilForumThread", err)
+	}
+}
+
+func TestForumThreadStartMessage(t *testing.T) {
+	var messages []map[string]interface{}
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Message map[string]interface{} `json:"message"`
+		}
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		part, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		if err != nil {
+			t.Errorf("error reading the payload part, %s", err)
+			return
+		}
+		json.NewDecoder(part).Decode(&body)
+		messages = append(messages, body.Message)
+
+		w.Write([]byte(`{"id":"thread","type":11,"parent_id":"forum"}`))
+	})
+	s.DefaultAllowedMentions = &MessageAllowedMentions{Parse: []AllowedMentionType{AllowedMentionTypeUsers}}
+
+	files := make([]*File, 1, 2)
+	files[0] = &File{Name: "a.txt", Reader: strings.NewReader("a")}
+	message := &MessageSend{
+		Embeds: []*MessageEmbed{{Title: "embed"}},
+		Files:  files,
+		File:   &File{Name: "b.txt", Reader: strings.NewReader("b")},
+	}
+	if _, err := s.ForumThreadStart("forum", &ForumThreadStart{Name: "post", Message: message}); err != nil {
+		t.Fatalf("ForumThreadStart returned error: %+v", err)
+	}
+
+	if message.Embeds[0].Type != "rich" {
+		t.Errorf("embed has type %q, expected rich", message.Embeds[0].Type)
+	}
+	if message.AllowedMentions != nil {
+		t.Error("DefaultAllowedMentions were set on the message of the caller")
+	}
+	if mentions, ok := messages[0]["allowed_mentions"].(map[string]interface{}); !ok || len(mentions["parse"].([]interface{})) != 1 {
+		t.Errorf("starter message was sent as %v, expected the default allowed mentions", messages[0])
+	}
+	if extra := files[:2][1]; extra != nil {
+		t.Errorf("File was appended to the Files of the caller, %+v", extra)
+	}
+
+	s.ValidatePayloads = true
+	long := &MessageSend{Content: strings.Repeat("a", 2001)}
+	if _, err := s.ForumThreadStart("forum", &ForumThreadStart{Name: "post", Message: long}); err == nil {
+		t.Error("ForumThreadStart returned no error for an invalid starter message")
+	}
+	if len(messages) != 1 {
+		t.Errorf("invalid starter message was sent")
+	}
+}
+
+func TestGuildThreadsActive(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v6/guilds/guild/threads/active" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		w.Write([]byte(`{
+			"threads":[
+				{"id":"t1","type":11,"parent_id":"c1","owner_id":"u1","message_count":3,"member_count":2,
+				 "thread_metadata":{"archived":false,"auto_archive_duration":1440,"archive_timestamp":"2021-06-01T00:00:00+00:00","locked":true}},
+				{"id":"t2","type":12,"parent_id":"c1"}
+			],
+			"members":[{"id":"t1","user_id":"bot","join_timestamp":"2021-06-01T00:00:00+00:00","flags":1}]
+		}`))
+	})
+
+	list, err := s.GuildThreadsActive("guild")
+	if err != nil {
+		t.Fatalf("GuildThreadsActive returned error: %+v", err)
+	}
+
+	if len(list.Threads) != 2 || len(list.Members) != 1 {
+		t.Fatalf("got %d threads and %d members, expected 2 and 1", len(list.Threads), len(list.Members))
+	}
+
+	thread := list.Threads[0]
+	if thread.OwnerID != "u1" || thread.MessageCount != 3 || thread.MemberCount != 2 {
+		t.Errorf("unexpected thread %+v", thread)
+	}
+	if thread.ThreadMetadata == nil || !thread.ThreadMetadata.Locked || thread.ThreadMetadata.AutoArchiveDuration != 1440 {
+		t.Errorf("unexpected thread metadata %+v", thread.ThreadMetadata)
+	}
+	if list.Threads[1].Type != ChannelTypeGuildPrivateThread {
+		t.Errorf("second thread has type %d, expected %d", list.Threads[1].Type, ChannelTypeGuildPrivateThread)
+	}
+	if m := list.Members[0]; m.ID != "t1" || m.UserID != "bot" || m.Flags != 1 {
+		t.Errorf("unexpected thread member %+v", m)
+	}
+}
+
+func TestThreadsArchived(t *testing.T) {
+	pages := map[string]string{
+		"":                          `{"threads":[{"id":"t2","thread_metadata":{"archived":true,"archive_timestamp":"2021-06-02T00:00:00+00:00"}}],"members":[],"has_more":true}`,
+		"2021-06-02T00:00:00+00:00": `{"threads":[{"id":"t1","thread_metadata":{"archived":true,"archive_timestamp":"2021-06-01T00:00:00+00:00"}}],"members":[],"has_more":false}`,
+	}
+
+	for _, visibility := range []string{"public", "private"} {
+		s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v6/channels/channel/threads/archived/"+visibility {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			}
+			if r.URL.Query().Get("limit") != "1" {
+				t.Errorf("limit is %q, expected 1", r.URL.Query().Get("limit"))
+			}
+			w.Write([]byte(pages[r.URL.Query().Get("before")]))
+		})
+
+		list := s.ThreadsArchivedPublic
+		if visibility == "private" {
+			list = s.ThreadsArchivedPrivate
+		}
+
+		var ids []string
+		before := ""
+		for {
+			page, err := list("channel", before, 1)
+			if err != nil {
+				t.Fatalf("%s: archived threads returned error: %+v", visibility, err)
+			}
+			for _, thread := range page.Threads {
+				ids = append(ids, thread.ID)
+			}
+			if !page.HasMore {
+				break
+			}
+			before = string(page.Threads[len(page.Threads)-1].ThreadMetadata.ArchiveTimestamp)
+		}
+
+		if len(ids) != 2 || ids[0] != "t2" || ids[1] != "t1" {
+			t.Errorf("%s: walked threads %v, expected [t2 t1]", visibility, ids)
+		}
+	}
+}
+
+func TestChannelMessageSendReply(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		b, _ := json.Marshal(body)
+		if r.URL.Path == "/api/v6/channels/channel/messages" && body["content"] == "pong" {
+			if expected := `{"content":"pong","message_reference":{"channel_id":"channel","message_id":"ping"},"tts":false}`; string(b) != expected {
+				t.Errorf("reply body is %s, expected %s", b, expected)
+			}
+		} else if expected := `{"embed":{"title":"pong","type":"rich"},"message_reference":{"message_id":"ping"},"tts":false}`; string(b) != expected {
+			t.Errorf("embed reply body is %s, expected %s", b, expected)
+		}
+
+		w.Write([]byte(`{"id":"reply","message_reference":{"message_id":"ping"}}`))
+	})
+
+	m, err := s.ChannelMessageSendReply("channel", "pong", (&Message{ID: "ping", ChannelID: "channel"}).Reference())
+	if err != nil {
+		t.Fatalf("ChannelMessageSendReply returned error: %+v", err)
+	}
+	if m.MessageReference == nil || m.MessageReference.MessageID != "ping" {
+		t.Errorf("reply has message reference %+v, expected ping", m.MessageReference)
+	}
+
+	if _, err = s.ChannelMessageSendEmbedReply("channel", &MessageEmbed{Title: "pong"}, &MessageReference{MessageID: "ping"}); err != nil {
+		t.Fatalf("ChannelMessageSendEmbedReply returned error: %+v", err)
+	}
+
+	if _, err = s.ChannelMessageSendReply("channel", "pong", nil); err != ErrNilReference {
+		t.Errorf("ChannelMessageSendReply with a nil reference returned %v, expected ErrNilReference", err)
+	}
+}
+
+// countingReader produces size bytes without holding them in memory, and
+// counts how many were read.
+type countingReader struct {
+	size int64
+	read int64
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	read := atomic.LoadInt64(&r.read)
+	if read >= r.size {
+		return 0, io.EOF
+	}
+	if n = len(p); int64(n) > r.size-read {
+		n = int(r.size - read)
+	}
+	for i := range p[:n] {
+		p[i] = 'x'
+	}
+	atomic.AddInt64(&r.read, int64(n))
+	return
+}
+
+func TestChannelMessageSendComplexStreamsFiles(t *testing.T) {
+	const size = 8 << 20
+	file := &countingReader{size: size}
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+			t.Fatalf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+
+		mr := multipart.NewReader(r.Body, params["boundary"])
+
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("error reading payload part, %s", err)
+		}
+		if part.FormName() != "payload_json" || part.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected payload part headers %v", part.Header)
+		}
+		if payload, _ := ioutil.ReadAll(part); string(payload) != `{"content":"video","tts":false}` {
+			t.Errorf("unexpected payload %s", payload)
+		}
+
+		part, err = mr.NextPart()
+		if err != nil {
+			t.Fatalf("error reading file part, %s", err)
+		}
+		if part.FormName() != "files[0]" || part.FileName() != `clip "1".mp4` || part.Header.Get("Content-Type") != "video/mp4" {
+			t.Errorf("unexpected file part headers %v", part.Header)
+		}
+
+		// The file is streamed to the request, not read before sending it.
+		if read := atomic.LoadInt64(&file.read); read > size/2 {
+			t.Errorf("%d of %d bytes were read before the file part was received", read, size)
+		}
+
+		n, _ := io.Copy(ioutil.Discard, part)
+		if n != size {
+			t.Errorf("file part holds %d bytes, expected %d", n, size)
+		}
+
+		w.Write([]byte(`{"id":"message"}`))
+	})
+
+	_, err := s.ChannelMessageSendComplex("channel", &MessageSend{
+		Content: "video",
+		Files:   []*File{{Name: `clip "1".mp4`, ContentType: "video/mp4", Reader: file}},
+	})
+	if err != nil {
+		t.Fatalf("ChannelMessageSendComplex returned error: %+v", err)
+	}
+}
+
+func TestChannelMessageSendComplexRetriesFiles(t *testing.T) {
+	var bodies []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"id":"message"}`))
+	})
+
+	_, err := s.ChannelMessageSendComplex("channel", &MessageSend{
+		Files: []*File{{Name: "a.txt", Reader: bytes.NewReader([]byte("contents"))}},
+	})
+	if err != nil {
+		t.Fatalf("ChannelMessageSendComplex returned error: %+v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || !bytes.Contains([]byte(bodies[1]), []byte("contents")) {
+		t.Errorf("retried upload differs from the first attempt: %q", bodies)
+	}
+
+	// A reader is rewound to where it was when it was passed in.
+	bodies = nil
+	advanced := bytes.NewReader([]byte("skipped contents"))
+	advanced.Seek(int64(len("skipped ")), io.SeekStart)
+	_, err = s.ChannelMessageSendComplex("channel", &MessageSend{
+		Files: []*File{{Name: "a.txt", Reader: advanced}},
+	})
+	if err != nil {
+		t.Fatalf("ChannelMessageSendComplex returned error: %+v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || strings.Contains(bodies[1], "skipped") {
+		t.Errorf("retried upload differs from the first attempt: %q", bodies)
+	}
+
+	bodies = nil
+	_, err = s.ChannelMessageSendComplex("channel", &MessageSend{
+		Files: []*File{{Name: "a.txt", Reader: &countingReader{size: 10}}},
+	})
+	if err != ErrFileNotSeekable {
+		t.Errorf("retrying an upload from a non seekable reader returned %v, expected ErrFileNotSeekable", err)
+	}
+}
+
+// slowSeeker is a slow io.ReadSeeker which records whether it was rewound
+// while being read.
+type slowSeeker struct {
+	r          *bytes.Reader
+	reading    int32
+	overlapped int32
+}
+
+func (r *slowSeeker) Read(p []byte) (int, error) {
+	atomic.StoreInt32(&r.reading, 1)
+	defer atomic.StoreInt32(&r.reading, 0)
+
+	time.Sleep(20 * time.Millisecond)
+	if len(p) > 512 {
+		p = p[:512]
+	}
+	return r.r.Read(p)
+}
+
+func (r *slowSeeker) Seek(offset int64, whence int) (int64, error) {
+	if atomic.LoadInt32(&r.reading) == 1 {
+		atomic.StoreInt32(&r.overlapped, 1)
+	}
+	return r.r.Seek(offset, whence)
+}
+
+func TestChannelMessageSendComplexRetryWaitsForWriter(t *testing.T) {
+	contents := bytes.Repeat([]byte("x"), 2048)
+	file := &slowSeeker{r: bytes.NewReader(contents)}
+
+	attempts := 0
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			// Reply once the file is being read, before it is sent whole.
+			_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			mr := multipart.NewReader(r.Body, params["boundary"])
+			mr.NextPart()
+			mr.NextPart()
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if !bytes.Contains(b, contents) {
+			t.Errorf("retried upload does not hold the whole file")
+		}
+		w.Write([]byte(`{"id":"message"}`))
+	})
+
+	_, err := s.ChannelMessageSendComplex("channel", &MessageSend{
+		Files: []*File{{Name: "a.txt", Reader: file}},
+	})
+	if err != nil {
+		t.Fatalf("ChannelMessageSendComplex returned error: %+v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("the upload was sent %d times, expected 2", attempts)
+	}
+	if atomic.LoadInt32(&file.overlapped) == 1 {
+		t.Error("the file was rewound while the previous attempt was reading it")
+	}
+}
+
+func TestChannelMessageSendComplexFileDescriptions(t *testing.T) {
+	var payload struct {
+		Attachments []struct {
+			ID          int    `json:"id"`
+			Filename    string `json:"filename"`
+			Description string `json:"description"`
+		} `json:"attachments"`
+	}
+	var files []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if part.FormName() == "payload_json" {
+				json.NewDecoder(part).Decode(&payload)
+				continue
+			}
+			files = append(files, part.FormName()+" "+part.FileName())
+		}
+		w.Write([]byte(`{"id":"message"}`))
+	})
+
+	_, err := s.ChannelMessageSendComplex("channel", &MessageSend{
+		Files: []*File{
+			{Name: "cat.png", Reader: bytes.NewReader([]byte("cat")), Description: "A cat asleep on a keyboard"},
+			nil,
+			{Name: "notes.txt", Reader: bytes.NewReader([]byte("notes"))},
+			{Name: "dog.png", Reader: bytes.NewReader([]byte("dog")), Description: "A dog"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ChannelMessageSendComplex returned error: %+v", err)
+	}
+
+	// Nil files are skipped, and each file is matched to its attachment
+	// by the index in its part name.
+	if strings.Join(files, ",") != "files[0] cat.png,files[1] notes.txt,files[2] dog.png" {
+		t.Fatalf("files were sent as %q", files)
+	}
+	if len(payload.Attachments) != 3 {
+		t.Fatalf("payload has attachments %+v, expected one for each file", payload.Attachments)
+	}
+	for i, expected := range []string{"A cat asleep on a keyboard", "", "A dog"} {
+		a := payload.Attachments[i]
+		if a.ID != i || a.Filename != files[i][len("files[0] "):] || a.Description != expected {
+			t.Errorf("attachment %d is %+v, expected files[%d] with description %q", i, a, i, expected)
+		}
+	}
+
+	// The attachments of a forum post are sent in its message.
+	b, _ := json.Marshal(&ForumThreadStart{Name: "post", Message: &MessageSend{
+		File: &File{Name: "cat.png", Description: "A cat"},
+	}})
+	if !bytes.Contains(b, []byte(`"message":{"tts":false,"attachments":[{"id":0,"filename":"cat.png","description":"A cat"}]}`)) {
+		t.Errorf("forum post was marshaled as %s", b)
+	}
+}
+
+func TestGuildMembersAddRole(t *testing.T) {
+	var inFlight, maxInFlight int32
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if r.Method != "PUT" {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		if strings.Contains(r.URL.Path, "/members/owner/") {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"code":50013,"message":"Missing Permissions"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	errs := s.GuildMembersAddRole("guild", "role", []string{"a", "b", "owner", "c", "d"}, 2)
+
+	if len(errs) != 1 {
+		t.Fatalf("GuildMembersAddRole returned %d errors, expected 1: %v", len(errs), errs)
+	}
+	restErr, ok := errs["owner"].(*RESTError)
+	if !ok || restErr.Message == nil || restErr.Message.Code != 50013 {
+		t.Errorf("error for owner is %v, expected Missing Permissions", errs["owner"])
+	}
+	if max := atomic.LoadInt32(&maxInFlight); max > 2 {
+		t.Errorf("%d requests were in flight at once, expected at most 2", max)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var agent string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		agent = r.Header.Get("User-Agent")
+		w.Write([]byte(`{}`))
+	})
+
+	s.Channel("channel")
+	if agent != DefaultUserAgent {
+		t.Errorf("User-Agent is %q, expected the default %q", agent, DefaultUserAgent)
+	}
+
+	s.UserAgent = "DiscordBot (https://example.com/mybot, 1.2.3)"
+	s.Channel("channel")
+	if agent != s.UserAgent {
+		t.Errorf("User-Agent is %q, expected %q", agent, s.UserAgent)
+	}
+}
+
+func TestGuildBansPage(t *testing.T) {
+	var queries []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v6/guilds/guild/bans" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		queries = append(queries, r.URL.RawQuery)
+
+		switch r.URL.Query().Get("after") {
+		case "":
+			w.Write([]byte(`[{"reason":"spam","user":{"id":"1"}},{"reason":"raid","user":{"id":"2"}}]`))
+		case "2":
+			w.Write([]byte(`[{"user":{"id":"3"}}]`))
+		}
+	})
+
+	var bans []*GuildBan
+	after := ""
+	for {
+		page, err := s.GuildBansPage("guild", "", after, 2)
+		if err != nil {
+			t.Fatalf("GuildBansPage returned error: %+v", err)
+		}
+		bans = append(bans, page...)
+		if len(page) < 2 {
+			break
+		}
+		after = page[len(page)-1].User.ID
+	}
+
+	if len(bans) != 3 || bans[0].Reason != "spam" || bans[1].User.ID != "2" || bans[2].User.ID != "3" {
+		t.Errorf("paged bans are %+v", bans)
+	}
+	if len(queries) != 2 || queries[0] != "limit=2" || queries[1] != "after=2&limit=2" {
+		t.Errorf("bans were requested with queries %q", queries)
+	}
+
+	queries = nil
+	s.GuildBansPage("guild", "9", "", 0)
+	s.GuildBans("guild")
+	if len(queries) != 2 || queries[0] != "before=9" || queries[1] != "" {
+		t.Errorf("bans were requested with queries %q", queries)
+	}
+}
+
+func TestGuildBulkBan(t *testing.T) {
+	var body map[string]interface{}
+	var reason string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v6/guilds/guild/bulk-ban" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		reason = r.Header.Get("X-Audit-Log-Reason")
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"banned_users":["1","2"],"failed_users":["3"]}`))
+	})
+
+	banned, failed, err := s.GuildBulkBan("guild", []string{"1", "2", "3"}, 3600, "raid")
+	if err != nil {
+		t.Fatalf("GuildBulkBan returned error: %+v", err)
+	}
+	if len(banned) != 2 || banned[0] != "1" || banned[1] != "2" || len(failed) != 1 || failed[0] != "3" {
+		t.Errorf("GuildBulkBan returned banned %q, failed %q", banned, failed)
+	}
+
+	ids, _ := body["user_ids"].([]interface{})
+	if len(ids) != 3 || ids[2] != "3" || body["delete_message_seconds"] != 3600.0 {
+		t.Errorf("users were banned with %v", body)
+	}
+	if reason != "raid" {
+		t.Errorf("audit log reason is %q", reason)
+	}
+
+	if _, _, err = s.GuildBulkBan("guild", make([]string, 201), 0, ""); err == nil {
+		t.Error("GuildBulkBan with 201 users returned no error")
+	}
+}
+
+func TestGuildBan(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v6/guilds/guild/bans/banned":
+			w.Write([]byte(`{"reason":"spam","user":{"id":"banned"}}`))
+		case "/api/v6/guilds/guild/bans/member":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":10026,"message":"Unknown Ban"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":10004,"message":"Unknown Guild"}`))
+		}
+	})
+
+	ban, err := s.GuildBan("guild", "banned")
+	if err != nil {
+		t.Fatalf("GuildBan returned error: %+v", err)
+	}
+	if ban.Reason != "spam" || ban.User == nil || ban.User.ID != "banned" {
+		t.Errorf("GuildBan returned %+v", ban)
+	}
+
+	if _, err = s.GuildBan("guild", "member"); err != ErrNotBanned {
+		t.Errorf("GuildBan of a user who is not banned returned %v, expected ErrNotBanned", err)
+	}
+
+	_, err = s.GuildBan("unknown", "member")
+	if restErr, ok := err.(*RESTError); !ok || restErr.Message == nil || restErr.Message.Code != ErrCodeUnknownGuild {
+		t.Errorf("GuildBan in an unknown guild returned %v, expected the REST error", err)
+	}
+}
+
+func TestSendWithoutState(t *testing.T) {
+	var requests []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{"id":"message","channel_id":"channel","content":"<@user> hi"}`))
+	})
+	s.StateEnabled = false
+	s.State = nil
+	s.SyncEvents = true
+
+	if _, err := s.ChannelMessageSend("channel", "hi"); err != nil {
+		t.Fatalf("ChannelMessageSend returned error: %+v", err)
+	}
+	if _, err := s.ChannelMessageSendReply("channel", "hi", &MessageReference{MessageID: "other"}); err != nil {
+		t.Fatalf("ChannelMessageSendReply returned error: %+v", err)
+	}
+	if _, err := s.ChannelMessageSendEmbed("channel", &MessageEmbed{Title: "hi"}); err != nil {
+		t.Fatalf("ChannelMessageSendEmbed returned error: %+v", err)
+	}
+
+	m, err := (&Channel{ID: "channel"}).SendMessage(s, "hi", nil, nil)
+	if err != nil {
+		t.Fatalf("Channel.SendMessage returned error: %+v", err)
+	}
+	m.Mentions = []*User{{ID: "user", Username: "someone"}}
+	if content, err := m.ContentWithMoreMentionsReplaced(s); err != nil || content != "@someone hi" {
+		t.Errorf("ContentWithMoreMentionsReplaced returned %q, %v", content, err)
+	}
+
+	if len(requests) != 4 {
+		t.Errorf("%d requests were made, expected 4 messages to be sent", len(requests))
+	}
+
+	// Events which read State internally are handled without it.
+	s.VoiceConnections = map[string]*VoiceConnection{"guild": {}}
+	s.onVoiceStateUpdate(&VoiceStateUpdate{VoiceState: &VoiceState{GuildID: "guild", ChannelID: "channel", UserID: "user"}})
+	if _, err := s.onEvent(websocket.TextMessage, []byte(`{"op":0,"s":1,"t":"MESSAGE_CREATE","d":{"id":"message","channel_id":"channel"}}`)); err != nil {
+		t.Errorf("onEvent returned error: %+v", err)
+	}
+}
+
+func TestRequestCustomEndpoint(t *testing.T) {
+	var requests []*http.Request
+	var bodies []map[string]interface{}
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+
+		if len(requests) == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", "200")
+		}
+		w.Write([]byte(`{"id":"thing"}`))
+	})
+
+	body, err := s.Request("POST", "guilds/guild/new-feature", map[string]string{"name": "thing"}, WithHeader("X-Test", "yes"), WithAuditLogReason("trying it out"))
+	if err != nil {
+		t.Fatalf("Request returned error: %+v", err)
+	}
+	if string(body) != `{"id":"thing"}` {
+		t.Errorf("Request returned %s", body)
+	}
+
+	r := requests[0]
+	if r.Method != "POST" || r.URL.String() != EndpointAPI+"guilds/guild/new-feature" {
+		t.Errorf("request was sent to %s %s", r.Method, r.URL)
+	}
+	if r.Header.Get("authorization") != "Bot test" || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("request was sent with headers %v, expected the token and a JSON body", r.Header)
+	}
+	if r.Header.Get("X-Test") != "yes" || r.Header.Get("X-Audit-Log-Reason") != "trying%20it%20out" {
+		t.Errorf("request was sent with headers %v, expected the headers of the options", r.Header)
+	}
+	if bodies[0]["name"] != "thing" {
+		t.Errorf("request was sent with body %v", bodies[0])
+	}
+
+	// The first response exhausted the bucket of the endpoint, so the next
+	// request waits for it to reset.
+	start := time.Now()
+	if _, err = s.Request("GET", EndpointAPI+"guilds/guild/new-feature?with_counts=true", nil); err != nil {
+		t.Fatalf("Request returned error: %+v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("second request was sent after %s, expected it to wait for the rate limit", elapsed)
+	}
+	if len(requests) != 2 || requests[1].URL.RawQuery != "with_counts=true" {
+		t.Errorf("second request was sent to %s", requests[len(requests)-1].URL)
+	}
+}
+
+func TestRequestJSON(t *testing.T) {
+	type soundboardSound struct {
+		ID     string  `json:"sound_id"`
+		Name   string  `json:"name"`
+		Volume float64 `json:"volume"`
+	}
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/v6/guilds/guild/soundboard-sounds/sound" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"sound_id":"sound","name":"quack","volume":0.5,"available":true}`))
+	})
+
+	sound, err := RequestJSON[soundboardSound](s, "GET", "guilds/guild/soundboard-sounds/sound", nil)
+	if err != nil {
+		t.Fatalf("RequestJSON returned error: %+v", err)
+	}
+	if sound.ID != "sound" || sound.Name != "quack" || sound.Volume != 0.5 {
+		t.Errorf("RequestJSON decoded %+v", sound)
+	}
+
+	s = newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`not json`))
+	})
+	if _, err = RequestJSON[soundboardSound](s, "GET", "guilds/guild/soundboard-sounds/sound", nil); err != ErrJSONUnmarshal {
+		t.Errorf("RequestJSON returned %v for an invalid response, expected ErrJSONUnmarshal", err)
+	}
+}
+
+func TestVoiceRegionsDecode(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/v6/voice/regions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`[{"id":"rotterdam","name":"Rotterdam","optimal":true,"deprecated":false,"custom":false},{"id":"amsterdam","name":"Amsterdam","optimal":false,"deprecated":true,"custom":false}]`))
+	})
+
+	regions, err := s.VoiceRegions()
+	if err != nil {
+		t.Fatalf("VoiceRegions returned error: %+v", err)
+	}
+	if len(regions) != 2 {
+		t.Fatalf("VoiceRegions returned %d regions, expected 2", len(regions))
+	}
+	if r := regions[0]; r.ID != "rotterdam" || r.Name != "Rotterdam" || !r.Optimal || r.Deprecated {
+		t.Errorf("first region decoded as %+v", r)
+	}
+	if r := regions[1]; r.ID != "amsterdam" || r.Optimal || !r.Deprecated {
+		t.Errorf("second region decoded as %+v", r)
+	}
+}
+
+func TestChannelEditRTCRegion(t *testing.T) {
+	var edits []map[string]interface{}
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		var edit map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&edit)
+		edits = append(edits, edit)
+		w.Write([]byte(`{"id":"voice","type":2,"rtc_region":"rotterdam"}`))
+	})
+
+	region := "rotterdam"
+	c, err := s.ChannelEditComplex("voice", &ChannelEdit{RTCRegion: &region})
+	if err != nil {
+		t.Fatalf("ChannelEditComplex returned error: %+v", err)
+	}
+	if edits[0]["rtc_region"] != "rotterdam" {
+		t.Errorf("channel was edited with %v, expected the rtc_region", edits[0])
+	}
+	if c.RTCRegion != "rotterdam" {
+		t.Errorf("edited channel has region %q", c.RTCRegion)
+	}
+
+	if _, err = s.ChannelEditComplex("voice", &ChannelEdit{Name: "voice"}); err != nil {
+		t.Fatalf("ChannelEditComplex returned error: %+v", err)
+	}
+	if _, ok := edits[1]["rtc_region"]; ok {
+		t.Errorf("channel was edited with %v, expected the region to be left unchanged", edits[1])
+	}
+
+	automatic := ""
+	if _, err = s.ChannelEditComplex("voice", &ChannelEdit{RTCRegion: &automatic}); err != nil {
+		t.Fatalf("ChannelEditComplex returned error: %+v", err)
+	}
+	if region, ok := edits[2]["rtc_region"]; !ok || region != nil {
+		t.Errorf("channel was edited with %v, expected a null rtc_region", edits[2])
+	}
+}
+
+func TestDefaultAllowedMentions(t *testing.T) {
+	var sent []map[string]interface{}
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		sent = append(sent, body)
+		w.Write([]byte(`{"id":"message"}`))
+	})
+	s.DefaultAllowedMentions = &MessageAllowedMentions{Parse: []AllowedMentionType{AllowedMentionTypeUsers}}
+
+	parse := func(i int) interface{} {
+		mentions, ok := sent[i]["allowed_mentions"].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		return mentions["parse"]
+	}
+
+	data := &MessageSend{Content: "@everyone hi"}
+	if _, err := s.ChannelMessageSendComplex("channel", data); err != nil {
+		t.Fatalf("ChannelMessageSendComplex returned error: %+v", err)
+	}
+	if p, ok := parse(0).([]interface{}); !ok || len(p) != 1 || p[0] != "users" {
+		t.Errorf("message was sent with allowed mentions %v, expected the default", sent[0]["allowed_mentions"])
+	}
+	if data.AllowedMentions != nil {
+		t.Error("the default allowed mentions were set on the MessageSend of the caller")
+	}
+
+	_, err := s.ChannelMessageSendComplex("channel", &MessageSend{
+		Content:         "@everyone hi",
+		AllowedMentions: &MessageAllowedMentions{Parse: []AllowedMentionType{AllowedMentionTypeEveryone}},
+	})
+	if err != nil {
+		t.Fatalf("ChannelMessageSendComplex returned error: %+v", err)
+	}
+	if p, ok := parse(1).([]interface{}); !ok || len(p) != 1 || p[0] != "everyone" {
+		t.Errorf("message was sent with allowed mentions %v, expected its own", sent[1]["allowed_mentions"])
+	}
+
+	if err = s.WebhookExecute("webhook", "token", false, &WebhookParams{Content: "hi"}); err != nil {
+		t.Fatalf("WebhookExecute returned error: %+v", err)
+	}
+	if p, ok := parse(2).([]interface{}); !ok || len(p) != 1 || p[0] != "users" {
+		t.Errorf("webhook was executed with allowed mentions %v, expected the default", sent[2]["allowed_mentions"])
+	}
+
+	err = s.InteractionRespond(&Interaction{ID: "interaction", Token: "token"}, &InteractionResponse{
+		Type: InteractionResponseChannelMessageWithSource,
+		Data: &InteractionResponseData{Content: "hi", AllowedMentions: &MessageAllowedMentions{Parse: []AllowedMentionType{}}},
+	})
+	if err != nil {
+		t.Fatalf("InteractionRespond returned error: %+v", err)
+	}
+	response, _ := sent[3]["data"].(map[string]interface{})
+	if mentions, _ := response["allowed_mentions"].(map[string]interface{}); mentions == nil || len(mentions["parse"].([]interface{})) != 0 {
+		t.Errorf("interaction was responded to with %v, expected no allowed mentions", response)
+	}
+
+	s.DefaultAllowedMentions = nil
+	if _, err = s.ChannelMessageSend("channel", "hi"); err != nil {
+		t.Fatalf("ChannelMessageSend returned error: %+v", err)
+	}
+	if _, ok := sent[4]["allowed_mentions"]; ok {
+		t.Errorf("message was sent with allowed mentions %v without a default", sent[4]["allowed_mentions"])
+	}
+}
+
+func TestChannelMessageOrFetch(t *testing.T) {
+	var requests []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		w.Write([]byte(`{"id":"fetched","channel_id":"channel","content":"from the API"}`))
+	})
+	s.State.MaxMessageCount = 10
+	s.State.GuildAdd(&Guild{ID: "guild", Channels: []*Channel{{ID: "channel", GuildID: "guild"}}})
+	s.State.MessageAdd(&Message{ID: "cached", ChannelID: "channel", Content: "from State"})
+
+	m, err := s.ChannelMessageOrFetch("channel", "cached")
+	if err != nil || m.Content != "from State" {
+		t.Fatalf("ChannelMessageOrFetch returned %+v, %v, expected the cached message", m, err)
+	}
+	if len(requests) != 0 {
+		t.Errorf("a cached message was fetched with %q", requests)
+	}
+
+	m, err = s.ChannelMessageOrFetch("channel", "fetched")
+	if err != nil || m.Content != "from the API" {
+		t.Fatalf("ChannelMessageOrFetch returned %+v, %v, expected the fetched message", m, err)
+	}
+	if len(requests) != 1 || requests[0] != "/api/v6/channels/channel/messages/fetched" {
+		t.Errorf("requests were %q", requests)
+	}
+	if cached, err := s.State.Message("channel", "fetched"); err != nil || cached != m {
+		t.Errorf("the fetched message was not cached in State")
+	}
+
+	if _, err = s.ChannelMessageOrFetch("channel", "fetched"); err != nil || len(requests) != 1 {
+		t.Errorf("the fetched message was fetched again, requests %q", requests)
+	}
+}
@@ -0,0 +1,71 @@
+// Discordgo - Discord bindings for Go
+// Available at https://github.com/bwmarrin/discordgo
+
+// Copyright 2015-2016 Bruce Marriner <bruce@sqls.net>.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains code related to remembering the messages already
+// handled, to skip those received again
+
+package discordgo
+
+import "sync"
+
+// defaultSeenMessageWindow is the number of message IDs remembered by
+// SeenMessage when Session.SeenMessageWindow is not set.
+const defaultSeenMessageWindow = 1000
+
+// seenMessages holds the IDs of the last messages passed to SeenMessage.
+type seenMessages struct {
+	sync.Mutex
+
+	// the IDs in the order they were seen, used as a ring buffer
+	ids  []string
+	next int
+
+	set map[string]struct{}
+}
+
+// seen returns whether id was seen before, and remembers it if not. Once
+// window IDs are remembered, the oldest is forgotten for each new one.
+func (c *seenMessages) seen(id string, window int) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.set[id]; ok {
+		return true
+	}
+
+	if c.set == nil {
+		c.set = make(map[string]struct{})
+	}
+
+	if len(c.ids) < window {
+		c.ids = append(c.ids, id)
+	} else {
+		if c.next >= len(c.ids) {
+			c.next = 0
+		}
+		delete(c.set, c.ids[c.next])
+		c.ids[c.next] = id
+		c.next++
+	}
+	c.set[id] = struct{}{}
+
+	return false
+}
+
+// SeenMessage returns whether a message was passed to SeenMessage before.
+// Events can be delivered more than once, e.g. around reconnects, so
+// MessageCreate handlers can call it to skip messages they already handled.
+// Only the last SeenMessageWindow message IDs are remembered.
+// id : The ID of the message.
+func (s *Session) SeenMessage(id string) bool {
+	window := s.SeenMessageWindow
+	if window <= 0 {
+		window = defaultSeenMessageWindow
+	}
+
+	return s.seen.seen(id, window)
+}
@@ -0,0 +1,52 @@
+package discordgo
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSeenMessage(t *testing.T) {
+	s, _ := New()
+	s.SyncEvents = true
+
+	var handled []string
+	s.AddHandler(func(s *Session, m *MessageCreate) {
+		if s.SeenMessage(m.ID) {
+			return
+		}
+		handled = append(handled, m.ID)
+	})
+
+	create := []byte(`{"op":0,"s":1,"t":"MESSAGE_CREATE","d":{"id":"message","channel_id":"channel","content":"!ban"}}`)
+	for i := 0; i < 2; i++ {
+		if _, err := s.onEvent(websocket.TextMessage, create); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+	}
+
+	if len(handled) != 1 {
+		t.Errorf("message was handled %d times, expected the redelivery to be seen", len(handled))
+	}
+}
+
+func TestSeenMessageWindow(t *testing.T) {
+	s := &Session{SeenMessageWindow: 2}
+
+	for _, id := range []string{"a", "b", "c"} {
+		if s.SeenMessage(id) {
+			t.Errorf("new message %s was seen", id)
+		}
+	}
+
+	// a was forgotten to make room for c.
+	if !s.SeenMessage("c") || !s.SeenMessage("b") {
+		t.Error("messages in the window were not seen")
+	}
+	if s.SeenMessage("a") {
+		t.Error("message outside the window was seen")
+	}
+	if s.SeenMessage("b") {
+		t.Error("b was still seen after a replaced it")
+	}
+}
@@ -13,8 +13,8 @@
 package discordgo
 
 import (
+	"container/list"
 	"errors"
-	"sort"
 	"sync"
 )
 
@@ -25,6 +25,26 @@ var ErrNilState = errors.New("state not instantiated, please use discordgo.New()
 // requested is not found
 var ErrStateNotFound = errors.New("state cache not found")
 
+// ErrPresencesNotTracked is returned by State helpers which read presences
+// when presence tracking is disabled with State.TrackPresences.
+var ErrPresencesNotTracked = errors.New("presence tracking is disabled, see State.TrackPresences")
+
+// ErrStateDisabled is returned by helpers which read State when state
+// tracking is disabled with Session.StateEnabled.
+var ErrStateDisabled = errors.New("state tracking is disabled, see Session.StateEnabled")
+
+// state returns the State of a session if state tracking is enabled, for
+// helpers which read State.
+func (s *Session) state() (st *State, err error) {
+	if !s.StateEnabled {
+		return nil, ErrStateDisabled
+	}
+	if s.State == nil {
+		return nil, ErrNilState
+	}
+	return s.State, nil
+}
+
 // A State contains the current known state.
 // As discord sends this in a READY blob, it seems reasonable to simply
 // use that struct as the data store.
@@ -41,9 +61,39 @@ type State struct {
 	TrackVoice      bool
 	TrackPresences  bool
 
+	// MaxRemovedMemberCount represents how many removed members the state
+	// keeps for RecentlyRemovedMember. The least recently removed members
+	// are forgotten first.
+	MaxRemovedMemberCount int
+
 	guildMap   map[string]*Guild
 	channelMap map[string]*Channel
 	memberMap  map[string]map[string]*Member
+	emojiMap   map[string]map[string]*Emoji
+
+	// recently removed members, most recent first, and their elements by
+	// guild and user ID
+	removedMembers   *list.List
+	removedMemberMap map[string]*list.Element
+
+	// whether the session has state tracking disabled, as of its last Ready
+	disabled bool
+}
+
+// enabled returns ErrStateDisabled if the state is not tracked by its
+// session, for helpers which read it.
+func (s *State) enabled() error {
+	if s == nil {
+		return ErrNilState
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	if s.disabled {
+		return ErrStateDisabled
+	}
+	return nil
 }
 
 // NewState creates an empty state.
@@ -62,6 +112,9 @@ func NewState() *State {
 		guildMap:       make(map[string]*Guild),
 		channelMap:     make(map[string]*Channel),
 		memberMap:      make(map[string]map[string]*Member),
+		emojiMap:       make(map[string]map[string]*Emoji),
+
+		MaxRemovedMemberCount: 100,
 	}
 }
 
@@ -73,6 +126,14 @@ func (s *State) createMemberMap(guild *Guild) {
 	s.memberMap[guild.ID] = members
 }
 
+func (s *State) createEmojiMap(guild *Guild) {
+	emojis := make(map[string]*Emoji, len(guild.Emojis))
+	for _, e := range guild.Emojis {
+		emojis[e.Name] = e
+	}
+	s.emojiMap[guild.ID] = emojis
+}
+
 // GuildAdd adds a guild to the current world state, or
 // updates it if it already exists.
 func (s *State) GuildAdd(guild *Guild) error {
@@ -121,11 +182,13 @@ func (s *State) GuildAdd(guild *Guild) error {
 			guild.VoiceStates = g.VoiceStates
 		}
 		*g = *guild
+		s.createEmojiMap(g)
 		return nil
 	}
 
 	s.Guilds = append(s.Guilds, guild)
 	s.guildMap[guild.ID] = guild
+	s.createEmojiMap(guild)
 
 	return nil
 }
@@ -145,6 +208,14 @@ func (s *State) GuildRemove(guild *Guild) error {
 	defer s.Unlock()
 
 	delete(s.guildMap, guild.ID)
+	delete(s.emojiMap, guild.ID)
+	delete(s.memberMap, guild.ID)
+
+	for id, c := range s.channelMap {
+		if c.GuildID == guild.ID {
+			delete(s.channelMap, id)
+		}
+	}
 
 	for i, g := range s.Guilds {
 		if g.ID == guild.ID {
@@ -156,6 +227,21 @@ func (s *State) GuildRemove(guild *Guild) error {
 	return nil
 }
 
+// guildUnavailable marks a guild in the state as unavailable, keeping
+// everything known about it until it becomes available again.
+func (s *State) guildUnavailable(guildID string) error {
+	g, err := s.Guild(guildID)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	g.Unavailable = true
+	return nil
+}
+
 // Guild gets a guild by ID.
 // Useful for querying if @me is in a guild:
 //     _, err := discordgo.Session.State.Guild(guildID)
@@ -276,6 +362,108 @@ func (s *State) Presence(guildID, userID string) (*Presence, error) {
 	return nil, ErrStateNotFound
 }
 
+// A MemberWithPresence is a member of a guild along with its presence.
+type MemberWithPresence struct {
+	*Member
+
+	// The presence of the member, nil if none is known, which usually
+	// means the member is offline.
+	Presence *Presence
+}
+
+// GuildMembersWithStatus returns the members of a guild known to the state,
+// each with its presence if it is known.
+// guildID : The ID of a Guild.
+func (s *State) GuildMembersWithStatus(guildID string) ([]*MemberWithPresence, error) {
+	if err := s.enabled(); err != nil {
+		return nil, err
+	}
+	if !s.TrackPresences {
+		return nil, ErrPresencesNotTracked
+	}
+
+	guild, err := s.Guild(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	presences := make(map[string]*Presence, len(guild.Presences))
+	for _, p := range guild.Presences {
+		presences[p.User.ID] = p
+	}
+
+	members := make([]*MemberWithPresence, 0, len(guild.Members))
+	for _, m := range guild.Members {
+		members = append(members, &MemberWithPresence{Member: m, Presence: presences[m.User.ID]})
+	}
+
+	return members, nil
+}
+
+// stateGuild returns the guild g as known to the state of a session, or
+// nil if it is not known.
+func (g *Guild) stateGuild(s *Session) (*State, *Guild) {
+	state, err := s.state()
+	if err != nil {
+		return nil, nil
+	}
+
+	guild, err := state.Guild(g.ID)
+	if err != nil {
+		return nil, nil
+	}
+	return state, guild
+}
+
+// StateMembers returns the members of the guild known to the state of a
+// session, or nil if the guild is not known. Unlike the Members field, it
+// stays up to date with the events received after the guild was.
+// s : The Session whose State is read.
+func (g *Guild) StateMembers(s *Session) []*Member {
+	state, guild := g.stateGuild(s)
+	if guild == nil {
+		return nil
+	}
+
+	state.RLock()
+	defer state.RUnlock()
+
+	return append([]*Member(nil), guild.Members...)
+}
+
+// StateChannels returns the channels of the guild known to the state of a
+// session, or nil if the guild is not known.
+// s : The Session whose State is read.
+func (g *Guild) StateChannels(s *Session) []*Channel {
+	state, guild := g.stateGuild(s)
+	if guild == nil {
+		return nil
+	}
+
+	state.RLock()
+	defer state.RUnlock()
+
+	return append([]*Channel(nil), guild.Channels...)
+}
+
+// StateRoles returns the roles of the guild known to the state of a
+// session, or nil if the guild is not known.
+// s : The Session whose State is read.
+func (g *Guild) StateRoles(s *Session) []*Role {
+	state, guild := g.stateGuild(s)
+	if guild == nil {
+		return nil
+	}
+
+	state.RLock()
+	defer state.RUnlock()
+
+	return append([]*Role(nil), guild.Roles...)
+}
+
 // TODO: Consider moving Guild state update methods onto *Guild.
 
 // MemberAdd adds a member to the current world state, or
@@ -333,11 +521,12 @@ func (s *State) MemberRemove(member *Member) error {
 		return ErrStateNotFound
 	}
 
-	_, ok = members[member.User.ID]
+	removed, ok := members[member.User.ID]
 	if !ok {
 		return ErrStateNotFound
 	}
 	delete(members, member.User.ID)
+	s.rememberRemovedMember(member.GuildID, removed)
 
 	for i, m := range guild.Members {
 		if m.User.ID == member.User.ID {
@@ -349,6 +538,62 @@ func (s *State) MemberRemove(member *Member) error {
 	return ErrStateNotFound
 }
 
+// removedMember is an element of State.removedMembers
+type removedMember struct {
+	key    string
+	member *Member
+}
+
+// removedMemberKey returns the key of a member in removedMemberMap.
+func removedMemberKey(guildID, userID string) string {
+	return guildID + "/" + userID
+}
+
+// rememberRemovedMember keeps a removed member for RecentlyRemovedMember.
+// The state must be locked.
+func (s *State) rememberRemovedMember(guildID string, member *Member) {
+	if s.MaxRemovedMemberCount <= 0 {
+		return
+	}
+
+	if s.removedMembers == nil {
+		s.removedMembers = list.New()
+		s.removedMemberMap = make(map[string]*list.Element)
+	}
+
+	key := removedMemberKey(guildID, member.User.ID)
+	if e, ok := s.removedMemberMap[key]; ok {
+		s.removedMembers.Remove(e)
+	}
+	s.removedMemberMap[key] = s.removedMembers.PushFront(removedMember{key, member})
+
+	for s.removedMembers.Len() > s.MaxRemovedMemberCount {
+		r := s.removedMembers.Remove(s.removedMembers.Back()).(removedMember)
+		delete(s.removedMemberMap, r.key)
+	}
+}
+
+// RecentlyRemovedMember returns a member as it was when it was last removed
+// from a guild, e.g. to log the roles of a member who left in a
+// GuildMemberRemove handler. Only the last MaxRemovedMemberCount removed
+// members are kept.
+// guildID   : The ID of a Guild.
+// userID    : The ID of a User.
+func (s *State) RecentlyRemovedMember(guildID, userID string) (*Member, bool) {
+	if s.enabled() != nil {
+		return nil, false
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	e, ok := s.removedMemberMap[removedMemberKey(guildID, userID)]
+	if !ok {
+		return nil, false
+	}
+	return e.Value.(removedMember).member, true
+}
+
 // Member gets a member by ID from a guild.
 func (s *State) Member(guildID, userID string) (*Member, error) {
 	if s == nil {
@@ -593,6 +838,8 @@ func (s *State) EmojiAdd(guildID string, emoji *Emoji) error {
 	s.Lock()
 	defer s.Unlock()
 
+	defer s.createEmojiMap(guild)
+
 	for i, e := range guild.Emojis {
 		if e.ID == emoji.ID {
 			guild.Emojis[i] = emoji
@@ -604,6 +851,35 @@ func (s *State) EmojiAdd(guildID string, emoji *Emoji) error {
 	return nil
 }
 
+// EmojiByName returns an emoji for a guild and emoji name.
+// The lookup is case sensitive, as emoji names are.
+func (s *State) EmojiByName(guildID, name string) (*Emoji, bool) {
+	if s.enabled() != nil {
+		return nil, false
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	e, ok := s.emojiMap[guildID][name]
+	return e, ok
+}
+
+// emojisSet replaces the emojis of a guild in the world state.
+func (s *State) emojisSet(guildID string, emojis []*Emoji) error {
+	guild, err := s.Guild(guildID)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	guild.Emojis = emojis
+	s.createEmojiMap(guild)
+	return nil
+}
+
 // EmojisAdd adds multiple emojis to the world state.
 func (s *State) EmojisAdd(guildID string, emojis []*Emoji) error {
 	for _, e := range emojis {
@@ -727,6 +1003,59 @@ func (s *State) voiceStateUpdate(update *VoiceStateUpdate) error {
 	return nil
 }
 
+// VoiceState gets the voice state of a user in a guild, if they are
+// connected to one of its voice channels. Voice states are only tracked
+// if TrackVoice is set.
+// guildID   : The ID of the guild.
+// userID    : The ID of the user.
+func (s *State) VoiceState(guildID, userID string) (*VoiceState, bool) {
+	if s == nil {
+		return nil, false
+	}
+
+	guild, err := s.Guild(guildID)
+	if err != nil {
+		return nil, false
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, state := range guild.VoiceStates {
+		if state.UserID == userID {
+			return state, true
+		}
+	}
+
+	return nil, false
+}
+
+// VoiceChannelMembers returns the IDs of the users connected to a voice
+// channel. Voice states are only tracked if TrackVoice is set.
+// guildID   : The ID of the guild.
+// channelID : The ID of the voice channel.
+func (s *State) VoiceChannelMembers(guildID, channelID string) (userIDs []string) {
+	if s.enabled() != nil {
+		return
+	}
+
+	guild, err := s.Guild(guildID)
+	if err != nil {
+		return
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, state := range guild.VoiceStates {
+		if state.ChannelID == channelID {
+			userIDs = append(userIDs, state.UserID)
+		}
+	}
+
+	return
+}
+
 // Message gets a message by channel and message ID.
 func (s *State) Message(channelID, messageID string) (*Message, error) {
 	if s == nil {
@@ -759,6 +1088,8 @@ func (s *State) onReady(se *Session, r *Ready) (err error) {
 	s.Lock()
 	defer s.Unlock()
 
+	s.disabled = !se.StateEnabled
+
 	// We must track at least the current user for Voice, even
 	// if state is disabled, store the bare essentials.
 	if !se.StateEnabled {
@@ -775,9 +1106,17 @@ func (s *State) onReady(se *Session, r *Ready) (err error) {
 
 	s.Ready = *r
 
+	// READY starts a new session, so the state is rebuilt from it and the
+	// guilds and channels of the previous session are forgotten.
+	s.guildMap = make(map[string]*Guild)
+	s.channelMap = make(map[string]*Channel)
+	s.memberMap = make(map[string]map[string]*Member)
+	s.emojiMap = make(map[string]map[string]*Emoji)
+
 	for _, g := range s.Guilds {
 		s.guildMap[g.ID] = g
 		s.createMemberMap(g)
+		s.createEmojiMap(g)
 
 		for _, c := range g.Channels {
 			s.channelMap[c.ID] = c
@@ -812,7 +1151,13 @@ func (s *State) OnInterface(se *Session, i interface{}) (err error) {
 	case *GuildUpdate:
 		err = s.GuildAdd(t.Guild)
 	case *GuildDelete:
-		err = s.GuildRemove(t.Guild)
+		// Guilds are deleted as unavailable during outages, and are only
+		// removed when the session actually left them.
+		if t.Unavailable {
+			err = s.guildUnavailable(t.ID)
+		} else {
+			err = s.GuildRemove(t.Guild)
+		}
 	case *GuildMemberAdd:
 		// Updates the MemberCount of the guild.
 		guild, err := s.Guild(t.Member.GuildID)
@@ -862,7 +1207,9 @@ func (s *State) OnInterface(se *Session, i interface{}) (err error) {
 		}
 	case *GuildEmojisUpdate:
 		if s.TrackEmojis {
-			err = s.EmojisAdd(t.GuildID, t.Emojis)
+			// The update holds every emoji of the guild, so emojis which
+			// are missing from it were deleted.
+			err = s.emojisSet(t.GuildID, t.Emojis)
 		}
 	case *ChannelCreate:
 		if s.TrackChannels {
@@ -973,6 +1320,42 @@ func (s *State) UserChannelPermissions(userID, channelID string) (apermissions i
 	return memberPermissions(guild, channel, member), nil
 }
 
+// MemberHighestRole returns the highest positioned role of a member, or the
+// @everyone role of the guild if the member has no other roles.
+// guildID   : The ID of the guild of the member.
+// userID    : The ID of the user.
+func (s *State) MemberHighestRole(guildID, userID string) (*Role, error) {
+	if err := s.enabled(); err != nil {
+		return nil, err
+	}
+
+	guild, err := s.Guild(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.Member(guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	if role := memberHighestRole(guild, member); role != nil {
+		return role, nil
+	}
+
+	// The @everyone role shares its ID with the guild.
+	for _, role := range guild.Roles {
+		if role.ID == guild.ID {
+			return role, nil
+		}
+	}
+
+	return nil, ErrStateNotFound
+}
+
 // UserColor returns the color of a user in a channel.
 // While colors are defined at a Guild level, determining for a channel is more useful in message handlers.
 // 0 is returned in cases of error, which is the color of @everyone.
@@ -988,28 +1371,47 @@ func (s *State) UserColor(userID, channelID string) int {
 		return 0
 	}
 
-	guild, err := s.Guild(channel.GuildID)
+	return s.MemberColor(channel.GuildID, userID)
+}
+
+// MemberColor returns the color of a member in a guild, which is the color
+// of their highest positioned role that has a color.
+// 0 is returned in cases of error, which is the color of @everyone.
+// guildID   : The ID of the guild of the member.
+// userID    : The ID of the user to calculate the color for.
+func (s *State) MemberColor(guildID, userID string) int {
+	if s.enabled() != nil {
+		return 0
+	}
+
+	guild, err := s.Guild(guildID)
 	if err != nil {
 		return 0
 	}
 
-	member, err := s.Member(guild.ID, userID)
+	member, err := s.Member(guildID, userID)
 	if err != nil {
 		return 0
 	}
 
-	roles := Roles(guild.Roles)
-	sort.Sort(roles)
+	s.RLock()
+	defer s.RUnlock()
 
-	for _, role := range roles {
+	var colored *Role
+	for _, role := range guild.Roles {
+		if role.Color == 0 || colored != nil && role.Position <= colored.Position {
+			continue
+		}
 		for _, roleID := range member.Roles {
 			if role.ID == roleID {
-				if role.Color != 0 {
-					return role.Color
-				}
+				colored = role
+				break
 			}
 		}
 	}
 
-	return 0
+	if colored == nil {
+		return 0
+	}
+	return colored.Color
 }
@@ -0,0 +1,448 @@
+package discordgo
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestStateEmojiByName(t *testing.T) {
+	s := &Session{StateEnabled: true, State: NewState()}
+
+	s.State.OnInterface(s, &GuildCreate{&Guild{
+		ID:     "guild",
+		Emojis: []*Emoji{{ID: "1", Name: "old"}, {ID: "2", Name: "kept"}},
+	}})
+
+	if e, ok := s.State.EmojiByName("guild", "old"); !ok || e.ID != "1" {
+		t.Fatalf("EmojiByName(old) = %+v, %t after GuildCreate", e, ok)
+	}
+
+	err := s.State.OnInterface(s, &GuildEmojisUpdate{
+		GuildID: "guild",
+		Emojis: []*Emoji{
+			{ID: "2", Name: "renamed"},
+			{ID: "3", Name: "party", Animated: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("OnInterface returned error: %+v", err)
+	}
+
+	if e, ok := s.State.EmojiByName("guild", "party"); !ok || e.ID != "3" || !e.Animated {
+		t.Errorf("EmojiByName(party) = %+v, %t, expected the animated emoji 3", e, ok)
+	}
+	if e, ok := s.State.EmojiByName("guild", "renamed"); !ok || e.ID != "2" {
+		t.Errorf("EmojiByName(renamed) = %+v, %t, expected emoji 2", e, ok)
+	}
+	for _, name := range []string{"old", "kept"} {
+		if e, ok := s.State.EmojiByName("guild", name); ok {
+			t.Errorf("EmojiByName(%s) = %+v, expected no emoji", name, e)
+		}
+	}
+
+	if err := s.State.EmojiAdd("guild", &Emoji{ID: "4", Name: "added"}); err != nil {
+		t.Fatalf("EmojiAdd returned error: %+v", err)
+	}
+	if e, ok := s.State.EmojiByName("guild", "added"); !ok || e.ID != "4" {
+		t.Errorf("EmojiByName(added) = %+v, %t, expected emoji 4", e, ok)
+	}
+
+	if _, ok := s.State.EmojiByName("unknown", "party"); ok {
+		t.Error("EmojiByName found an emoji in an unknown guild")
+	}
+
+	disableState(t, s)
+	if e, ok := s.State.EmojiByName("guild", "added"); ok {
+		t.Errorf("EmojiByName(added) = %+v with state disabled, expected no emoji", e)
+	}
+}
+
+// disableState disables state tracking for the session s, which the State
+// of s learns from the next Ready.
+func disableState(t *testing.T, s *Session) {
+	s.StateEnabled = false
+	if err := s.State.OnInterface(s, &Ready{SessionID: "session"}); err != nil {
+		t.Fatalf("OnInterface returned error: %+v", err)
+	}
+}
+
+// newTestHierarchyState returns a Session with a guild in its State, owned by
+// "owner", and with the members "admin", "mod", "mod2", "member" and "plain".
+func newTestHierarchyState() *Session {
+	s := &Session{StateEnabled: true, State: NewState()}
+
+	s.State.GuildAdd(&Guild{
+		ID:      "guild",
+		OwnerID: "owner",
+		Roles: []*Role{
+			{ID: "guild", Name: "@everyone", Position: 0},
+			{ID: "admin", Position: 3},
+			{ID: "mod", Position: 2},
+			{ID: "member", Position: 1},
+		},
+	})
+	for userID, roles := range map[string][]string{
+		"owner":  nil,
+		"admin":  {"member", "admin"},
+		"mod":    {"mod", "member"},
+		"mod2":   {"mod"},
+		"member": {"member"},
+		"plain":  nil,
+	} {
+		s.State.MemberAdd(&Member{GuildID: "guild", User: &User{ID: userID}, Roles: roles})
+	}
+
+	return s
+}
+
+func TestCanActOn(t *testing.T) {
+	s := newTestHierarchyState()
+
+	tests := []struct {
+		actor, target string
+		expected      bool
+	}{
+		{"owner", "admin", true},
+		{"plain", "owner", false},
+		{"admin", "owner", false},
+		{"admin", "mod", true},
+		{"mod", "admin", false},
+		{"mod", "mod2", false},
+		{"member", "plain", true},
+		{"plain", "member", false},
+	}
+
+	for _, tt := range tests {
+		can, err := s.CanActOn("guild", tt.actor, tt.target)
+		if err != nil {
+			t.Fatalf("CanActOn(%s, %s) returned error: %+v", tt.actor, tt.target, err)
+		}
+		if can != tt.expected {
+			t.Errorf("CanActOn(%s, %s) = %t, expected %t", tt.actor, tt.target, can, tt.expected)
+		}
+	}
+
+	if _, err := s.CanActOn("guild", "admin", "unknown"); err != ErrStateNotFound {
+		t.Errorf("CanActOn with an unknown target returned %v, expected ErrStateNotFound", err)
+	}
+}
+
+func TestStateMemberHighestRole(t *testing.T) {
+	s := newTestHierarchyState()
+
+	tests := map[string]string{
+		"admin":  "admin",
+		"mod":    "mod",
+		"member": "member",
+		"plain":  "guild",
+	}
+	for userID, expected := range tests {
+		role, err := s.State.MemberHighestRole("guild", userID)
+		if err != nil {
+			t.Fatalf("MemberHighestRole(%s) returned error: %+v", userID, err)
+		}
+		if role.ID != expected {
+			t.Errorf("MemberHighestRole(%s) = %s, expected %s", userID, role.ID, expected)
+		}
+	}
+
+	if _, err := s.State.MemberHighestRole("guild", "unknown"); err != ErrStateNotFound {
+		t.Errorf("MemberHighestRole of an unknown member returned %v, expected ErrStateNotFound", err)
+	}
+
+	disableState(t, s)
+	if _, err := s.State.MemberHighestRole("guild", "admin"); err != ErrStateDisabled {
+		t.Errorf("MemberHighestRole returned %v with state disabled, expected ErrStateDisabled", err)
+	}
+}
+
+func TestStateMemberColor(t *testing.T) {
+	s := &Session{StateEnabled: true, State: NewState()}
+
+	s.State.GuildAdd(&Guild{
+		ID: "guild",
+		Roles: []*Role{
+			{ID: "colorless", Position: 3},
+			{ID: "red", Position: 2, Color: 0xff0000},
+			{ID: "blue", Position: 1, Color: 0x0000ff},
+		},
+	})
+	s.State.MemberAdd(&Member{GuildID: "guild", User: &User{ID: "colored"}, Roles: []string{"blue", "colorless", "red"}})
+	s.State.MemberAdd(&Member{GuildID: "guild", User: &User{ID: "colorless"}, Roles: []string{"colorless"}})
+	s.State.ChannelAdd(&Channel{ID: "channel", GuildID: "guild"})
+
+	if color := s.State.MemberColor("guild", "colored"); color != 0xff0000 {
+		t.Errorf("MemberColor is %06x, expected the highest colored role ff0000", color)
+	}
+	if color := s.State.UserColor("colored", "channel"); color != 0xff0000 {
+		t.Errorf("UserColor is %06x, expected ff0000", color)
+	}
+	if color := s.State.MemberColor("guild", "colorless"); color != 0 {
+		t.Errorf("MemberColor of a member without colored roles is %06x, expected 0", color)
+	}
+	if color := s.State.MemberColor("guild", "unknown"); color != 0 {
+		t.Errorf("MemberColor of an unknown member is %06x, expected 0", color)
+	}
+
+	disableState(t, s)
+	if color := s.State.MemberColor("guild", "colored"); color != 0 {
+		t.Errorf("MemberColor is %06x with state disabled, expected 0", color)
+	}
+}
+
+func TestStateVoiceStates(t *testing.T) {
+	s := &Session{StateEnabled: true, State: NewState()}
+
+	s.State.OnInterface(s, &GuildCreate{&Guild{
+		ID: "guild",
+		VoiceStates: []*VoiceState{
+			{UserID: "a", ChannelID: "lounge"},
+		},
+	}})
+
+	for _, vs := range []*VoiceState{
+		{GuildID: "guild", UserID: "b", ChannelID: "lounge", SelfMute: true},
+		{GuildID: "guild", UserID: "c", ChannelID: "music"},
+		{GuildID: "guild", UserID: "c", ChannelID: "lounge", Deaf: true},
+		{GuildID: "guild", UserID: "a", ChannelID: ""},
+	} {
+		if err := s.State.OnInterface(s, &VoiceStateUpdate{vs}); err != nil {
+			t.Fatalf("OnInterface returned error: %+v", err)
+		}
+	}
+
+	if vs, ok := s.State.VoiceState("guild", "b"); !ok || vs.ChannelID != "lounge" || !vs.SelfMute {
+		t.Errorf("VoiceState(b) = %+v, %t, expected b muted in lounge", vs, ok)
+	}
+	if vs, ok := s.State.VoiceState("guild", "c"); !ok || vs.ChannelID != "lounge" || !vs.Deaf {
+		t.Errorf("VoiceState(c) = %+v, %t, expected c deafened in lounge", vs, ok)
+	}
+	if vs, ok := s.State.VoiceState("guild", "a"); ok {
+		t.Errorf("VoiceState(a) = %+v, expected a to have left", vs)
+	}
+	if _, ok := s.State.VoiceState("unknown", "b"); ok {
+		t.Error("VoiceState found a user in an unknown guild")
+	}
+
+	members := s.State.VoiceChannelMembers("guild", "lounge")
+	if len(members) != 2 || members[0] != "b" || members[1] != "c" {
+		t.Errorf("VoiceChannelMembers(lounge) = %v, expected [b c]", members)
+	}
+	if members = s.State.VoiceChannelMembers("guild", "music"); len(members) != 0 {
+		t.Errorf("VoiceChannelMembers(music) = %v, expected none", members)
+	}
+
+	disableState(t, s)
+	if members = s.State.VoiceChannelMembers("guild", "lounge"); members != nil {
+		t.Errorf("VoiceChannelMembers(lounge) = %v with state disabled, expected none", members)
+	}
+}
+
+func TestStateDisabled(t *testing.T) {
+	s, _ := New()
+	s.State = newTestHierarchyState().State
+	s.StateEnabled = false
+	s.SyncEvents = true
+
+	var created *GuildCreate
+	s.AddHandler(func(s *Session, g *GuildCreate) { created = g })
+
+	if _, err := s.onEvent(websocket.TextMessage, []byte(`{"op":0,"s":1,"t":"GUILD_CREATE","d":{"id":"other","name":"Other"}}`)); err != nil {
+		t.Fatalf("onEvent returned error: %+v", err)
+	}
+	if created == nil || created.ID != "other" {
+		t.Fatalf("GUILD_CREATE handler got %+v with state disabled", created)
+	}
+	if _, err := s.State.Guild("other"); err != ErrStateNotFound {
+		t.Errorf("guild was added to State with state disabled, error %v", err)
+	}
+
+	if _, err := s.CanActOn("guild", "owner", "admin"); err != ErrStateDisabled {
+		t.Errorf("CanActOn returned %v with state disabled, expected ErrStateDisabled", err)
+	}
+
+	s.State = nil
+	if _, err := s.CanActOn("guild", "owner", "admin"); err != ErrStateDisabled {
+		t.Errorf("CanActOn returned %v with a nil State, expected ErrStateDisabled", err)
+	}
+
+	s.StateEnabled = true
+	if _, err := s.CanActOn("guild", "owner", "admin"); err != ErrNilState {
+		t.Errorf("CanActOn returned %v with state enabled and a nil State, expected ErrNilState", err)
+	}
+}
+
+func TestStateRecentlyRemovedMember(t *testing.T) {
+	s, _ := New()
+	s.SyncEvents = true
+	s.State.MaxRemovedMemberCount = 2
+	s.State.GuildAdd(&Guild{
+		ID:          "guild",
+		MemberCount: 3,
+		Members: []*Member{
+			{GuildID: "guild", User: &User{ID: "a"}, Roles: []string{"mod"}, JoinedAt: "2020-01-01T00:00:00Z"},
+			{GuildID: "guild", User: &User{ID: "b"}},
+			{GuildID: "guild", User: &User{ID: "c"}},
+		},
+	})
+
+	var removed *Member
+	s.AddHandler(func(s *Session, m *GuildMemberRemove) {
+		removed, _ = s.State.RecentlyRemovedMember(m.GuildID, m.User.ID)
+	})
+
+	if _, err := s.onEvent(websocket.TextMessage, []byte(`{"op":0,"s":1,"t":"GUILD_MEMBER_REMOVE","d":{"guild_id":"guild","user":{"id":"a"}}}`)); err != nil {
+		t.Fatalf("onEvent returned error: %+v", err)
+	}
+	if removed == nil || len(removed.Roles) != 1 || removed.Roles[0] != "mod" || removed.JoinedAt != "2020-01-01T00:00:00Z" {
+		t.Fatalf("GuildMemberRemove handler got removed member %+v", removed)
+	}
+	if _, err := s.State.Member("guild", "a"); err != ErrStateNotFound {
+		t.Errorf("removed member is still in State, error %v", err)
+	}
+
+	// Only the last MaxRemovedMemberCount members are kept.
+	s.State.MemberRemove(&Member{GuildID: "guild", User: &User{ID: "b"}})
+	s.State.MemberRemove(&Member{GuildID: "guild", User: &User{ID: "c"}})
+	if _, ok := s.State.RecentlyRemovedMember("guild", "a"); ok {
+		t.Error("least recently removed member was kept")
+	}
+	for _, id := range []string{"b", "c"} {
+		if m, ok := s.State.RecentlyRemovedMember("guild", id); !ok || m.User.ID != id {
+			t.Errorf("RecentlyRemovedMember(%s) = %+v, %t", id, m, ok)
+		}
+	}
+	if _, ok := s.State.RecentlyRemovedMember("other", "b"); ok {
+		t.Error("removed member was found in another guild")
+	}
+
+	disableState(t, s)
+	if m, ok := s.State.RecentlyRemovedMember("guild", "b"); ok {
+		t.Errorf("RecentlyRemovedMember(b) = %+v with state disabled, expected no member", m)
+	}
+}
+
+func TestStateGuildDelete(t *testing.T) {
+	s, _ := New()
+	s.State.GuildAdd(&Guild{
+		ID:       "guild",
+		Name:     "Guild",
+		Channels: []*Channel{{ID: "channel", GuildID: "guild"}},
+		Members:  []*Member{{GuildID: "guild", User: &User{ID: "user"}}},
+	})
+	s.State.GuildAdd(&Guild{ID: "other", Channels: []*Channel{{ID: "other-channel", GuildID: "other"}}})
+
+	// An outage keeps the guild, marked as unavailable.
+	if err := s.State.OnInterface(s, decodeEvent(t, "GUILD_DELETE", `{"id":"guild","unavailable":true}`)); err != nil {
+		t.Fatalf("OnInterface returned error: %+v", err)
+	}
+	g, err := s.State.Guild("guild")
+	if err != nil {
+		t.Fatalf("unavailable guild was removed, %s", err)
+	}
+	if !g.Unavailable || g.Name != "Guild" {
+		t.Errorf("unavailable guild is %+v", g)
+	}
+	if _, err = s.State.Channel("channel"); err != nil {
+		t.Errorf("channel of the unavailable guild was removed, %s", err)
+	}
+	if _, err = s.State.Member("guild", "user"); err != nil {
+		t.Errorf("member of the unavailable guild was removed, %s", err)
+	}
+
+	// Leaving the guild removes it with its channels and members.
+	if err = s.State.OnInterface(s, decodeEvent(t, "GUILD_DELETE", `{"id":"guild"}`)); err != nil {
+		t.Fatalf("OnInterface returned error: %+v", err)
+	}
+	if _, err = s.State.Guild("guild"); err != ErrStateNotFound {
+		t.Errorf("left guild is still in State, error %v", err)
+	}
+	if _, err = s.State.Channel("channel"); err != ErrStateNotFound {
+		t.Errorf("channel of the left guild is still in State, error %v", err)
+	}
+	if _, err = s.State.Member("guild", "user"); err != ErrStateNotFound {
+		t.Errorf("member of the left guild is still in State, error %v", err)
+	}
+	if _, err = s.State.Channel("other-channel"); err != nil {
+		t.Errorf("channel of another guild was removed, %s", err)
+	}
+}
+
+func TestStateGuildMembersWithStatus(t *testing.T) {
+	state := NewState()
+	state.GuildAdd(&Guild{ID: "guild"})
+	for _, id := range []string{"online", "idle", "offline"} {
+		state.MemberAdd(&Member{GuildID: "guild", User: &User{ID: id}})
+	}
+	state.PresenceAdd("guild", &Presence{User: &User{ID: "online"}, Status: StatusOnline})
+	state.PresenceAdd("guild", &Presence{User: &User{ID: "idle"}, Status: StatusIdle})
+
+	members, err := state.GuildMembersWithStatus("guild")
+	if err != nil {
+		t.Fatalf("GuildMembersWithStatus returned error: %+v", err)
+	}
+	if len(members) != 3 {
+		t.Fatalf("GuildMembersWithStatus returned %d members, expected 3", len(members))
+	}
+
+	statuses := map[string]Status{}
+	for _, m := range members {
+		if m.Presence == nil {
+			statuses[m.User.ID] = ""
+			continue
+		}
+		statuses[m.User.ID] = m.Presence.Status
+	}
+	expected := map[string]Status{"online": StatusOnline, "idle": StatusIdle, "offline": ""}
+	for id, status := range expected {
+		if s, ok := statuses[id]; !ok || s != status {
+			t.Errorf("member %s has status %q, expected %q", id, s, status)
+		}
+	}
+
+	if _, err = state.GuildMembersWithStatus("unknown"); err != ErrStateNotFound {
+		t.Errorf("GuildMembersWithStatus returned %v for an unknown guild, expected ErrStateNotFound", err)
+	}
+
+	state.TrackPresences = false
+	if _, err = state.GuildMembersWithStatus("guild"); err != ErrPresencesNotTracked {
+		t.Errorf("GuildMembersWithStatus returned %v without presence tracking, expected ErrPresencesNotTracked", err)
+	}
+
+	disableState(t, &Session{State: state})
+	if _, err = state.GuildMembersWithStatus("guild"); err != ErrStateDisabled {
+		t.Errorf("GuildMembersWithStatus returned %v with state disabled, expected ErrStateDisabled", err)
+	}
+}
+
+func TestGuildStateMembers(t *testing.T) {
+	s, _ := New()
+	s.State.GuildAdd(&Guild{ID: "guild", Name: "cached"})
+	s.State.MemberAdd(&Member{GuildID: "guild", User: &User{ID: "user"}})
+	s.State.ChannelAdd(&Channel{ID: "channel", GuildID: "guild"})
+	s.State.RoleAdd("guild", &Role{ID: "role"})
+
+	// A guild received without its members, as in a GUILD_UPDATE.
+	g := &Guild{ID: "guild"}
+
+	if members := g.StateMembers(s); len(members) != 1 || members[0].User.ID != "user" {
+		t.Errorf("StateMembers returned %+v, expected the cached member", members)
+	}
+	if channels := g.StateChannels(s); len(channels) != 1 || channels[0].ID != "channel" {
+		t.Errorf("StateChannels returned %+v, expected the cached channel", channels)
+	}
+	if roles := g.StateRoles(s); len(roles) != 1 || roles[0].ID != "role" {
+		t.Errorf("StateRoles returned %+v, expected the cached role", roles)
+	}
+
+	unknown := &Guild{ID: "unknown", Members: []*Member{{User: &User{ID: "user"}}}}
+	if members := unknown.StateMembers(s); members != nil {
+		t.Errorf("StateMembers returned %+v for an unknown guild, expected nil", members)
+	}
+
+	s.StateEnabled = false
+	if roles := g.StateRoles(s); roles != nil {
+		t.Errorf("StateRoles returned %+v with State disabled, expected nil", roles)
+	}
+}
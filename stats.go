@@ -0,0 +1,71 @@
+// Discordgo - Discord bindings for Go
+// Available at https://github.com/bwmarrin/discordgo
+
+// Copyright 2015-2016 Bruce Marriner <bruce@sqls.net>.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains the counters of a Session, such as the gateway events
+// and REST requests it handled
+
+package discordgo
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SessionStats is a snapshot of the counters of a Session.
+type SessionStats struct {
+	// The number of gateway dispatches received, by event type.
+	Events map[string]int64
+
+	// The number of REST requests sent, by HTTP method. Retried requests
+	// are counted once for every attempt.
+	Requests map[string]int64
+
+	// The number of requests which were rate limited by Discord.
+	RateLimits int64
+
+	// The number of times the session reconnected to the gateway.
+	Reconnects int64
+}
+
+// sessionStats holds the counters of a Session. Counting is lock-free, so
+// it can be done on the hot paths of the gateway and REST API.
+type sessionStats struct {
+	events     sync.Map // string -> *int64
+	requests   sync.Map // string -> *int64
+	rateLimits int64
+	reconnects int64
+}
+
+// countKey increments the counter for key in m.
+func countKey(m *sync.Map, key string) {
+	n, ok := m.Load(key)
+	if !ok {
+		n, _ = m.LoadOrStore(key, new(int64))
+	}
+	atomic.AddInt64(n.(*int64), 1)
+}
+
+// snapshotKeys returns the current value of all counters in m.
+func snapshotKeys(m *sync.Map) map[string]int64 {
+	counts := map[string]int64{}
+	m.Range(func(key, n interface{}) bool {
+		counts[key.(string)] = atomic.LoadInt64(n.(*int64))
+		return true
+	})
+	return counts
+}
+
+// Stats returns a snapshot of the events, requests, rate limits and
+// reconnects counted by the session since it was created.
+func (s *Session) Stats() SessionStats {
+	return SessionStats{
+		Events:     snapshotKeys(&s.stats.events),
+		Requests:   snapshotKeys(&s.stats.requests),
+		RateLimits: atomic.LoadInt64(&s.stats.rateLimits),
+		Reconnects: atomic.LoadInt64(&s.stats.reconnects),
+	}
+}
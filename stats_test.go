@@ -0,0 +1,52 @@
+package discordgo
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestStats(t *testing.T) {
+	var limited int32
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		// Rate limit the first DELETE once.
+		if r.Method == "DELETE" && atomic.CompareAndSwapInt32(&limited, 0, 1) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"retry_after":1}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+
+	for _, payload := range []string{
+		`{"op":0,"s":1,"t":"MESSAGE_CREATE","d":{"id":"a"}}`,
+		`{"op":0,"s":2,"t":"MESSAGE_CREATE","d":{"id":"b"}}`,
+		`{"op":0,"s":3,"t":"TYPING_START","d":{}}`,
+		`{"op":11}`,
+	} {
+		if _, err := s.onEvent(websocket.TextMessage, []byte(payload)); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+	}
+
+	s.Channel("channel")
+	s.Channel("channel")
+	s.ChannelMessageDelete("channel", "message")
+
+	stats := s.Stats()
+	if len(stats.Events) != 2 || stats.Events["MESSAGE_CREATE"] != 2 || stats.Events["TYPING_START"] != 1 {
+		t.Errorf("Stats().Events = %v", stats.Events)
+	}
+	// The rate limited DELETE is counted once for each attempt.
+	if len(stats.Requests) != 2 || stats.Requests["GET"] != 2 || stats.Requests["DELETE"] != 2 {
+		t.Errorf("Stats().Requests = %v", stats.Requests)
+	}
+	if stats.RateLimits != 1 {
+		t.Errorf("Stats().RateLimits = %d, expected 1", stats.RateLimits)
+	}
+	if stats.Reconnects != 0 {
+		t.Errorf("Stats().Reconnects = %d, expected 0", stats.Reconnects)
+	}
+}
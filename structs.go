@@ -14,8 +14,10 @@ package discordgo
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -39,9 +41,49 @@ type Session struct {
 	// Should the session reconnect the websocket on errors.
 	ShouldReconnectOnError bool
 
+	// The delay before the first retry when reconnecting to the gateway
+	// fails, the longest delay between retries, and the factor the delay
+	// grows by after each failed retry. Defaults are used when unset:
+	// 1 second, 10 minutes and 2.
+	ReconnectInitialBackoff    time.Duration
+	ReconnectMaxBackoff        time.Duration
+	ReconnectBackoffMultiplier float64
+
+	// The fraction of each reconnect delay which is randomized, e.g. 0.2
+	// waits between 80% and 120% of the delay, so that many sessions
+	// dropped at once don't all retry at the same time.
+	ReconnectJitter float64
+
 	// Should the session request compressed websocket data.
 	Compress bool
 
+	// Should the session request zlib-stream transport compression.
+	// The whole gateway connection then shares a single zlib context,
+	// and per-payload compression (Compress) is not requested.
+	TransportCompress bool
+
+	// The User-Agent sent with REST requests and when connecting to the
+	// gateway. DefaultUserAgent is used if empty.
+	UserAgent string
+
+	// The dialer used to connect to the gateway and voice websockets, e.g.
+	// with a Proxy set. websocket.DefaultDialer is used if nil, which uses
+	// the proxy from the environment. REST requests use Client.
+	Dialer *websocket.Dialer
+
+	// The max time Open waits for the gateway websocket to be connected,
+	// and then for Discord to complete the handshake with Hello and READY
+	// or RESUMED. ErrGatewayTimeout is returned when either is exceeded.
+	// There is no limit if zero. Both are set by New.
+	DialTimeout      time.Duration
+	HandshakeTimeout time.Duration
+
+	// The interval of the heartbeats sent to the gateway, replacing the
+	// interval Discord sends in Hello if set, e.g. for tests. Discord may
+	// rate limit or disconnect sessions sending heartbeats more often than
+	// it asks, and disconnects those sending them less often.
+	HeartbeatInterval time.Duration
+
 	// Sharding
 	ShardID    int
 	ShardCount int
@@ -55,6 +97,38 @@ type Session struct {
 	// e.g false = launch event handlers in their own goroutines.
 	SyncEvents bool
 
+	// The number of workers handling gateway events. If set, events are
+	// decoded by the gateway read loop and queued for the workers, which
+	// call the handlers of each event one after the other, so a slow
+	// handler does not hold up heartbeats. Events are applied to State by
+	// the read loop, in the order they are received, before they are
+	// queued, so e.g. the session is set up by the time Open returns. With
+	// more than one worker, the handlers of different events are called
+	// concurrently and may be called out of order, see OrderedEvents. Must
+	// be set before the session is opened.
+	EventHandlerWorkers int
+
+	// The number of events which can wait for a free worker before the
+	// gateway read loop blocks. Only used with EventHandlerWorkers.
+	EventQueueSize int
+
+	// Should events be kept in order per guild when handled by
+	// EventHandlerWorkers. Each guild is assigned to a worker, so the events
+	// of a guild, including those of its channels, are handled one at a time
+	// in the order they were received, while other guilds are handled in
+	// parallel. Channels outside of guilds, such as DMs, are kept in order
+	// in the same way, and events with neither are all handled by one
+	// worker. EventQueueSize is the size of the queue of each worker.
+	OrderedEvents bool
+
+	// Should the Ready handlers return before the following events are
+	// handled. If set, the Ready handlers are called synchronously by the
+	// gateway read loop, even without SyncEvents or with EventHandlerWorkers,
+	// so e.g. the GuildCreate handlers do not run before the setup done by a
+	// Ready handler. The Ready handlers should then return quickly, as no
+	// events are read, and heartbeat ACKs not seen, until they do.
+	SerializeReady bool
+
 	// Exposed but should not be modified by User.
 
 	// Whether the Data Websocket is ready
@@ -63,6 +137,47 @@ type Session struct {
 	// Max number of REST API retries
 	MaxRestRetries int
 
+	// Should GET responses carrying an ETag be cached. Cached URLs are
+	// requested with If-None-Match, and on a 304 Not Modified the cached
+	// body is returned instead.
+	CacheETags bool
+
+	// MaxETagCacheCount represents how many URLs the ETag cache keeps
+	// responses for. The least recently requested URLs are forgotten first.
+	// The default of 1000 is used if it is not positive.
+	MaxETagCacheCount int
+
+	// Should all members of every guild be requested after READY, so that
+	// State holds the members of large guilds too. The requests are spread
+	// out to stay under the gateway's rate limit.
+	ChunkGuildsOnStartup bool
+
+	// The IDs of the guilds the session may be in. If set, any guild
+	// received in a GUILD_CREATE which is not on the list is left right
+	// away. The GUILD_CREATE event is still dispatched.
+	GuildAllowlist []string
+
+	// The number of message IDs remembered by SeenMessage, 1000 if unset.
+	SeenMessageWindow int
+
+	// Should messages and embeds be validated against Discord's length
+	// limits before they are sent, so that mistakes are caught without
+	// a request being made.
+	ValidatePayloads bool
+
+	// The mentions allowed in the messages sent with
+	// ChannelMessageSendComplex, WebhookExecute, WebhookExecuteAs and
+	// InteractionRespond which do not set their own AllowedMentions,
+	// e.g. to never ping @everyone. All mentions are allowed if nil.
+	DefaultAllowedMentions *MessageAllowedMentions
+
+	// Should the message of MessageReactionAdd and MessageReactionRemove
+	// events be set before they are dispatched. The message is read from
+	// State, or fetched before the handlers are called, which delays the
+	// next events only if SyncEvents is set. Handlers get a copy of the
+	// message cached in State.
+	HydrateReactions bool
+
 	// Status stores the currect status of the websocket connection
 	// this is being tested, may stay, may go away.
 	status int32
@@ -92,6 +207,12 @@ type Session struct {
 	// used to deal with rate limits
 	Ratelimiter *RateLimiter
 
+	// stores ETags and bodies of GET responses when CacheETags is true
+	etags *etagCache
+
+	// stores the last known invites of guilds, used by GuildInviteUsed
+	invites inviteTracker
+
 	// Event handlers
 	handlersMu   sync.RWMutex
 	handlers     map[string][]*eventHandlerInstance
@@ -114,6 +235,22 @@ type Session struct {
 
 	// used to make sure gateway websocket writes do not happen concurrently
 	wsMutex sync.Mutex
+
+	// counts running event handlers and REST requests for Shutdown, and
+	// stops dispatching events while Shutdown is running
+	active activeCounter
+
+	// counters reported by Stats
+	stats sessionStats
+
+	// gateway events waiting for an event handler worker
+	queue eventQueue
+
+	// message IDs passed to SeenMessage
+	seen seenMessages
+
+	// limits gateway commands sent by the session
+	commands gatewayLimiter
 }
 
 // UserConnection is a Connection returned from the UserConnections endpoint
@@ -133,11 +270,33 @@ type Integration struct {
 	Enabled           bool               `json:"enabled"`
 	Syncing           bool               `json:"syncing"`
 	RoleID            string             `json:"role_id"`
+	EnableEmoticons   bool               `json:"enable_emoticons"`
 	ExpireBehavior    int                `json:"expire_behavior"`
 	ExpireGracePeriod int                `json:"expire_grace_period"`
 	User              *User              `json:"user"`
 	Account           IntegrationAccount `json:"account"`
 	SyncedAt          Timestamp          `json:"synced_at"`
+	SubscriberCount   int                `json:"subscriber_count"`
+	Revoked           bool               `json:"revoked"`
+
+	// The bot application of the integration, for discord integrations.
+	Application *IntegrationApplication `json:"application"`
+}
+
+// Block contains the valid known Integration.ExpireBehavior values,
+// deciding what happens to a subscriber whose subscription expires.
+const (
+	IntegrationExpireBehaviorRemoveRole = 0
+	IntegrationExpireBehaviorKick       = 1
+)
+
+// IntegrationApplication is the bot application of an Integration
+type IntegrationApplication struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Icon        string `json:"icon"`
+	Description string `json:"description"`
+	Bot         *User  `json:"bot"`
 }
 
 // IntegrationAccount is integration account information
@@ -153,6 +312,15 @@ type VoiceRegion struct {
 	Name     string `json:"name"`
 	Hostname string `json:"sample_hostname"`
 	Port     int    `json:"sample_port"`
+
+	// Whether the region is the closest to the current user's client.
+	Optimal bool `json:"optimal"`
+
+	// Whether the region should no longer be used.
+	Deprecated bool `json:"deprecated"`
+
+	// Whether the region is a custom one, e.g. for events.
+	Custom bool `json:"custom"`
 }
 
 // A VoiceICE stores data for voice ICE servers.
@@ -187,6 +355,23 @@ type Invite struct {
 	ApproximateMemberCount   int `json:"approximate_member_count"`
 }
 
+// InviteParams holds the settings of a new invite, used with
+// Channel.InviteCreate.
+type InviteParams struct {
+	// How long the invite lasts in seconds, 0 for never. Discord defaults
+	// to 24 hours.
+	MaxAge int `json:"max_age"`
+
+	// How many times the invite can be used, 0 for unlimited.
+	MaxUses int `json:"max_uses"`
+
+	// Whether the invite only grants temporary membership.
+	Temporary bool `json:"temporary"`
+
+	// Whether a new invite is always created, instead of reusing a similar one.
+	Unique bool `json:"unique"`
+}
+
 // ChannelType is the type of a Channel
 type ChannelType int
 
@@ -197,6 +382,13 @@ const (
 	ChannelTypeGuildVoice
 	ChannelTypeGroupDM
 	ChannelTypeGuildCategory
+	ChannelTypeGuildNews
+	ChannelTypeGuildStore
+	ChannelTypeGuildNewsThread    ChannelType = 10
+	ChannelTypeGuildPublicThread  ChannelType = 11
+	ChannelTypeGuildPrivateThread ChannelType = 12
+	ChannelTypeGuildStageVoice    ChannelType = 13
+	ChannelTypeGuildForum         ChannelType = 15
 )
 
 // ErrNotATextChannel gets returned when an action gets called on a channel
@@ -254,13 +446,85 @@ type Channel struct {
 	// The user limit of the voice channel.
 	UserLimit int `json:"user_limit"`
 
+	// The ID of the voice region of the voice channel, empty if it is
+	// chosen automatically.
+	RTCRegion string `json:"rtc_region"`
+
 	// The ID of the parent channel, if the channel is under a category
 	ParentID string `json:"parent_id"`
+
+	// The IDs of the tags applied to the thread, if it is in a forum channel.
+	AppliedTags []string `json:"applied_tags,omitempty"`
+
+	// The ID of the creator of the thread.
+	OwnerID string `json:"owner_id,omitempty"`
+
+	// Approximate counts of the messages and members of the thread,
+	// both stop counting at 50.
+	MessageCount int `json:"message_count,omitempty"`
+	MemberCount  int `json:"member_count,omitempty"`
+
+	// Thread specific fields, only set for thread channels.
+	ThreadMetadata *ThreadMetadata `json:"thread_metadata,omitempty"`
+
+	// The thread member of the current user, if they have joined the thread.
+	Member *ThreadMember `json:"member,omitempty"`
+}
+
+// ThreadMetadata stores the thread specific fields of a thread channel.
+type ThreadMetadata struct {
+	// Whether the thread is archived.
+	Archived bool `json:"archived"`
+
+	// The number of minutes of inactivity after which the thread is archived.
+	AutoArchiveDuration int `json:"auto_archive_duration"`
+
+	// The time at which the thread was last archived or unarchived.
+	ArchiveTimestamp Timestamp `json:"archive_timestamp"`
+
+	// Whether the thread is locked, only members with the manage threads
+	// permission can unarchive locked threads.
+	Locked bool `json:"locked"`
+
+	// Whether non-moderators can add other non-moderators to a private thread.
+	Invitable bool `json:"invitable"`
+}
+
+// A ThreadMember stores data of a user which joined a thread.
+type ThreadMember struct {
+	// The ID of the thread.
+	ID string `json:"id,omitempty"`
+
+	// The ID of the user.
+	UserID string `json:"user_id,omitempty"`
+
+	// The time at which the user last joined the thread.
+	JoinTimestamp Timestamp `json:"join_timestamp"`
+
+	// Any user-thread settings, currently only used for notifications.
+	Flags int `json:"flags"`
+}
+
+// AddedThreadMember holds the data of a user who was added to a thread.
+type AddedThreadMember struct {
+	*ThreadMember
+	Member   *Member   `json:"member"`
+	Presence *Presence `json:"presence"`
+}
+
+// ThreadsList stores a list of threads and the thread members of the
+// current user for them.
+type ThreadsList struct {
+	Threads []*Channel      `json:"threads"`
+	Members []*ThreadMember `json:"members"`
+
+	// Whether there are more threads to fetch, only set for archived threads.
+	HasMore bool `json:"has_more"`
 }
 
 // Mention returns a string which mentions the channel
 func (c *Channel) Mention() string {
-	return fmt.Sprintf("<#%s>", c.ID)
+	return ChannelMention(c.ID)
 }
 
 // SendMessage sends a message to the channel
@@ -293,6 +557,71 @@ func (c *Channel) SendMessageComplex(s *Session, data *MessageSend) (message *Me
 	return s.ChannelMessageSendComplex(c.ID, data)
 }
 
+// SendEmbeds sends a message with up to 10 embeds to the channel. The embeds
+// are validated against Discord's limits before anything is sent.
+// embeds        : The embeds to send
+func (c *Channel) SendEmbeds(s *Session, embeds []*MessageEmbed) (message *Message, err error) {
+	data := &MessageSend{Embeds: embeds}
+	if err = data.Validate(); err != nil {
+		return
+	}
+
+	return c.SendMessageComplex(s, data)
+}
+
+// Delete deletes the channel, returning the deleted channel
+// reason        : The reason shown in the audit log, or empty for none
+func (c *Channel) Delete(s *Session, reason string) (st *Channel, err error) {
+	return s.ChannelDeleteWithReason(c.ID, reason)
+}
+
+// WebhookCreate creates a new webhook in the channel
+// name          : The name of the webhook
+// avatarDataURI : The avatar of the webhook as a data URI, or empty for none
+func (c *Channel) WebhookCreate(s *Session, name, avatarDataURI string) (st *Webhook, err error) {
+	return s.WebhookCreate(c.ID, name, avatarDataURI)
+}
+
+// Webhooks returns the webhooks of the channel
+func (c *Channel) Webhooks(s *Session) (st []*Webhook, err error) {
+	return s.ChannelWebhooks(c.ID)
+}
+
+// InviteCreate creates a new invite for the channel
+// params        : The settings of the invite, Discord's defaults are used if nil
+func (c *Channel) InviteCreate(s *Session, params *InviteParams) (st *Invite, err error) {
+	if params == nil {
+		params = &InviteParams{MaxAge: 86400}
+	}
+
+	return s.ChannelInviteCreate(c.ID, Invite{
+		MaxAge:    params.MaxAge,
+		MaxUses:   params.MaxUses,
+		Temporary: params.Temporary,
+		Unique:    params.Unique,
+	})
+}
+
+// ForumThreadStart holds the data of a new forum post, used with
+// ForumThreadStart.
+type ForumThreadStart struct {
+	// The name of the thread.
+	Name string `json:"name"`
+
+	// The number of minutes of inactivity after which the thread is
+	// archived: 60, 1440, 4320 or 10080.
+	AutoArchiveDuration int `json:"auto_archive_duration,omitempty"`
+
+	// The slowmode of the thread, in seconds.
+	RateLimitPerUser int `json:"rate_limit_per_user,omitempty"`
+
+	// The IDs of the forum tags applied to the thread.
+	AppliedTags []string `json:"applied_tags,omitempty"`
+
+	// The starter message of the thread.
+	Message *MessageSend `json:"message"`
+}
+
 // A ChannelEdit holds Channel Field data for a channel edit.
 type ChannelEdit struct {
 	Name                 string                 `json:"name,omitempty"`
@@ -304,6 +633,22 @@ type ChannelEdit struct {
 	PermissionOverwrites []*PermissionOverwrite `json:"permission_overwrites,omitempty"`
 	ParentID             string                 `json:"parent_id,omitempty"`
 	RateLimitPerUser     int                    `json:"rate_limit_per_user,omitempty"`
+
+	// The ID of the voice region of a voice channel, such as the ID of a
+	// VoiceRegion returned by VoiceRegions, or an empty string to choose the
+	// region automatically. Left unchanged if nil.
+	RTCRegion *string `json:"rtc_region,omitempty"`
+}
+
+// MarshalJSON marshals a ChannelEdit, sending an empty RTCRegion as null,
+// which Discord requires to choose the region automatically
+func (e ChannelEdit) MarshalJSON() ([]byte, error) {
+	type channelEdit ChannelEdit
+
+	return json.Marshal(struct {
+		channelEdit
+		RTCRegion json.RawMessage `json:"rtc_region,omitempty"`
+	}{channelEdit(e), nullableString(e.RTCRegion)})
 }
 
 // A PermissionOverwrite holds permission overwrite data for a Channel
@@ -314,6 +659,49 @@ type PermissionOverwrite struct {
 	Allow int    `json:"allow"`
 }
 
+// MarshalJSON marshals a PermissionOverwrite with its permissions as strings,
+// as expected by Discord
+func (p PermissionOverwrite) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ID    string `json:"id"`
+		Type  string `json:"type"`
+		Deny  string `json:"deny"`
+		Allow string `json:"allow"`
+	}{p.ID, p.Type, strconv.Itoa(p.Deny), strconv.Itoa(p.Allow)})
+}
+
+// UnmarshalJSON unmarshals JSON into a PermissionOverwrite, accepting its
+// permissions either as numbers or as strings
+func (p *PermissionOverwrite) UnmarshalJSON(b []byte) error {
+	temp := struct {
+		ID    string          `json:"id"`
+		Type  string          `json:"type"`
+		Deny  json.RawMessage `json:"deny"`
+		Allow json.RawMessage `json:"allow"`
+	}{}
+	err := json.Unmarshal(b, &temp)
+	if err != nil {
+		return err
+	}
+
+	p.ID = temp.ID
+	p.Type = temp.Type
+	if p.Deny, err = permissionsFromJSON(temp.Deny); err != nil {
+		return err
+	}
+	p.Allow, err = permissionsFromJSON(temp.Allow)
+	return err
+}
+
+// permissionsFromJSON parses a permission bit set sent as a number or a string.
+func permissionsFromJSON(b json.RawMessage) (int, error) {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
 // Emoji struct holds data related to Emoji's
 type Emoji struct {
 	ID            string   `json:"id"`
@@ -419,6 +807,12 @@ type Guild struct {
 	// update events, and thus is only present in state-cached guilds.
 	MemberCount int `json:"member_count"`
 
+	// The approximate number of members and of online members in the
+	// guild. These fields are only present in guilds fetched with
+	// Session.GuildWithCounts.
+	ApproximateMemberCount   int `json:"approximate_member_count"`
+	ApproximatePresenceCount int `json:"approximate_presence_count"`
+
 	// The verification level required for the guild.
 	VerificationLevel VerificationLevel `json:"verification_level"`
 
@@ -482,6 +876,86 @@ type Guild struct {
 
 	// The Channel ID to which system messages are sent (eg join and leave messages)
 	SystemChannelID string `json:"system_channel_id"`
+
+	// The hash of the guild's banner.
+	Banner string `json:"banner"`
+
+	// The hash of the guild's discovery splash.
+	DiscoverySplash string `json:"discovery_splash"`
+}
+
+// withSize adds the size parameter to an image URL, if size is not empty.
+func withSize(URL, size string) string {
+	if size != "" {
+		return URL + "?size=" + size
+	}
+	return URL
+}
+
+// IconURL returns a URL to the guild's icon, animated if the icon is.
+// size      : The size of the icon as a power of two, or empty for no size.
+// An empty string is returned if the guild has no icon.
+func (g *Guild) IconURL(size string) string {
+	if !g.HasIcon() {
+		return ""
+	}
+
+	if strings.HasPrefix(g.Icon, "a_") {
+		return withSize(EndpointGuildIconAnimated(g.ID, g.Icon), size)
+	}
+	return withSize(EndpointGuildIcon(g.ID, g.Icon), size)
+}
+
+// IconURLOrDefault returns a URL to the guild's icon, or placeholder if the
+// guild has no icon.
+// size        : The size of the icon as a power of two, or empty for no size.
+// placeholder : The URL returned if the guild has no icon.
+func (g *Guild) IconURLOrDefault(size, placeholder string) string {
+	if !g.HasIcon() {
+		return placeholder
+	}
+	return g.IconURL(size)
+}
+
+// HasIcon returns whether the guild has an icon.
+func (g *Guild) HasIcon() bool {
+	return g.Icon != ""
+}
+
+// BannerURL returns a URL to the guild's banner, animated if the banner is.
+// size      : The size of the banner as a power of two, or empty for no size.
+// An empty string is returned if the guild has no banner.
+func (g *Guild) BannerURL(size string) string {
+	if g.Banner == "" {
+		return ""
+	}
+
+	if strings.HasPrefix(g.Banner, "a_") {
+		return withSize(EndpointGuildBannerAnimated(g.ID, g.Banner), size)
+	}
+	return withSize(EndpointGuildBanner(g.ID, g.Banner), size)
+}
+
+// SplashURL returns a URL to the guild's invite splash.
+// size      : The size of the splash as a power of two, or empty for no size.
+// An empty string is returned if the guild has no splash.
+func (g *Guild) SplashURL(size string) string {
+	if g.Splash == "" {
+		return ""
+	}
+
+	return withSize(EndpointGuildSplash(g.ID, g.Splash), size)
+}
+
+// DiscoverySplashURL returns a URL to the guild's discovery splash.
+// size      : The size of the splash as a power of two, or empty for no size.
+// An empty string is returned if the guild has no discovery splash.
+func (g *Guild) DiscoverySplashURL(size string) string {
+	if g.DiscoverySplash == "" {
+		return ""
+	}
+
+	return withSize(EndpointGuildDiscoverySplash(g.ID, g.DiscoverySplash), size)
 }
 
 // A UserGuild holds a brief version of a Guild
@@ -534,11 +1008,73 @@ type Role struct {
 	// This is a combination of bit masks; the presence of a certain permission can
 	// be checked by performing a bitwise AND between this int and the permission.
 	Permissions int `json:"permissions"`
+
+	// The hash of the icon of the role, and the unicode emoji shown
+	// instead of an icon, if the role has either.
+	Icon         string `json:"icon"`
+	UnicodeEmoji string `json:"unicode_emoji"`
+}
+
+// IconURL returns a URL to the role's icon.
+// size      : The size of the icon as a power of two, or empty for no size.
+// An empty string is returned if the role has no icon.
+func (r *Role) IconURL(size string) string {
+	if r.Icon == "" {
+		return ""
+	}
+
+	return withSize(EndpointRoleIcon(r.ID, r.Icon), size)
+}
+
+// RoleParams holds the settings of a role created with GuildRoleCreateComplex
+// or edited with GuildRoleEditComplex. Nil fields are left unchanged.
+type RoleParams struct {
+	Name        string `json:"name,omitempty"`
+	Color       *int   `json:"color,omitempty"`
+	Hoist       *bool  `json:"hoist,omitempty"`
+	Permissions *int   `json:"permissions,omitempty"`
+	Mentionable *bool  `json:"mentionable,omitempty"`
+
+	// The icon of the role as a data URI, e.g. "data:image/png;base64,...",
+	// or an empty string to remove it, which is sent as null. Only guilds
+	// with the ROLE_ICONS feature can set icons.
+	Icon *string `json:"icon,omitempty"`
+
+	// The unicode emoji shown as the icon of the role, or an empty string to
+	// remove it, which is sent as null.
+	UnicodeEmoji *string `json:"unicode_emoji,omitempty"`
+}
+
+// MarshalJSON marshals RoleParams, sending an empty Icon or UnicodeEmoji as
+// null, which Discord requires to remove them
+func (p RoleParams) MarshalJSON() ([]byte, error) {
+	type roleParams RoleParams
+
+	return json.Marshal(struct {
+		roleParams
+		Icon         json.RawMessage `json:"icon,omitempty"`
+		UnicodeEmoji json.RawMessage `json:"unicode_emoji,omitempty"`
+	}{roleParams(p), nullableString(p.Icon), nullableString(p.UnicodeEmoji)})
+}
+
+// nullableString returns the JSON encoding of an optional string field of a
+// request: nothing if s is nil, so that the field is omitted, and null if s
+// is empty.
+func nullableString(s *string) json.RawMessage {
+	if s == nil {
+		return nil
+	}
+	if *s == "" {
+		return json.RawMessage("null")
+	}
+
+	b, _ := json.Marshal(*s)
+	return b
 }
 
 // Mention returns a string which mentions the role
 func (r *Role) Mention() string {
-	return fmt.Sprintf("<@&%s>", r.ID)
+	return RoleMention(r.ID)
 }
 
 // Roles are a collection of Role
@@ -656,6 +1192,9 @@ type Member struct {
 
 	// A list of IDs of the roles which are possessed by the member.
 	Roles []string `json:"roles"`
+
+	// The hash of the member's guild specific avatar, if they have one.
+	Avatar string `json:"avatar"`
 }
 
 // Mention creates a member mention
@@ -663,6 +1202,32 @@ func (m *Member) Mention() string {
 	return "<@!" + m.User.ID + ">"
 }
 
+// DisplayName returns the name the member is shown with in the guild: the
+// nickname if they have one, else the user's global name, else the username.
+func (m *Member) DisplayName() string {
+	if m.Nick != "" {
+		return m.Nick
+	}
+	if m.User.GlobalName != "" {
+		return m.User.GlobalName
+	}
+	return m.User.Username
+}
+
+// AvatarURL returns a URL to the member's guild specific avatar, or to the
+// user's avatar if the member has none.
+// size      : The size of the avatar as a power of two, or empty for no size.
+func (m *Member) AvatarURL(size string) string {
+	if m.Avatar == "" {
+		return m.User.AvatarURL(size)
+	}
+
+	if strings.HasPrefix(m.Avatar, "a_") {
+		return withSize(EndpointGuildMemberAvatarAnimated(m.GuildID, m.User.ID, m.Avatar), size)
+	}
+	return withSize(EndpointGuildMemberAvatar(m.GuildID, m.User.ID, m.Avatar), size)
+}
+
 // A Settings stores data for a specific users Discord client settings.
 type Settings struct {
 	RenderEmbeds           bool               `json:"render_embeds"`
@@ -865,6 +1430,42 @@ type Webhook struct {
 	Token     string `json:"token"`
 }
 
+// webhookHosts are the hosts of Discord webhook URLs.
+var webhookHosts = map[string]bool{
+	"discord.com":           true,
+	"canary.discord.com":    true,
+	"ptb.discord.com":       true,
+	"discordapp.com":        true,
+	"canary.discordapp.com": true,
+	"ptb.discordapp.com":    true,
+}
+
+// WebhookFromURL parses a webhook URL, such as
+// https://discord.com/api/webhooks/{id}/{token}, into the ID and token of
+// the webhook. The API version may be part of the path, and anything after
+// the token, like /github or a query, is ignored.
+// ErrInvalidWebhookURL is returned if rawURL is not a webhook URL.
+func WebhookFromURL(rawURL string) (id, token string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || !webhookHosts[u.Hostname()] {
+		return "", "", ErrInvalidWebhookURL
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) > 1 && parts[0] == "api" && strings.HasPrefix(parts[1], "v") {
+		// Drop the API version.
+		parts = append(parts[:1], parts[2:]...)
+	}
+	if len(parts) < 4 || parts[0] != "api" || parts[1] != "webhooks" || parts[3] == "" {
+		return "", "", ErrInvalidWebhookURL
+	}
+	if _, err := strconv.ParseUint(parts[2], 10, 64); err != nil {
+		return "", "", ErrInvalidWebhookURL
+	}
+
+	return parts[2], parts[3], nil
+}
+
 // WebhookParams is a struct for webhook params, used in the WebhookExecute command.
 type WebhookParams struct {
 	Content   string          `json:"content,omitempty"`
@@ -873,6 +1474,10 @@ type WebhookParams struct {
 	TTS       bool            `json:"tts,omitempty"`
 	File      string          `json:"file,omitempty"`
 	Embeds    []*MessageEmbed `json:"embeds,omitempty"`
+
+	// The mentions allowed in the message, Session.DefaultAllowedMentions
+	// if nil.
+	AllowedMentions *MessageAllowedMentions `json:"allowed_mentions,omitempty"`
 }
 
 // MessageReaction stores the data for a message reaction.
@@ -890,6 +1495,177 @@ type GatewayBotResponse struct {
 	Shards int    `json:"shards"`
 }
 
+// AutoModerationRuleEventType is the type of event which triggers an
+// AutoModerationRule.
+type AutoModerationRuleEventType int
+
+// Block contains known AutoModerationRuleEventType values
+const (
+	AutoModerationEventMessageSend AutoModerationRuleEventType = 1
+)
+
+// AutoModerationRuleTriggerType is the type of content which triggers an
+// AutoModerationRule.
+type AutoModerationRuleTriggerType int
+
+// Block contains known AutoModerationRuleTriggerType values
+const (
+	AutoModerationEventTriggerKeyword       AutoModerationRuleTriggerType = 1
+	AutoModerationEventTriggerSpam          AutoModerationRuleTriggerType = 3
+	AutoModerationEventTriggerKeywordPreset AutoModerationRuleTriggerType = 4
+	AutoModerationEventTriggerMentionSpam   AutoModerationRuleTriggerType = 5
+)
+
+// AutoModerationKeywordPreset is a list of words predefined by Discord.
+type AutoModerationKeywordPreset int
+
+// Block contains known AutoModerationKeywordPreset values
+const (
+	AutoModerationKeywordPresetProfanity     AutoModerationKeywordPreset = 1
+	AutoModerationKeywordPresetSexualContent AutoModerationKeywordPreset = 2
+	AutoModerationKeywordPresetSlurs         AutoModerationKeywordPreset = 3
+)
+
+// AutoModerationTriggerMetadata holds additional data used to determine
+// whether an AutoModerationRule is triggered. Which fields apply depends on
+// the trigger type of the rule.
+type AutoModerationTriggerMetadata struct {
+	// Substrings which trigger a keyword rule.
+	KeywordFilter []string `json:"keyword_filter,omitempty"`
+
+	// Regular expressions which trigger a keyword rule.
+	RegexPatterns []string `json:"regex_patterns,omitempty"`
+
+	// Word lists which trigger a keyword preset rule.
+	Presets []AutoModerationKeywordPreset `json:"presets,omitempty"`
+
+	// Substrings which never trigger the rule.
+	AllowList *[]string `json:"allow_list,omitempty"`
+
+	// The number of unique role and user mentions allowed per message,
+	// for mention spam rules.
+	MentionTotalLimit int `json:"mention_total_limit,omitempty"`
+}
+
+// AutoModerationActionType is the type of an AutoModerationAction.
+type AutoModerationActionType int
+
+// Block contains known AutoModerationActionType values
+const (
+	AutoModerationRuleActionBlockMessage     AutoModerationActionType = 1
+	AutoModerationRuleActionSendAlertMessage AutoModerationActionType = 2
+	AutoModerationRuleActionTimeout          AutoModerationActionType = 3
+)
+
+// AutoModerationActionMetadata holds additional data used when an
+// AutoModerationAction is executed.
+type AutoModerationActionMetadata struct {
+	// The channel to which the alert of a send alert action is sent.
+	ChannelID string `json:"channel_id,omitempty"`
+
+	// The length of a timeout action, in seconds (max 2419200).
+	Duration int `json:"duration_seconds,omitempty"`
+
+	// The message shown to the member when a block action blocks a message.
+	CustomMessage string `json:"custom_message,omitempty"`
+}
+
+// AutoModerationAction is an action taken when an AutoModerationRule is
+// triggered.
+type AutoModerationAction struct {
+	Type     AutoModerationActionType      `json:"type"`
+	Metadata *AutoModerationActionMetadata `json:"metadata,omitempty"`
+}
+
+// AutoModerationRule stores the data for an automod rule. It is also used
+// with AutoModerationRuleCreate and AutoModerationRuleEdit, where unset
+// fields are left out.
+type AutoModerationRule struct {
+	ID              string                         `json:"id,omitempty"`
+	GuildID         string                         `json:"guild_id,omitempty"`
+	Name            string                         `json:"name,omitempty"`
+	CreatorID       string                         `json:"creator_id,omitempty"`
+	EventType       AutoModerationRuleEventType    `json:"event_type,omitempty"`
+	TriggerType     AutoModerationRuleTriggerType  `json:"trigger_type,omitempty"`
+	TriggerMetadata *AutoModerationTriggerMetadata `json:"trigger_metadata,omitempty"`
+	Actions         []AutoModerationAction         `json:"actions,omitempty"`
+	Enabled         *bool                          `json:"enabled,omitempty"`
+
+	// The IDs of the roles and channels the rule does not apply to.
+	ExemptRoles    *[]string `json:"exempt_roles,omitempty"`
+	ExemptChannels *[]string `json:"exempt_channels,omitempty"`
+}
+
+// GuildOnboardingMode defines the criteria used to satisfy onboarding
+// constraints required to join a guild.
+type GuildOnboardingMode int
+
+// Block contains known GuildOnboardingMode values
+const (
+	// GuildOnboardingModeDefault counts only default channels towards
+	// the constraints.
+	GuildOnboardingModeDefault GuildOnboardingMode = 0
+	// GuildOnboardingModeAdvanced counts default channels and questions
+	// towards the constraints.
+	GuildOnboardingModeAdvanced GuildOnboardingMode = 1
+)
+
+// GuildOnboardingPromptType is the type of a GuildOnboardingPrompt.
+type GuildOnboardingPromptType int
+
+// Block contains known GuildOnboardingPromptType values
+const (
+	GuildOnboardingPromptTypeMultipleChoice GuildOnboardingPromptType = 0
+	GuildOnboardingPromptTypeDropdown       GuildOnboardingPromptType = 1
+)
+
+// GuildOnboardingPromptOption is an answer to a GuildOnboardingPrompt.
+type GuildOnboardingPromptOption struct {
+	ID          string `json:"id,omitempty"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+
+	// The channels and roles a member is given when picking the option.
+	ChannelIDs []string `json:"channel_ids"`
+	RoleIDs    []string `json:"role_ids"`
+
+	// The emoji of the option, as returned by Discord.
+	Emoji *Emoji `json:"emoji,omitempty"`
+
+	// The emoji of the option, when editing onboarding.
+	EmojiID       string `json:"emoji_id,omitempty"`
+	EmojiName     string `json:"emoji_name,omitempty"`
+	EmojiAnimated bool   `json:"emoji_animated,omitempty"`
+}
+
+// GuildOnboardingPrompt is a question shown to new members during onboarding.
+type GuildOnboardingPrompt struct {
+	ID           string                        `json:"id,omitempty"`
+	Type         GuildOnboardingPromptType     `json:"type"`
+	Options      []GuildOnboardingPromptOption `json:"options"`
+	Title        string                        `json:"title"`
+	SingleSelect bool                          `json:"single_select"`
+	Required     bool                          `json:"required"`
+	InOnboarding bool                          `json:"in_onboarding"`
+}
+
+// GuildOnboarding stores the onboarding configuration of a guild. It is
+// also used with GuildOnboardingEdit, where unset fields are left unchanged.
+type GuildOnboarding struct {
+	GuildID string `json:"guild_id,omitempty"`
+
+	// The questions shown to new members.
+	Prompts *[]GuildOnboardingPrompt `json:"prompts,omitempty"`
+
+	// The IDs of the channels members get opted into automatically.
+	DefaultChannelIDs []string `json:"default_channel_ids,omitempty"`
+
+	// Whether onboarding is enabled for the guild.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	Mode *GuildOnboardingMode `json:"mode,omitempty"`
+}
+
 // Constants for the different bit offsets of text channel permissions
 const (
 	PermissionReadMessages = 1 << (iota + 10)
@@ -903,6 +1679,9 @@ const (
 	PermissionUseExternalEmojis
 )
 
+// PermissionViewGuildInsights allows viewing guild insights
+const PermissionViewGuildInsights = 1 << 19
+
 // Constants for the different bit offsets of voice permissions
 const (
 	PermissionVoiceConnect = 1 << (iota + 20)
@@ -932,6 +1711,8 @@ const (
 	PermissionManageServer
 	PermissionAddReactions
 	PermissionViewAuditLogs
+	PermissionPrioritySpeaker
+	PermissionVoiceStreamVideo
 
 	PermissionAllText = PermissionReadMessages |
 		PermissionSendMessages |
@@ -963,6 +1744,117 @@ const (
 		PermissionManageEmojis
 )
 
+// Constants for the different bit offsets of permissions above 32 bits. They
+// don't fit in the int permissions of a Role on 32 bit platforms, and are
+// meant to be used with Permissions.
+const (
+	PermissionUseApplicationCommands = 1 << (iota + 31)
+	PermissionRequestToSpeak
+	PermissionManageEvents
+	PermissionManageThreads
+	PermissionCreatePublicThreads
+	PermissionCreatePrivateThreads
+	PermissionUseExternalStickers
+	PermissionSendMessagesInThreads
+	PermissionUseEmbeddedActivities
+	PermissionModerateMembers
+	PermissionViewCreatorMonetizationAnalytics
+	PermissionUseSoundboard
+	PermissionCreateGuildExpressions
+	PermissionCreateEvents
+	PermissionUseExternalSounds
+	PermissionSendVoiceMessages
+	_
+	_
+	PermissionSendPolls
+	PermissionUseExternalApps
+)
+
+// Permissions is a set of permission bits, such as PermissionSendMessages
+type Permissions int64
+
+// permissionNames holds the display names of the permission bits, in bit order
+var permissionNames = []struct {
+	bit  Permissions
+	name string
+}{
+	{PermissionCreateInstantInvite, "Create Invite"},
+	{PermissionKickMembers, "Kick Members"},
+	{PermissionBanMembers, "Ban Members"},
+	{PermissionAdministrator, "Administrator"},
+	{PermissionManageChannels, "Manage Channels"},
+	{PermissionManageServer, "Manage Server"},
+	{PermissionAddReactions, "Add Reactions"},
+	{PermissionViewAuditLogs, "View Audit Log"},
+	{PermissionPrioritySpeaker, "Priority Speaker"},
+	{PermissionVoiceStreamVideo, "Video"},
+	{PermissionReadMessages, "View Channel"},
+	{PermissionSendMessages, "Send Messages"},
+	{PermissionSendTTSMessages, "Send Text-to-Speech Messages"},
+	{PermissionManageMessages, "Manage Messages"},
+	{PermissionEmbedLinks, "Embed Links"},
+	{PermissionAttachFiles, "Attach Files"},
+	{PermissionReadMessageHistory, "Read Message History"},
+	{PermissionMentionEveryone, "Mention Everyone"},
+	{PermissionUseExternalEmojis, "Use External Emojis"},
+	{PermissionViewGuildInsights, "View Server Insights"},
+	{PermissionVoiceConnect, "Connect"},
+	{PermissionVoiceSpeak, "Speak"},
+	{PermissionVoiceMuteMembers, "Mute Members"},
+	{PermissionVoiceDeafenMembers, "Deafen Members"},
+	{PermissionVoiceMoveMembers, "Move Members"},
+	{PermissionVoiceUseVAD, "Use Voice Activity"},
+	{PermissionChangeNickname, "Change Nickname"},
+	{PermissionManageNicknames, "Manage Nicknames"},
+	{PermissionManageRoles, "Manage Roles"},
+	{PermissionManageWebhooks, "Manage Webhooks"},
+	{PermissionManageEmojis, "Manage Expressions"},
+	{PermissionUseApplicationCommands, "Use Application Commands"},
+	{PermissionRequestToSpeak, "Request to Speak"},
+	{PermissionManageEvents, "Manage Events"},
+	{PermissionManageThreads, "Manage Threads"},
+	{PermissionCreatePublicThreads, "Create Public Threads"},
+	{PermissionCreatePrivateThreads, "Create Private Threads"},
+	{PermissionUseExternalStickers, "Use External Stickers"},
+	{PermissionSendMessagesInThreads, "Send Messages in Threads"},
+	{PermissionUseEmbeddedActivities, "Use Activities"},
+	{PermissionModerateMembers, "Timeout Members"},
+	{PermissionViewCreatorMonetizationAnalytics, "View Creator Monetization Analytics"},
+	{PermissionUseSoundboard, "Use Soundboard"},
+	{PermissionCreateGuildExpressions, "Create Expressions"},
+	{PermissionCreateEvents, "Create Events"},
+	{PermissionUseExternalSounds, "Use External Sounds"},
+	{PermissionSendVoiceMessages, "Send Voice Messages"},
+	{PermissionSendPolls, "Create Polls"},
+	{PermissionUseExternalApps, "Use External Apps"},
+}
+
+// Add returns the permissions with the given permission bits set
+func (p Permissions) Add(perms Permissions) Permissions {
+	return p | perms
+}
+
+// Remove returns the permissions with the given permission bits cleared
+func (p Permissions) Remove(perms Permissions) Permissions {
+	return p &^ perms
+}
+
+// Has returns whether all of the given permission bits are set
+func (p Permissions) Has(perms Permissions) bool {
+	return p&perms == perms
+}
+
+// Names returns the display names of the known permissions that are set,
+// in bit order.
+func (p Permissions) Names() (names []string) {
+	for _, n := range permissionNames {
+		if p&n.bit != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return
+}
+
 // Block contains Discord JSON Error Response codes
 const (
 	ErrCodeUnknownAccount     = 10001
@@ -980,6 +1872,7 @@ const (
 	ErrCodeUnknownUser        = 10013
 	ErrCodeUnknownEmoji       = 10014
 	ErrCodeUnknownWebhook     = 10015
+	ErrCodeUnknownBan         = 10026
 
 	ErrCodeBotsCannotUseEndpoint  = 20001
 	ErrCodeOnlyBotsCanUseEndpoint = 20002
@@ -0,0 +1,336 @@
+package discordgo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestGuildImageURLs(t *testing.T) {
+	g := &Guild{
+		ID:              "guild",
+		Icon:            "a_icon",
+		Banner:          "banner",
+		Splash:          "splash",
+		DiscoverySplash: "discovery",
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{"animated icon", g.IconURL("256"), "https://cdn.discordapp.com/icons/guild/a_icon.gif?size=256"},
+		{"banner", g.BannerURL(""), "https://cdn.discordapp.com/banners/guild/banner.png"},
+		{"splash", g.SplashURL("1024"), "https://cdn.discordapp.com/splashes/guild/splash.png?size=1024"},
+		{"discovery splash", g.DiscoverySplashURL("64"), "https://cdn.discordapp.com/discovery-splashes/guild/discovery.png?size=64"},
+	}
+	for _, tt := range tests {
+		if tt.url != tt.expected {
+			t.Errorf("%s URL is %q, expected %q", tt.name, tt.url, tt.expected)
+		}
+	}
+
+	g.Icon = "icon"
+	g.Banner = "a_banner"
+	if url := g.IconURL(""); url != "https://cdn.discordapp.com/icons/guild/icon.png" {
+		t.Errorf("static icon URL is %q", url)
+	}
+	if url := g.BannerURL("512"); url != "https://cdn.discordapp.com/banners/guild/a_banner.gif?size=512" {
+		t.Errorf("animated banner URL is %q", url)
+	}
+
+	empty := &Guild{ID: "guild"}
+	for name, url := range map[string]string{
+		"icon":             empty.IconURL("256"),
+		"banner":           empty.BannerURL("256"),
+		"splash":           empty.SplashURL("256"),
+		"discovery splash": empty.DiscoverySplashURL("256"),
+	} {
+		if url != "" {
+			t.Errorf("%s URL of a guild without one is %q, expected it to be empty", name, url)
+		}
+	}
+}
+
+func TestGuildIconFallback(t *testing.T) {
+	const placeholder = "https://example.com/placeholder.png"
+
+	g := &Guild{ID: "guild", Icon: "icon"}
+	if !g.HasIcon() {
+		t.Error("guild with an icon has no icon")
+	}
+	if url := g.IconURLOrDefault("128", placeholder); url != "https://cdn.discordapp.com/icons/guild/icon.png?size=128" {
+		t.Errorf("icon URL of a guild with an icon is %q", url)
+	}
+
+	g = &Guild{ID: "guild"}
+	if g.HasIcon() {
+		t.Error("guild without an icon has an icon")
+	}
+	if url := g.IconURLOrDefault("128", placeholder); url != placeholder {
+		t.Errorf("icon URL of a guild without an icon is %q, expected the placeholder", url)
+	}
+	if url := g.IconURLOrDefault("128", ""); url != "" {
+		t.Errorf("icon URL of a guild without an icon is %q, expected it to be empty", url)
+	}
+}
+
+func TestMemberAvatarURL(t *testing.T) {
+	m := &Member{
+		GuildID: "guild",
+		Avatar:  "member",
+		User:    &User{ID: "user", Avatar: "global", Discriminator: "1"},
+	}
+
+	if url := m.AvatarURL("128"); url != "https://cdn.discordapp.com/guilds/guild/users/user/avatars/member.png?size=128" {
+		t.Errorf("member avatar URL is %q", url)
+	}
+
+	m.Avatar = "a_member"
+	if url := m.AvatarURL(""); url != "https://cdn.discordapp.com/guilds/guild/users/user/avatars/a_member.gif" {
+		t.Errorf("animated member avatar URL is %q", url)
+	}
+
+	m.Avatar = ""
+	if url := m.AvatarURL("64"); url != m.User.AvatarURL("64") {
+		t.Errorf("avatar URL without a member avatar is %q, expected the user avatar %q", url, m.User.AvatarURL("64"))
+	}
+}
+
+func TestMemberDisplayName(t *testing.T) {
+	m := &Member{
+		Nick: "nick",
+		User: &User{Username: "username", GlobalName: "global"},
+	}
+
+	if name := m.DisplayName(); name != "nick" {
+		t.Errorf("DisplayName with a nickname is %q, expected %q", name, "nick")
+	}
+
+	m.Nick = ""
+	if name := m.DisplayName(); name != "global" {
+		t.Errorf("DisplayName with a global name is %q, expected %q", name, "global")
+	}
+
+	m.User.GlobalName = ""
+	if name := m.DisplayName(); name != "username" {
+		t.Errorf("DisplayName with only a username is %q, expected %q", name, "username")
+	}
+}
+
+func TestWebhookFromURL(t *testing.T) {
+	valid := []string{
+		"https://discord.com/api/webhooks/1234567890/token-abc_DEF",
+		"https://canary.discord.com/api/webhooks/1234567890/token-abc_DEF",
+		"https://ptb.discordapp.com/api/webhooks/1234567890/token-abc_DEF",
+		"https://discord.com/api/v10/webhooks/1234567890/token-abc_DEF",
+		"https://discord.com/api/webhooks/1234567890/token-abc_DEF/github",
+		"https://discord.com/api/webhooks/1234567890/token-abc_DEF?wait=true&thread_id=1",
+		"https://discord.com/api/webhooks/1234567890/token-abc_DEF/",
+	}
+	for _, u := range valid {
+		id, token, err := WebhookFromURL(u)
+		if err != nil || id != "1234567890" || token != "token-abc_DEF" {
+			t.Errorf("WebhookFromURL(%q) = %q, %q, %v", u, id, token, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"not a url",
+		"https://example.com/api/webhooks/1234567890/token",
+		"https://discord.com/api/channels/1234567890/token",
+		"https://discord.com/api/webhooks/1234567890",
+		"https://discord.com/api/webhooks/1234567890/",
+		"https://discord.com/api/webhooks/notanid/token",
+		"ftp://discord.com/api/webhooks/1234567890/token",
+	}
+	for _, u := range invalid {
+		if id, token, err := WebhookFromURL(u); err != ErrInvalidWebhookURL {
+			t.Errorf("WebhookFromURL(%q) = %q, %q, %v, expected ErrInvalidWebhookURL", u, id, token, err)
+		}
+	}
+}
+
+func TestPermissionsHas(t *testing.T) {
+	p := Permissions(PermissionSendMessages).Add(PermissionModerateMembers | PermissionUseExternalApps)
+
+	if !p.Has(PermissionSendMessages) || !p.Has(PermissionModerateMembers) || !p.Has(PermissionUseExternalApps) {
+		t.Errorf("permissions %d are missing bits that were added", p)
+	}
+	if !p.Has(PermissionModerateMembers | PermissionSendMessages) {
+		t.Errorf("permissions %d don't have both added bits", p)
+	}
+	if p.Has(PermissionModerateMembers | PermissionManageEvents) {
+		t.Errorf("permissions %d have all bits when only some are set", p)
+	}
+
+	p = p.Remove(PermissionModerateMembers)
+	if p.Has(PermissionModerateMembers) || !p.Has(PermissionUseExternalApps) {
+		t.Errorf("removing a bit left permissions %d", p)
+	}
+
+	if PermissionModerateMembers != 1<<40 || PermissionViewCreatorMonetizationAnalytics != 1<<41 || PermissionSendPolls != 1<<49 {
+		t.Errorf("high permission bits have the wrong offsets")
+	}
+}
+
+func TestPermissionsNames(t *testing.T) {
+	p := Permissions(PermissionModerateMembers | PermissionSendMessages | PermissionAdministrator | 1<<62)
+
+	names := p.Names()
+	expected := []string{"Administrator", "Send Messages", "Timeout Members"}
+	if len(names) != len(expected) {
+		t.Fatalf("Names returned %q, expected %q", names, expected)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("Names returned %q, expected %q", names, expected)
+		}
+	}
+
+	if names := Permissions(0).Names(); len(names) != 0 {
+		t.Errorf("Names of no permissions returned %q", names)
+	}
+}
+
+func TestChannelSendEmbeds(t *testing.T) {
+	var sent struct {
+		Embeds []*MessageEmbed `json:"embeds"`
+	}
+	requests := 0
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Method != "POST" || r.URL.Path != "/api/v6/channels/channel/messages" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Write([]byte(`{"id":"message"}`))
+	})
+
+	c := &Channel{ID: "channel", Type: ChannelTypeGuildText}
+	m, err := c.SendEmbeds(s, []*MessageEmbed{{Title: "CPU"}, {Title: "Memory"}})
+	if err != nil {
+		t.Fatalf("SendEmbeds returned error: %+v", err)
+	}
+	if m.ID != "message" {
+		t.Errorf("SendEmbeds returned %+v", m)
+	}
+	if len(sent.Embeds) != 2 || sent.Embeds[0].Title != "CPU" || sent.Embeds[1].Title != "Memory" {
+		t.Errorf("embeds were sent as %+v", sent.Embeds)
+	}
+
+	embeds := make([]*MessageEmbed, 11)
+	for i := range embeds {
+		embeds[i] = &MessageEmbed{Title: "embed"}
+	}
+	if _, err = c.SendEmbeds(s, embeds); err == nil {
+		t.Error("SendEmbeds with 11 embeds returned no error")
+	}
+	if requests != 1 {
+		t.Errorf("%d requests were made, expected the over-limit embeds not to be sent", requests)
+	}
+}
+
+func TestRoleIconURL(t *testing.T) {
+	r := &Role{ID: "role", Icon: "icon"}
+	if u := r.IconURL("64"); u != "https://cdn.discordapp.com/role-icons/role/icon.png?size=64" {
+		t.Errorf("role icon URL is %s", u)
+	}
+
+	r = &Role{ID: "role", UnicodeEmoji: "🔥"}
+	if u := r.IconURL(""); u != "" {
+		t.Errorf("role without an icon has icon URL %s", u)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		mention  string
+		expected string
+	}{
+		{"Role.Mention", (&Role{ID: "123"}).Mention(), "<@&123>"},
+		{"Channel.Mention", (&Channel{ID: "456"}).Mention(), "<#456>"},
+		{"User.Mention", (&User{ID: "789"}).Mention(), "<@789>"},
+		{"Member.Mention", (&Member{User: &User{ID: "789"}}).Mention(), "<@!789>"},
+	}
+	for _, test := range tests {
+		if test.mention != test.expected {
+			t.Errorf("%s is %q, expected %q", test.name, test.mention, test.expected)
+		}
+	}
+}
+
+func TestGuildRoleCreateComplex(t *testing.T) {
+	var body map[string]interface{}
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v6/guilds/guild/roles" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"id":"role","name":"Hot","unicode_emoji":"🔥"}`))
+	})
+
+	emoji := "🔥"
+	hoist := true
+	role, err := s.GuildRoleCreateComplex("guild", &RoleParams{Name: "Hot", Hoist: &hoist, UnicodeEmoji: &emoji})
+	if err != nil {
+		t.Fatalf("GuildRoleCreateComplex returned error: %+v", err)
+	}
+	if role.UnicodeEmoji != "🔥" {
+		t.Errorf("role decoded as %+v", role)
+	}
+
+	if body["name"] != "Hot" || body["hoist"] != true || body["unicode_emoji"] != "🔥" {
+		t.Errorf("role was created with %v", body)
+	}
+	for _, k := range []string{"color", "permissions", "mentionable", "icon"} {
+		if _, ok := body[k]; ok {
+			t.Errorf("unset setting %s was sent, body %v", k, body)
+		}
+	}
+}
+
+func TestGuildRoleComplexNilParams(t *testing.T) {
+	var bodies []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		w.Write([]byte(`{"id":"role"}`))
+	})
+
+	if _, err := s.GuildRoleCreateComplex("guild", nil); err != nil {
+		t.Fatalf("GuildRoleCreateComplex returned error: %+v", err)
+	}
+	if _, err := s.GuildRoleEditComplex("guild", "role", nil); err != nil {
+		t.Fatalf("GuildRoleEditComplex returned error: %+v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != `{}` || bodies[1] != `{}` {
+		t.Errorf("roles were created and edited with %q, expected empty bodies", bodies)
+	}
+}
+
+func TestRoleParamsIcon(t *testing.T) {
+	icon, emoji, none := "data:image/png;base64,aWNvbg==", "🔥", ""
+	for _, test := range []struct {
+		icon, emoji *string
+		expected    string
+	}{
+		{nil, nil, `{}`},
+		{&icon, nil, `{"icon":"data:image/png;base64,aWNvbg=="}`},
+		{&none, nil, `{"icon":null}`},
+		{nil, &emoji, `{"unicode_emoji":"🔥"}`},
+		{nil, &none, `{"unicode_emoji":null}`},
+	} {
+		b, err := json.Marshal(&RoleParams{Icon: test.icon, UnicodeEmoji: test.emoji})
+		if err != nil {
+			t.Fatalf("error marshaling role params, %s", err)
+		}
+		if string(b) != test.expected {
+			t.Errorf("role params were marshaled as %s, expected %s", b, test.expected)
+		}
+	}
+}
@@ -12,6 +12,7 @@ package discordgo
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -24,6 +25,32 @@ func (t Timestamp) Parse() (time.Time, error) {
 	return time.Parse(time.RFC3339, string(t))
 }
 
+// TimestampStyle is the style a timestamp formatted with FormatTimestamp is
+// shown in. Discord shows it in the time zone and locale of the reader.
+type TimestampStyle string
+
+// Block contains the valid known TimestampStyle values, with examples
+const (
+	TimestampStyleDefault       TimestampStyle = ""  // 20 April 2021 16:20
+	TimestampStyleShortTime     TimestampStyle = "t" // 16:20
+	TimestampStyleLongTime      TimestampStyle = "T" // 16:20:30
+	TimestampStyleShortDate     TimestampStyle = "d" // 20/04/2021
+	TimestampStyleLongDate      TimestampStyle = "D" // 20 April 2021
+	TimestampStyleShortDateTime TimestampStyle = "f" // 20 April 2021 16:20
+	TimestampStyleLongDateTime  TimestampStyle = "F" // Tuesday, 20 April 2021 16:20
+	TimestampStyleRelativeTime  TimestampStyle = "R" // 2 months ago
+)
+
+// FormatTimestamp returns the markdown for a timestamp, which Discord shows
+// in the given style.
+func FormatTimestamp(t time.Time, style TimestampStyle) string {
+	unix := strconv.FormatInt(t.Unix(), 10)
+	if style == TimestampStyleDefault {
+		return "<t:" + unix + ">"
+	}
+	return "<t:" + unix + ":" + string(style) + ">"
+}
+
 // RESTError stores error information about a request with a bad response code.
 // Message is not always present, there are cases where api calls can fail
 // without returning a json message.
@@ -22,3 +22,26 @@ func TestTimestampParse(t *testing.T) {
 		t.Error("Incorrect timezone")
 	}
 }
+
+func TestFormatTimestamp(t *testing.T) {
+	ts := time.Date(2021, time.April, 20, 16, 20, 30, 0, time.FixedZone("CEST", 2*60*60))
+
+	tests := []struct {
+		style    TimestampStyle
+		expected string
+	}{
+		{TimestampStyleDefault, "<t:1618928430>"},
+		{TimestampStyleShortTime, "<t:1618928430:t>"},
+		{TimestampStyleLongTime, "<t:1618928430:T>"},
+		{TimestampStyleShortDate, "<t:1618928430:d>"},
+		{TimestampStyleLongDate, "<t:1618928430:D>"},
+		{TimestampStyleShortDateTime, "<t:1618928430:f>"},
+		{TimestampStyleLongDateTime, "<t:1618928430:F>"},
+		{TimestampStyleRelativeTime, "<t:1618928430:R>"},
+	}
+	for _, tt := range tests {
+		if s := FormatTimestamp(ts, tt.style); s != tt.expected {
+			t.Errorf("FormatTimestamp with style %q = %s, expected %s", tt.style, s, tt.expected)
+		}
+	}
+}
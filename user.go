@@ -14,6 +14,9 @@ type User struct {
 	// The user's username.
 	Username string `json:"username"`
 
+	// The user's display name, if they have set one.
+	GlobalName string `json:"global_name"`
+
 	// The hash of the user's avatar. Use Session.UserAvatar
 	// to retrieve the avatar itself.
 	Avatar string `json:"avatar"`
@@ -48,7 +51,7 @@ func (u *User) String() string {
 
 // Mention return a string which mentions the user
 func (u *User) Mention() string {
-	return "<@" + u.ID + ">"
+	return UserMention(u.ID)
 }
 
 // AvatarURL returns a URL to the user's avatar.
@@ -14,6 +14,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
@@ -73,17 +74,45 @@ type VoiceConnection struct {
 // VoiceSpeakingUpdate event
 type VoiceSpeakingUpdateHandler func(vc *VoiceConnection, vs *VoiceSpeakingUpdate)
 
+// SpeakingFlags is the speaking mode of a VoiceConnection. It is a
+// bitfield, e.g. SpeakingMicrophone | SpeakingPriority.
+type SpeakingFlags int
+
+// Block contains the valid known SpeakingFlags values
+const (
+	// Normal transmission of voice audio.
+	SpeakingMicrophone SpeakingFlags = 1 << iota
+	// Transmission of the audio of a screen share, without the speaking
+	// indicator.
+	SpeakingSoundshare
+	// Speaking as a priority speaker, lowering the volume of the others.
+	SpeakingPriority
+)
+
 // Speaking sends a speaking notification to Discord over the voice websocket.
 // This must be sent as true prior to sending audio and should be set to false
 // once finished sending audio.
+// It is the same as SetSpeaking with SpeakingMicrophone, or 0 if b is false.
 //  b  : Send true if speaking, false if not.
 func (v *VoiceConnection) Speaking(b bool) (err error) {
+	if b {
+		return v.SetSpeaking(SpeakingMicrophone)
+	}
+	return v.SetSpeaking(0)
+}
+
+// SetSpeaking sends the speaking mode to Discord over the voice websocket.
+// A mode must be set prior to sending audio and should be set to 0 once
+// finished sending audio.
+//  mode  : The speaking mode, 0 if not speaking.
+func (v *VoiceConnection) SetSpeaking(mode SpeakingFlags) (err error) {
 
-	v.log(LogDebug, "called (%t)", b)
+	v.log(LogDebug, "called (%d)", mode)
 
 	type voiceSpeakingData struct {
-		Speaking bool `json:"speaking"`
-		Delay    int  `json:"delay"`
+		Speaking SpeakingFlags `json:"speaking"`
+		Delay    int           `json:"delay"`
+		SSRC     uint32        `json:"ssrc"`
 	}
 
 	type voiceSpeakingOp struct {
@@ -95,7 +124,10 @@ func (v *VoiceConnection) Speaking(b bool) (err error) {
 		return fmt.Errorf("no VoiceConnection websocket")
 	}
 
-	data := voiceSpeakingOp{5, voiceSpeakingData{b, 0}}
+	v.RLock()
+	data := voiceSpeakingOp{5, voiceSpeakingData{mode, 0, v.op2.SSRC}}
+	v.RUnlock()
+
 	v.wsMutex.Lock()
 	err = v.wsConn.WriteJSON(data)
 	v.wsMutex.Unlock()
@@ -104,11 +136,11 @@ func (v *VoiceConnection) Speaking(b bool) (err error) {
 	defer v.Unlock()
 	if err != nil {
 		v.speaking = false
-		v.log(LogError, "Speaking() write json error, %s", err)
+		v.log(LogError, "SetSpeaking() write json error, %s", err)
 		return
 	}
 
-	v.speaking = b
+	v.speaking = mode != 0
 
 	return
 }
@@ -301,7 +333,9 @@ func (v *VoiceConnection) open() (err error) {
 	// Connect to VoiceConnection Websocket
 	vg := "wss://" + strings.TrimSuffix(v.endpoint, ":80")
 	v.log(LogInformational, "connecting to voice endpoint %s", vg)
-	v.wsConn, _, err = websocket.DefaultDialer.Dial(vg, nil)
+	header := http.Header{}
+	header.Set("User-Agent", v.session.userAgent())
+	v.wsConn, _, err = v.session.dialer().Dial(vg, header)
 	if err != nil {
 		v.log(LogWarning, "error connecting to voice endpoint %s, %s", vg, err)
 		v.log(LogDebug, "voice struct: %#v\n", v)
@@ -746,6 +780,43 @@ func (v *VoiceConnection) opusSender(udpConn *net.UDPConn, close <-chan struct{}
 	}
 }
 
+// silenceFrame is an Opus frame of silence.
+var silenceFrame = []byte{0xF8, 0xFF, 0xFE}
+
+// SendSilence sends frames Opus frames of silence, which should be sent
+// when pausing or stopping audio so that the clients do not interpolate the
+// missing audio. Discord recommends sending 5 frames. It returns an error
+// if the connection is not ready to send audio.
+//  frames  : The number of silence frames to send.
+func (v *VoiceConnection) SendSilence(frames int) (err error) {
+
+	v.RLock()
+	opus := v.OpusSend
+	closed := v.close
+	ready := v.Ready
+	v.RUnlock()
+
+	if opus == nil {
+		return fmt.Errorf("no VoiceConnection opus send channel")
+	}
+
+	// Unless the connection is ready, nothing may receive the frames, and
+	// nothing closes the connection to stop waiting for it.
+	if !ready || closed == nil {
+		return fmt.Errorf("VoiceConnection not ready to send silence")
+	}
+
+	for i := 0; i < frames; i++ {
+		select {
+		case opus <- silenceFrame:
+		case <-closed:
+			return fmt.Errorf("VoiceConnection closed while sending silence")
+		}
+	}
+
+	return
+}
+
 // A Packet contains the headers and content of a received voice packet.
 type Packet struct {
 	SSRC      uint32
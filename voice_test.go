@@ -0,0 +1,160 @@
+package discordgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+func TestSetSpeaking(t *testing.T) {
+	ops := make(chan testGatewayOp, 1)
+	gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+		for {
+			op, err := readOp(conn)
+			if err != nil {
+				return
+			}
+			ops <- op
+		}
+	})
+	defer gw.Close()
+
+	wsConn, _, err := websocket.DefaultDialer.Dial(gw.URL(), nil)
+	if err != nil {
+		t.Fatalf("error dialing voice websocket, %s", err)
+	}
+	defer wsConn.Close()
+
+	v := &VoiceConnection{wsConn: wsConn}
+	v.op2.SSRC = 42
+
+	tests := []struct {
+		mode     SpeakingFlags
+		speaking int
+	}{
+		{SpeakingMicrophone, 1},
+		{SpeakingSoundshare, 2},
+		{SpeakingPriority, 4},
+		{SpeakingMicrophone | SpeakingPriority, 5},
+		{SpeakingMicrophone | SpeakingSoundshare | SpeakingPriority, 7},
+		{0, 0},
+	}
+	for _, test := range tests {
+		if err := v.SetSpeaking(test.mode); err != nil {
+			t.Fatalf("SetSpeaking(%d) returned error: %+v", test.mode, err)
+		}
+
+		var op testGatewayOp
+		select {
+		case op = <-ops:
+		case <-time.After(time.Second):
+			t.Fatalf("SetSpeaking(%d) did not send an op", test.mode)
+		}
+
+		var data struct {
+			Speaking int    `json:"speaking"`
+			Delay    int    `json:"delay"`
+			SSRC     uint32 `json:"ssrc"`
+		}
+		json.Unmarshal(op.Data, &data)
+		if op.Op != 5 || data.Speaking != test.speaking || data.Delay != 0 || data.SSRC != 42 {
+			t.Errorf("SetSpeaking(%d) sent op %d with %s, expected speaking %d", test.mode, op.Op, op.Data, test.speaking)
+		}
+
+		v.RLock()
+		speaking := v.speaking
+		v.RUnlock()
+		if speaking != (test.mode != 0) {
+			t.Errorf("after SetSpeaking(%d) the connection is speaking: %t", test.mode, speaking)
+		}
+	}
+
+	if err := v.Speaking(true); err != nil {
+		t.Fatalf("Speaking(true) returned error: %+v", err)
+	}
+	if op := <-ops; !bytes.Contains(op.Data, []byte(`"speaking":1`)) {
+		t.Errorf("Speaking(true) sent %s, expected the microphone mode", op.Data)
+	}
+
+	if err := (&VoiceConnection{}).SetSpeaking(SpeakingMicrophone); err == nil {
+		t.Error("SetSpeaking returned no error without a websocket")
+	}
+}
+
+func TestSendSilence(t *testing.T) {
+	v := &VoiceConnection{OpusSend: make(chan []byte, 5), close: make(chan struct{}), Ready: true}
+
+	if err := v.SendSilence(5); err != nil {
+		t.Fatalf("SendSilence returned error: %+v", err)
+	}
+	if len(v.OpusSend) != 5 {
+		t.Fatalf("SendSilence sent %d frames, expected 5", len(v.OpusSend))
+	}
+	for i := 0; i < 5; i++ {
+		if frame := <-v.OpusSend; !bytes.Equal(frame, []byte{0xF8, 0xFF, 0xFE}) {
+			t.Errorf("frame %d is %x, expected a silence frame", i, frame)
+		}
+	}
+
+	if err := (&VoiceConnection{}).SendSilence(5); err == nil {
+		t.Error("SendSilence returned no error without an opus send channel")
+	}
+
+	if err := (&VoiceConnection{OpusSend: make(chan []byte)}).SendSilence(5); err == nil {
+		t.Error("SendSilence returned no error on a connection that is not ready")
+	}
+
+	closed := &VoiceConnection{OpusSend: make(chan []byte), close: make(chan struct{}), Ready: true}
+	close(closed.close)
+	if err := closed.SendSilence(5); err == nil {
+		t.Error("SendSilence returned no error on a closed connection")
+	}
+}
+
+func TestSendSilenceUDP(t *testing.T) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("cannot listen on UDP, %s", err)
+	}
+	defer server.Close()
+
+	udpConn, err := net.DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("error dialing UDP, %s", err)
+	}
+	defer udpConn.Close()
+
+	v := &VoiceConnection{OpusSend: make(chan []byte, 2), close: make(chan struct{}), Ready: true, speaking: true}
+	v.op4.SecretKey[0] = 1
+	defer close(v.close)
+	go v.opusSender(udpConn, v.close, v.OpusSend, 48000, 960)
+
+	if err := v.SendSilence(2); err != nil {
+		t.Fatalf("SendSilence returned error: %+v", err)
+	}
+
+	buf := make([]byte, 1024)
+	for i := 0; i < 2; i++ {
+		server.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := server.Read(buf)
+		if err != nil {
+			t.Fatalf("silence frame %d was not sent, %s", i, err)
+		}
+
+		var nonce [24]byte
+		copy(nonce[:], buf[:12])
+		opus, ok := secretbox.Open(nil, buf[12:n], &nonce, &v.op4.SecretKey)
+		if !ok {
+			t.Fatalf("packet %d could not be decrypted", i)
+		}
+		if !bytes.Equal(opus, silenceFrame) {
+			t.Errorf("packet %d holds %x, expected a silence frame", i, opus)
+		}
+	}
+}
@@ -12,13 +12,21 @@ package discordgo
 
 import (
 	"bytes"
+	"compress/flate"
 	"compress/zlib"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
 	"net/http"
+	"reflect"
 	"runtime"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -33,10 +41,71 @@ var ErrWSAlreadyOpen = errors.New("web socket already opened")
 // that doesn't exist
 var ErrWSNotFound = errors.New("no websocket connection exists")
 
+// ErrGatewayTimeout is returned by Open when the gateway is not connected
+// within DialTimeout, or the handshake not completed within HandshakeTimeout.
+var ErrGatewayTimeout = errors.New("timed out connecting to the gateway")
+
 // ErrWSShardBounds is thrown when you try to use a shard ID that is
 // less than the total shard count
 var ErrWSShardBounds = errors.New("ShardID must be less than ShardCount")
 
+// Block contains the known close codes of the gateway.
+const (
+	GatewayCloseUnknownError         = 4000
+	GatewayCloseUnknownOpcode        = 4001
+	GatewayCloseDecodeError          = 4002
+	GatewayCloseNotAuthenticated     = 4003
+	GatewayCloseAuthenticationFailed = 4004
+	GatewayCloseAlreadyAuthenticated = 4005
+	GatewayCloseInvalidSequence      = 4007
+	GatewayCloseRateLimited          = 4008
+	GatewayCloseSessionTimedOut      = 4009
+	GatewayCloseInvalidShard         = 4010
+	GatewayCloseShardingRequired     = 4011
+	GatewayCloseInvalidAPIVersion    = 4012
+	GatewayCloseInvalidIntents       = 4013
+	GatewayCloseDisallowedIntents    = 4014
+)
+
+// A GatewayCloseError is returned when the gateway closes the connection,
+// such as when Open is called with an invalid token.
+type GatewayCloseError struct {
+	Code int
+	Text string
+}
+
+// Error returns a description of the close code.
+func (e *GatewayCloseError) Error() string {
+	return fmt.Sprintf("gateway closed the connection with code %d: %s", e.Code, e.Text)
+}
+
+// Fatal returns whether connecting again would fail the same way, because
+// the session is misconfigured, e.g. with an invalid token or intents. The
+// session does not reconnect after a fatal close.
+func (e *GatewayCloseError) Fatal() bool {
+	switch e.Code {
+	case GatewayCloseAuthenticationFailed, GatewayCloseInvalidShard, GatewayCloseShardingRequired,
+		GatewayCloseInvalidAPIVersion, GatewayCloseInvalidIntents, GatewayCloseDisallowedIntents:
+		return true
+	}
+	return false
+}
+
+// fatalCloseError returns the GatewayCloseError of err if it is a fatal
+// close of the gateway connection.
+func fatalCloseError(err error) (*GatewayCloseError, bool) {
+	closeErr, ok := err.(*GatewayCloseError)
+	if !ok {
+		if wsErr, isClose := err.(*websocket.CloseError); isClose {
+			closeErr, ok = &GatewayCloseError{Code: wsErr.Code, Text: wsErr.Text}, true
+		}
+	}
+	if !ok || !closeErr.Fatal() {
+		return nil, false
+	}
+	return closeErr, true
+}
+
 type resumePacket struct {
 	Op   int `json:"op"`
 	Data struct {
@@ -46,6 +115,14 @@ type resumePacket struct {
 	} `json:"d"`
 }
 
+// dialer returns the dialer the session connects to websockets with.
+func (s *Session) dialer() *websocket.Dialer {
+	if s.Dialer != nil {
+		return s.Dialer
+	}
+	return websocket.DefaultDialer
+}
+
 // Open creates a websocket connection to Discord.
 // See: https://discordapp.com/developers/docs/topics/gateway#connecting
 func (s *Session) Open() error {
@@ -63,6 +140,9 @@ func (s *Session) Open() error {
 		return ErrWSAlreadyOpen
 	}
 
+	// Accept events again if the session was shut down before.
+	s.active.reopen()
+
 	// Get the gateway to use for the Websocket connection
 	if s.gateway == "" {
 		s.gateway, err = s.Gateway()
@@ -72,18 +152,32 @@ func (s *Session) Open() error {
 
 		// Add the version and encoding to the URL
 		s.gateway = s.gateway + "?v=" + APIVersion + "&encoding=json"
+
+		if s.TransportCompress {
+			s.gateway += "&compress=zlib-stream"
+		}
 	}
 
 	// Connect to the Gateway
 	s.log(LogInformational, "connecting to gateway %s", s.gateway)
 	header := http.Header{}
 	header.Add("accept-encoding", "zlib")
-	s.wsConn, _, err = websocket.DefaultDialer.Dial(s.gateway, header)
+	header.Set("User-Agent", s.userAgent())
+	ctx := context.Background()
+	if s.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.DialTimeout)
+		defer cancel()
+	}
+	s.wsConn, _, err = s.dialer().DialContext(ctx, s.gateway, header)
 	if err != nil {
 		s.log(LogWarning, "error connecting to gateway %s, %s", s.gateway, err)
 		s.gateway = "" // clear cached gateway
 		s.wsConn = nil // Just to be safe.
-		return err
+		if ctx.Err() == context.DeadlineExceeded {
+			return ErrGatewayTimeout
+		}
+		return timeoutError(err)
 	}
 
 	s.wsConn.SetCloseHandler(func(code int, text string) error {
@@ -100,10 +194,22 @@ func (s *Session) Open() error {
 		}
 	}()
 
+	// A zlib-stream context lives as long as the websocket it belongs to.
+	var zs *zlibStream
+	if s.TransportCompress {
+		zs = &zlibStream{}
+	}
+
+	// The handshake, up to READY or RESUMED, must complete in time.
+	if s.HandshakeTimeout > 0 {
+		s.wsConn.SetReadDeadline(time.Now().Add(s.HandshakeTimeout))
+	}
+
 	// The first response from Discord should be an Op 10 (Hello) Packet.
 	// When processed by onEvent the heartbeat goroutine will be started.
-	mt, m, err := s.wsConn.ReadMessage()
+	mt, m, err := s.readMessage(s.wsConn, zs)
 	if err != nil {
+		err = handshakeError(err)
 		return err
 	}
 	e, err := s.onEvent(mt, m)
@@ -137,16 +243,8 @@ func (s *Session) Open() error {
 	} else {
 
 		// Send Op 6 Resume Packet
-		p := resumePacket{}
-		p.Op = 6
-		p.Data.Token = s.Token
-		p.Data.SessionID = s.sessionID
-		p.Data.Sequence = sequence
-
 		s.log(LogInformational, "sending resume packet to gateway")
-		s.wsMutex.Lock()
-		err = s.wsConn.WriteJSON(p)
-		s.wsMutex.Unlock()
+		err = s.resume(sequence)
 		if err != nil {
 			err = fmt.Errorf("error sending gateway resume packet, %s, %s", s.gateway, err)
 			return err
@@ -169,10 +267,12 @@ func (s *Session) Open() error {
 	}
 
 	// Now Discord should send us a READY or RESUMED packet.
-	mt, m, err = s.wsConn.ReadMessage()
+	mt, m, err = s.readMessage(s.wsConn, zs)
 	if err != nil {
+		err = handshakeError(err)
 		return err
 	}
+	s.wsConn.SetReadDeadline(time.Time{})
 	e, err = s.onEvent(mt, m)
 	if err != nil {
 		return err
@@ -199,22 +299,117 @@ func (s *Session) Open() error {
 	s.listening = make(chan interface{})
 
 	// Start sending heartbeats and reading messages from Discord.
-	go s.heartbeat(s.wsConn, s.listening, h.HeartbeatInterval)
-	go s.listen(s.wsConn, s.listening)
+	go s.heartbeat(s.wsConn, s.listening, s.heartbeatInterval(h.HeartbeatInterval))
+	go s.listen(s.wsConn, s.listening, zs)
 
 	s.log(LogInformational, "exiting")
 	return nil
 }
 
+// timeoutError returns ErrGatewayTimeout if err is a deadline of the
+// connection to the gateway being exceeded, or err otherwise.
+func timeoutError(err error) error {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return ErrGatewayTimeout
+	}
+	return err
+}
+
+// handshakeError returns the error of a read during the handshake of Open,
+// as a GatewayCloseError if the gateway closed the connection.
+func handshakeError(err error) error {
+	if wsErr, ok := err.(*websocket.CloseError); ok {
+		return &GatewayCloseError{Code: wsErr.Code, Text: wsErr.Text}
+	}
+	return timeoutError(err)
+}
+
+// zlibSuffix is the Z_SYNC_FLUSH marker that terminates every complete
+// message sent over a zlib-stream compressed gateway connection.
+var zlibSuffix = []byte{0x00, 0x00, 0xff, 0xff}
+
+// zlibWindowSize is the size of the deflate sliding window.
+const zlibWindowSize = 32 * 1024
+
+// zlibStream inflates a zlib-stream compressed gateway connection.
+// Discord compresses the whole connection with a single zlib context and
+// flushes it at the end of every message, so a message may only reference
+// data from the previous ones through the deflate window.
+type zlibStream struct {
+	// Compressed frames that do not yet end with zlibSuffix.
+	buf []byte
+
+	// The last inflated bytes, used as the dictionary for the next message.
+	window []byte
+
+	// Whether the two byte zlib header has been read.
+	header bool
+}
+
+// decompress adds a websocket frame to the stream. Once a frame completes a
+// message, the inflated message is returned with ok set to true.
+func (z *zlibStream) decompress(frame []byte) (message []byte, ok bool, err error) {
+	z.buf = append(z.buf, frame...)
+	if !bytes.HasSuffix(z.buf, zlibSuffix) {
+		return nil, false, nil
+	}
+
+	data := z.buf
+	z.buf = nil
+
+	if !z.header {
+		if len(data) < 2 || data[0]&0x0f != 8 || (uint16(data[0])<<8|uint16(data[1]))%31 != 0 {
+			return nil, false, zlib.ErrHeader
+		}
+		data = data[2:]
+		z.header = true
+	}
+
+	// The stream never ends, so the inflater always runs out of input
+	// right after the sync flush.
+	message, err = ioutil.ReadAll(flate.NewReaderDict(bytes.NewReader(data), z.window))
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, false, err
+	}
+
+	z.window = append(z.window, message...)
+	if len(z.window) > zlibWindowSize {
+		z.window = z.window[len(z.window)-zlibWindowSize:]
+	}
+
+	return message, true, nil
+}
+
+// readMessage reads the next complete message from the gateway websocket.
+// When zs is not nil, binary frames are buffered and inflated through it.
+func (s *Session) readMessage(wsConn *websocket.Conn, zs *zlibStream) (messageType int, message []byte, err error) {
+	for {
+		messageType, message, err = wsConn.ReadMessage()
+		if err != nil || zs == nil || messageType != websocket.BinaryMessage {
+			return
+		}
+
+		var ok bool
+		message, ok, err = zs.decompress(message)
+		if err != nil {
+			s.log(LogError, "error inflating zlib-stream message, %s", err)
+			return
+		}
+		if ok {
+			return websocket.TextMessage, message, nil
+		}
+	}
+}
+
 // listen polls the websocket connection for events, it will stop when the
 // listening channel is closed, or an error occurs.
-func (s *Session) listen(wsConn *websocket.Conn, listening <-chan interface{}) {
+func (s *Session) listen(wsConn *websocket.Conn, listening <-chan interface{}, zs *zlibStream) {
 
 	s.log(LogInformational, "called")
 
 	for {
 
-		messageType, message, err := wsConn.ReadMessage()
+		messageType, message, err := s.readMessage(wsConn, zs)
 
 		if err != nil {
 
@@ -230,9 +425,18 @@ func (s *Session) listen(wsConn *websocket.Conn, listening <-chan interface{}) {
 				s.log(LogWarning, "error reading from gateway %s websocket, %s", s.gateway, err)
 				// There has been an error reading, close the websocket so that
 				// OnDisconnect event is emitted.
-				err := s.Close()
-				if err != nil {
-					s.log(LogWarning, "error closing session connection, %s", err)
+				code := websocket.CloseAbnormalClosure
+				if wsErr, ok := err.(*websocket.CloseError); ok {
+					code = wsErr.Code
+				}
+				closeErr := s.close(code)
+				if closeErr != nil {
+					s.log(LogWarning, "error closing session connection, %s", closeErr)
+				}
+
+				if fatal, ok := fatalCloseError(err); ok {
+					s.log(LogError, "not reconnecting to gateway, %s", fatal)
+					return
 				}
 
 				s.log(LogInformational, "calling reconnect() now")
@@ -264,6 +468,18 @@ type helloOp struct {
 	Trace             []string      `json:"_trace"`
 }
 
+// heartbeatInterval returns the interval of the heartbeats in milliseconds,
+// given the interval sent in Hello, unless HeartbeatInterval is set.
+func (s *Session) heartbeatInterval(hello time.Duration) time.Duration {
+	if s.HeartbeatInterval <= 0 {
+		return hello
+	}
+	if ms := s.HeartbeatInterval / time.Millisecond; ms > 0 {
+		return ms
+	}
+	return 1
+}
+
 // FailedHeartbeatAcks is the Number of heartbeat intervals to wait until forcing a connection restart.
 const FailedHeartbeatAcks time.Duration = 5 * time.Millisecond
 
@@ -300,6 +516,15 @@ func (s *Session) heartbeat(wsConn *websocket.Conn, listening <-chan interface{}
 		err = wsConn.WriteJSON(heartbeatOp{1, sequence})
 		s.wsMutex.Unlock()
 		if err != nil || time.Now().UTC().Sub(last) > (heartbeatIntervalMsec*FailedHeartbeatAcks) {
+			// If the connection was closed already, such as by listen after
+			// the gateway closed it, whoever closed it decides to reconnect.
+			s.RLock()
+			sameConnection := s.wsConn == wsConn
+			s.RUnlock()
+			if !sameConnection {
+				return
+			}
+
 			if err != nil {
 				s.log(LogError, "error sending heartbeat to gateway %s, %s", s.gateway, err)
 			} else {
@@ -399,6 +624,53 @@ func (s *Session) UpdateStatusComplex(usd UpdateStatusData) (err error) {
 	return
 }
 
+// The number of gateway commands sent through a gatewayLimiter per
+// gatewayCommandWindow. Discord allows 120, some are left for heartbeats and
+// status updates.
+const (
+	gatewayCommandLimit  = 110
+	gatewayCommandWindow = time.Minute
+)
+
+// gatewayLimiter keeps gateway commands, such as member requests, under
+// Discord's limit, so that sending many of them doesn't get the session
+// disconnected.
+type gatewayLimiter struct {
+	sync.Mutex
+
+	// the limit and window, gatewayCommandLimit and gatewayCommandWindow
+	// if unset
+	limit  int
+	window time.Duration
+
+	// the times of the last commands sent, oldest first
+	sent []time.Time
+}
+
+// wait blocks until another command can be sent, and counts it as sent.
+func (l *gatewayLimiter) wait() {
+	l.Lock()
+	defer l.Unlock()
+
+	limit, window := l.limit, l.window
+	if limit <= 0 {
+		limit = gatewayCommandLimit
+	}
+	if window <= 0 {
+		window = gatewayCommandWindow
+	}
+
+	now := time.Now()
+	if len(l.sent) >= limit {
+		if d := l.sent[0].Add(window).Sub(now); d > 0 {
+			time.Sleep(d)
+			now = time.Now()
+		}
+		l.sent = l.sent[1:]
+	}
+	l.sent = append(l.sent, now)
+}
+
 type requestGuildMembersData struct {
 	GuildID string `json:"guild_id"`
 	Query   string `json:"query"`
@@ -418,6 +690,8 @@ type requestGuildMembersOp struct {
 func (s *Session) RequestGuildMembers(guildID, query string, limit int) (err error) {
 	s.log(LogInformational, "called")
 
+	s.commands.wait()
+
 	s.RLock()
 	defer s.RUnlock()
 	if s.wsConn == nil {
@@ -437,6 +711,64 @@ func (s *Session) RequestGuildMembers(guildID, query string, limit int) (err err
 	return
 }
 
+type guildSubscribeData struct {
+	GuildID    string              `json:"guild_id"`
+	Typing     bool                `json:"typing"`
+	Threads    bool                `json:"threads"`
+	Activities bool                `json:"activities"`
+	Channels   map[string][][2]int `json:"channels,omitempty"`
+}
+
+type guildSubscribeOp struct {
+	Op   int                `json:"op"`
+	Data guildSubscribeData `json:"d"`
+}
+
+// GuildSubscribe subscribes to the member list of a large guild, in which
+// Discord doesn't send all members. The gateway responds with
+// GUILD_MEMBER_LIST_UPDATE events for the requested ranges of the member
+// list, as shown in the given channels.
+// guildID  : The ID of the guild to subscribe to
+// channels : The ranges of the member list to subscribe to by channel ID, e.g. {channelID: {{0, 99}}}
+func (s *Session) GuildSubscribe(guildID string, channels map[string][][2]int) (err error) {
+	s.log(LogInformational, "called")
+
+	s.commands.wait()
+
+	s.RLock()
+	defer s.RUnlock()
+	if s.wsConn == nil {
+		return ErrWSNotFound
+	}
+
+	data := guildSubscribeData{
+		GuildID:    guildID,
+		Typing:     true,
+		Threads:    true,
+		Activities: true,
+		Channels:   channels,
+	}
+
+	s.wsMutex.Lock()
+	err = s.wsConn.WriteJSON(guildSubscribeOp{14, data})
+	s.wsMutex.Unlock()
+
+	return
+}
+
+// chunkGuilds requests all members of the given guilds, so that they are
+// added to State by the GuildMembersChunk events sent in response.
+func (s *Session) chunkGuilds(guildIDs []string) {
+	for _, id := range guildIDs {
+		if err := s.RequestGuildMembers(id, "", 0); err != nil {
+			s.log(LogError, "error requesting members of guild %s, %s", id, err)
+			if err == ErrWSNotFound {
+				return
+			}
+		}
+	}
+}
+
 // onEvent is the "event handler" for all messages received on the
 // Discord Gateway API websocket connection.
 //
@@ -505,9 +837,29 @@ func (s *Session) onEvent(messageType int, message []byte) (*Event, error) {
 	}
 
 	// Invalid Session
-	// Must respond with a Identify packet.
+	// Must respond with a Resume packet if the session is resumable, or an
+	// Identify packet otherwise.
 	if e.Operation == 9 {
 
+		// A resumable session is resumed. Otherwise it is forgotten, so that
+		// a new session is identified rather than resumed on the next
+		// reconnect.
+		var resumable bool
+		json.Unmarshal(e.RawData, &resumable)
+		if resumable && s.sessionID != "" {
+			s.log(LogInformational, "sending resume packet to gateway in response to Op9")
+
+			err = s.resume(atomic.LoadInt64(s.sequence))
+			if err != nil {
+				s.log(LogWarning, "error sending gateway resume packet, %s, %s", s.gateway, err)
+				return e, err
+			}
+
+			return e, nil
+		}
+		s.sessionID = ""
+		atomic.StoreInt64(s.sequence, 0)
+
 		s.log(LogInformational, "sending identify packet to gateway in response to Op9")
 
 		err = s.identify()
@@ -542,6 +894,10 @@ func (s *Session) onEvent(messageType int, message []byte) (*Event, error) {
 
 	// Store the message sequence
 	atomic.StoreInt64(s.sequence, e.Sequence)
+	countKey(&s.stats.events, e.Type)
+
+	// Raw handlers see the dispatch before it is decoded.
+	s.handleRaw(e)
 
 	// Map event to registered event handlers and pass it along to any registered handlers.
 	if eh, ok := registeredInterfaceProviders[e.Type]; ok {
@@ -559,13 +915,13 @@ func (s *Session) onEvent(messageType int, message []byte) (*Event, error) {
 		// it's better to pass along what we received than nothing at all.
 		// TODO: Think about that decision :)
 		// Either way, READY events must fire, even with errors.
-		s.handleEvent(e.Type, e.Struct)
+		s.queueEvent(e.Type, e.Struct)
 	} else {
 		s.log(LogWarning, "unknown event: Op: %d, Seq: %d, Type: %s, Data: %s", e.Operation, e.Sequence, e.Type, string(e.RawData))
 	}
 
 	// For legacy reasons, we send the raw event also, this could be useful for handling unknown events.
-	s.handleEvent(eventEventType, e)
+	s.queueEvent(eventEventType, e)
 
 	return e, nil
 }
@@ -675,8 +1031,9 @@ func (s *Session) onVoiceStateUpdate(st *VoiceStateUpdate) {
 		return
 	}
 
-	// We only care about events that are about us.
-	if s.State.User.ID != st.UserID {
+	// We only care about events that are about us. State may have been
+	// unset, or not have received READY yet.
+	if s.State == nil || s.State.User == nil || s.State.User.ID != st.UserID {
 		return
 	}
 
@@ -758,7 +1115,7 @@ func (s *Session) identify() error {
 	data := identifyData{s.Token,
 		properties,
 		250,
-		s.Compress,
+		s.Compress && !s.TransportCompress,
 		nil,
 	}
 
@@ -780,6 +1137,23 @@ func (s *Session) identify() error {
 	return err
 }
 
+// resume sends an Op 6 Resume packet for the current session, replaying
+// the events after the given sequence.
+func (s *Session) resume(sequence int64) error {
+
+	p := resumePacket{}
+	p.Op = 6
+	p.Data.Token = s.Token
+	p.Data.SessionID = s.sessionID
+	p.Data.Sequence = sequence
+
+	s.wsMutex.Lock()
+	err := s.wsConn.WriteJSON(p)
+	s.wsMutex.Unlock()
+
+	return err
+}
+
 func (s *Session) reconnect() {
 
 	s.log(LogInformational, "called")
@@ -788,7 +1162,7 @@ func (s *Session) reconnect() {
 
 	if s.ShouldReconnectOnError {
 
-		wait := time.Duration(1)
+		b := s.reconnectBackoff()
 
 		for {
 			s.log(LogInformational, "trying to reconnect to gateway")
@@ -796,6 +1170,7 @@ func (s *Session) reconnect() {
 			err = s.Open()
 			if err == nil {
 				s.log(LogInformational, "successfully reconnected to gateway")
+				atomic.AddInt64(&s.stats.reconnects, 1)
 
 				// I'm not sure if this is actually needed.
 				// if the gw reconnect works properly, voice should stay alive
@@ -824,20 +1199,79 @@ func (s *Session) reconnect() {
 				return
 			}
 
+			if fatal, ok := fatalCloseError(err); ok {
+				s.log(LogError, "not reconnecting to gateway, %s", fatal)
+				return
+			}
+
 			s.log(LogError, "error reconnecting to gateway, %s", err)
 
-			<-time.After(wait * time.Second)
-			wait *= 2
-			if wait > 600 {
-				wait = 600
-			}
+			<-time.After(b.next())
 		}
 	}
 }
 
+// backoff computes the delays between reconnect attempts.
+type backoff struct {
+	delay      time.Duration
+	max        time.Duration
+	multiplier float64
+	jitter     float64
+
+	// returns a random number in [0, 1)
+	random func() float64
+}
+
+// reconnectBackoff returns the backoff for a reconnect loop, from the
+// reconnect settings of the session.
+func (s *Session) reconnectBackoff() *backoff {
+	b := &backoff{
+		delay:      s.ReconnectInitialBackoff,
+		max:        s.ReconnectMaxBackoff,
+		multiplier: s.ReconnectBackoffMultiplier,
+		jitter:     s.ReconnectJitter,
+		random:     rand.Float64,
+	}
+	if b.delay <= 0 {
+		b.delay = time.Second
+	}
+	if b.max <= 0 {
+		b.max = 10 * time.Minute
+	}
+	if b.multiplier < 1 {
+		b.multiplier = 2
+	}
+	return b
+}
+
+// next returns the delay before the next attempt, and grows the delay for
+// the attempt after it.
+func (b *backoff) next() time.Duration {
+	d := b.delay
+	if d > b.max {
+		d = b.max
+	}
+
+	b.delay = time.Duration(float64(d) * b.multiplier)
+	if b.delay > b.max {
+		b.delay = b.max
+	}
+
+	if b.jitter > 0 {
+		d += time.Duration(float64(d) * b.jitter * (2*b.random() - 1))
+	}
+	return d
+}
+
 // Close closes a websocket and stops all listening/heartbeat goroutines.
 // TODO: Add support for Voice WS/UDP connections
 func (s *Session) Close() (err error) {
+	return s.close(0)
+}
+
+// close is Close, with the close code of the connection set on the
+// Disconnect event.
+func (s *Session) close(code int) (err error) {
 
 	s.log(LogInformational, "called")
 	s.Lock()
@@ -880,7 +1314,164 @@ func (s *Session) Close() (err error) {
 	s.Unlock()
 
 	s.log(LogInformational, "emit disconnect event")
-	s.handleEvent(disconnectEventType, &Disconnect{})
+	s.handleEvent(disconnectEventType, &Disconnect{Code: code})
+
+	return
+}
+
+// Shutdown gracefully closes the session. It stops dispatching new events,
+// including the final Disconnect event, then waits for running event
+// handlers and REST requests to finish before closing the websocket.
+// If ctx expires first, the websocket is closed anyway and the error of
+// ctx is returned.
+//
+// An event handler may call Shutdown, which then waits for the other
+// handlers, but not for the handler calling it.
+func (s *Session) Shutdown(ctx context.Context) (err error) {
+	s.log(LogInformational, "called")
+
+	s.active.shutdown()
+
+	held, worker := heldActive()
+
+	select {
+	case <-s.active.idle(held):
+	case <-ctx.Done():
+		err = ctx.Err()
+		s.log(LogWarning, "shutting down before handlers and requests finished, %s", err)
+	}
+
+	s.stopEventWorkers(ctx, worker)
 
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
 	return
 }
+
+// activeCounter counts the event handlers and REST requests of a session
+// which are still running.
+type activeCounter struct {
+	sync.Mutex
+	n       int
+	waiting []activeWaiter
+
+	// set by shutdown, to stop dispatching events
+	closed bool
+}
+
+// add marks the start of a handler or request.
+func (c *activeCounter) add() {
+	c.Lock()
+	c.n++
+	c.Unlock()
+}
+
+// start marks the start of the dispatch of an event, unless the session is
+// shutting down, in which case false is returned and the event must be
+// dropped.
+func (c *activeCounter) start() bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.closed {
+		return false
+	}
+	c.n++
+	return true
+}
+
+// shutdown stops the dispatch of events, before waiting for idle.
+func (c *activeCounter) shutdown() {
+	c.Lock()
+	c.closed = true
+	c.Unlock()
+}
+
+// reopen dispatches events again after shutdown.
+func (c *activeCounter) reopen() {
+	c.Lock()
+	c.closed = false
+	c.Unlock()
+}
+
+// done marks the end of a handler or request.
+func (c *activeCounter) done() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.n--
+	waiting := c.waiting[:0]
+	for _, w := range c.waiting {
+		if c.n <= w.n {
+			close(w.idle)
+		} else {
+			waiting = append(waiting, w)
+		}
+	}
+	c.waiting = waiting
+}
+
+// activeWaiter waits for an activeCounter to count at most n.
+type activeWaiter struct {
+	n    int
+	idle chan struct{}
+}
+
+// idle returns a channel which is closed once nothing is running but the
+// held handlers and dispatches of the caller.
+func (c *activeCounter) idle(held int) <-chan struct{} {
+	c.Lock()
+	defer c.Unlock()
+
+	idle := make(chan struct{})
+	if c.n <= held {
+		close(idle)
+		return idle
+	}
+
+	c.waiting = append(c.waiting, activeWaiter{held, idle})
+	return idle
+}
+
+// activeFrames are the functions which are counted by activeCounter while
+// they run, e.g. a handler, so that a goroutine running them can find out
+// how much of the count it holds itself.
+var activeFrames = func() map[string]bool {
+	prefix := reflect.TypeOf(Session{}).PkgPath() + ".(*Session)."
+	return map[string]bool{
+		prefix + "handleRaw":   true,
+		prefix + "dispatch":    true,
+		prefix + "runHandler":  true,
+		prefix + "eventWorker": true,
+	}
+}()
+
+// heldActive returns how much of the count of running handlers and
+// dispatches is held by the calling goroutine, e.g. when a handler calls
+// Shutdown, and whether it is an event handler worker.
+func heldActive() (held int, worker bool) {
+	pc := make([]uintptr, 64)
+	for {
+		n := runtime.Callers(2, pc)
+		if n < len(pc) {
+			pc = pc[:n]
+			break
+		}
+		pc = make([]uintptr, 2*len(pc))
+	}
+
+	frames := runtime.CallersFrames(pc)
+	for {
+		frame, more := frames.Next()
+		if activeFrames[frame.Function] {
+			held++
+			if strings.HasSuffix(frame.Function, ".eventWorker") {
+				worker = true
+			}
+		}
+		if !more {
+			return
+		}
+	}
+}
@@ -0,0 +1,1587 @@
+package discordgo
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testGateway is a fake Discord gateway. Every websocket connection made to
+// it is passed to handler.
+type testGateway struct {
+	*httptest.Server
+}
+
+func newTestGateway(t *testing.T, handler func(conn *websocket.Conn, r *http.Request)) *testGateway {
+	upgrader := websocket.Upgrader{}
+
+	return &testGateway{httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("error upgrading gateway connection, %s", err)
+			return
+		}
+		defer conn.Close()
+
+		handler(conn, r)
+	}))}
+}
+
+// URL returns the websocket address of the gateway.
+func (g *testGateway) URL() string {
+	return "ws" + strings.TrimPrefix(g.Server.URL, "http") + "/"
+}
+
+// testGatewayOp is an op as read by the fake gateway.
+type testGatewayOp struct {
+	Op   int             `json:"op"`
+	Data json.RawMessage `json:"d"`
+}
+
+// readOp reads the next op sent by the session, skipping heartbeats.
+func readOp(conn *websocket.Conn) (op testGatewayOp, err error) {
+	for {
+		if err = conn.ReadJSON(&op); err != nil || op.Op != 1 {
+			return
+		}
+	}
+}
+
+// hello returns a Hello payload with the given heartbeat interval.
+func hello(interval int) []byte {
+	return []byte(fmt.Sprintf(`{"op":10,"d":{"heartbeat_interval":%d}}`, interval))
+}
+
+// dispatch returns a dispatch payload for the event t.
+func dispatch(seq int, t string, data string) []byte {
+	return []byte(fmt.Sprintf(`{"op":0,"s":%d,"t":%q,"d":%s}`, seq, t, data))
+}
+
+func TestZlibStream(t *testing.T) {
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+
+	messages := []string{
+		`{"op":10,"d":{"heartbeat_interval":41250}}`,
+		`{"op":0,"s":1,"t":"READY","d":{"session_id":"abc"}}`,
+		`{"op":0,"s":2,"t":"READY","d":{"session_id":"abc"}}`,
+	}
+
+	var frames [][]byte
+	for _, m := range messages {
+		w.Write([]byte(m))
+		w.Flush()
+		frames = append(frames, append([]byte(nil), compressed.Bytes()...))
+		compressed.Reset()
+	}
+
+	zs := &zlibStream{}
+
+	// The first message arrives in one frame.
+	m, ok, err := zs.decompress(frames[0])
+	if err != nil || !ok || string(m) != messages[0] {
+		t.Fatalf("decompress(frame 0) = %q, %t, %v", m, ok, err)
+	}
+
+	// The second message is split across three reads.
+	split := frames[1]
+	for _, part := range [][]byte{split[:3], split[3 : len(split)-2]} {
+		m, ok, err = zs.decompress(part)
+		if err != nil || ok {
+			t.Fatalf("decompress(partial frame) = %q, %t, %v", m, ok, err)
+		}
+	}
+	m, ok, err = zs.decompress(split[len(split)-2:])
+	if err != nil || !ok || string(m) != messages[1] {
+		t.Fatalf("decompress(frame 1) = %q, %t, %v", m, ok, err)
+	}
+
+	// The third message repeats the second, so it is compressed with
+	// back references into the data inflated before it.
+	m, ok, err = zs.decompress(frames[2])
+	if err != nil || !ok || string(m) != messages[2] {
+		t.Fatalf("decompress(frame 2) = %q, %t, %v", m, ok, err)
+	}
+}
+
+func TestZlibStreamBadHeader(t *testing.T) {
+	zs := &zlibStream{}
+	if _, _, err := zs.decompress(append([]byte{0x12, 0x34}, zlibSuffix...)); err != zlib.ErrHeader {
+		t.Errorf("decompress() returned %v, expected zlib.ErrHeader", err)
+	}
+}
+
+func TestOpenTransportCompress(t *testing.T) {
+	gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+		if r.URL.Query().Get("compress") != "zlib-stream" {
+			t.Errorf("gateway URL %q does not request zlib-stream", r.URL)
+		}
+
+		var compressed bytes.Buffer
+		w := zlib.NewWriter(&compressed)
+		send := func(p []byte, split bool) {
+			w.Write(p)
+			w.Flush()
+			frame := compressed.Bytes()
+			if split {
+				conn.WriteMessage(websocket.BinaryMessage, frame[:len(frame)/2])
+				frame = frame[len(frame)/2:]
+			}
+			conn.WriteMessage(websocket.BinaryMessage, frame)
+			compressed.Reset()
+		}
+
+		send(hello(45000), false)
+
+		op, err := readOp(conn)
+		if err != nil || op.Op != 2 {
+			t.Errorf("expected identify, got %d, %v", op.Op, err)
+			return
+		}
+		var identify identifyData
+		json.Unmarshal(op.Data, &identify)
+		if identify.Compress {
+			t.Error("identify requested payload compression with zlib-stream enabled")
+		}
+
+		send(dispatch(1, "READY", `{"session_id":"session","user":{"id":"bot"}}`), true)
+
+		conn.ReadMessage()
+	})
+	defer gw.Close()
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+	})
+	s.TransportCompress = true
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open() returned error: %+v", err)
+	}
+	defer s.Close()
+
+	if s.State.SessionID != "session" {
+		t.Errorf("READY was not inflated, session ID is %q", s.State.SessionID)
+	}
+}
+
+func TestShutdownWaitsForHandlers(t *testing.T) {
+	s, _ := New()
+
+	finished := make(chan struct{})
+	s.AddHandler(func(s *Session, m *MessageCreate) {
+		time.Sleep(100 * time.Millisecond)
+		close(finished)
+	})
+
+	called := make(chan struct{}, 1)
+	s.AddHandler(func(s *Session, m *MessageDelete) {
+		called <- struct{}{}
+	})
+
+	s.handleEvent(messageCreateEventType, &MessageCreate{&Message{}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown returned error: %+v", err)
+	}
+
+	select {
+	case <-finished:
+	default:
+		t.Error("Shutdown returned before the running handler finished")
+	}
+
+	// Events are no longer dispatched once shutting down.
+	s.handleEvent(messageDeleteEventType, &MessageDelete{&Message{}})
+	select {
+	case <-called:
+		t.Error("handler was called after Shutdown")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	s, _ := New()
+
+	release := make(chan struct{})
+	defer close(release)
+	s.AddHandler(func(s *Session, m *MessageCreate) {
+		<-release
+	})
+
+	s.handleEvent(messageCreateEventType, &MessageCreate{&Message{}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown returned %v, expected context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Shutdown took %s, expected it to stop at the deadline", elapsed)
+	}
+}
+
+func TestShutdownFromHandler(t *testing.T) {
+	modes := map[string]func(s *Session){
+		"async":   func(s *Session) {},
+		"sync":    func(s *Session) { s.SyncEvents = true },
+		"workers": func(s *Session) { s.EventHandlerWorkers = 2 },
+	}
+	for name, mode := range modes {
+		s, _ := New()
+		mode(s)
+
+		// The handler calling Shutdown does not wait for itself.
+		returned := make(chan error, 1)
+		s.AddHandler(func(s *Session, m *MessageCreate) {
+			returned <- s.Shutdown(context.Background())
+		})
+		s.queueEvent(messageCreateEventType, &MessageCreate{&Message{}})
+
+		select {
+		case err := <-returned:
+			if err != nil {
+				t.Errorf("Shutdown returned error in a %s handler: %+v", name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Shutdown did not return in a %s handler", name)
+		}
+	}
+
+	// It still waits for the other handlers.
+	s, _ := New()
+	release := make(chan struct{})
+	var finished int32
+	s.AddHandler(func(s *Session, m *MessageDelete) {
+		<-release
+		atomic.StoreInt32(&finished, 1)
+	})
+	s.handleEvent(messageDeleteEventType, &MessageDelete{&Message{}})
+
+	returned := make(chan error, 1)
+	s.AddHandler(func(s *Session, m *MessageCreate) {
+		returned <- s.Shutdown(context.Background())
+	})
+	s.handleEvent(messageCreateEventType, &MessageCreate{&Message{}})
+
+	select {
+	case err := <-returned:
+		t.Fatalf("Shutdown returned %v before the other handler finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case err := <-returned:
+		if err != nil {
+			t.Errorf("Shutdown returned error: %+v", err)
+		}
+		if atomic.LoadInt32(&finished) != 1 {
+			t.Error("Shutdown returned before the other handler finished")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not return in a handler")
+	}
+}
+
+func TestShutdownWaitsForGuildLeave(t *testing.T) {
+	var left int32
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&left, 1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.GuildAllowlist = []string{"allowed"}
+
+	s.handleEvent(guildCreateEventType, &GuildCreate{&Guild{ID: "disallowed"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown returned error: %+v", err)
+	}
+	if atomic.LoadInt32(&left) != 1 {
+		t.Error("Shutdown returned before the disallowed guild was left")
+	}
+}
+
+func TestShutdownDuringDispatch(t *testing.T) {
+	s, _ := New()
+
+	var running, afterShutdown int32
+	var shutDown int32
+	s.AddHandler(func(s *Session, m *MessageCreate) {
+		if atomic.LoadInt32(&shutDown) == 1 {
+			atomic.StoreInt32(&afterShutdown, 1)
+		}
+		atomic.AddInt32(&running, 1)
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	})
+
+	stop := make(chan struct{})
+	var dispatching sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		dispatching.Add(1)
+		go func() {
+			defer dispatching.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					s.handleEvent(messageCreateEventType, &MessageCreate{&Message{}})
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		dispatching.Wait()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown returned error: %+v", err)
+	}
+	atomic.StoreInt32(&shutDown, 1)
+
+	if n := atomic.LoadInt32(&running); n != 0 {
+		t.Errorf("%d handlers were running after Shutdown returned", n)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if atomic.LoadInt32(&afterShutdown) == 1 {
+		t.Error("a handler was started after Shutdown returned")
+	}
+}
+
+func TestShutdownStopsEventWorkers(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		s, _ := New()
+		s.EventHandlerWorkers = 3
+		s.EventQueueSize = 10
+		s.OrderedEvents = ordered
+
+		handled := make(chan struct{}, 1)
+		s.AddHandler(func(s *Session, m *MessageCreate) {
+			handled <- struct{}{}
+		})
+
+		s.queueEvent(messageCreateEventType, &MessageCreate{&Message{ChannelID: "channel"}})
+		<-handled
+
+		s.queue.Lock()
+		running := s.queue.running
+		s.queue.Unlock()
+
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown returned error: %+v", err)
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			running.Wait()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatalf("with ordered events %t, the workers were not stopped by Shutdown", ordered)
+		}
+
+		// Queued events are dropped until the session is opened again.
+		s.queueEvent(messageCreateEventType, &MessageCreate{&Message{}})
+		s.queue.Lock()
+		restarted := s.queue.stop != nil
+		s.queue.Unlock()
+		if restarted {
+			t.Errorf("with ordered events %t, the workers were started again after Shutdown", ordered)
+		}
+	}
+}
+
+func TestEventHandlerWorkers(t *testing.T) {
+	s, _ := New()
+	s.SyncEvents = true
+	s.EventHandlerWorkers = 1
+	s.EventQueueSize = 10
+
+	release := make(chan struct{})
+	handled := make(chan string, 2)
+	s.AddHandler(func(s *Session, m *MessageCreate) {
+		<-release
+		handled <- m.ID
+	})
+
+	read := make(chan struct{})
+	go func() {
+		defer close(read)
+		for _, payload := range []string{
+			`{"op":0,"s":1,"t":"MESSAGE_CREATE","d":{"id":"first"}}`,
+			`{"op":0,"s":2,"t":"MESSAGE_CREATE","d":{"id":"second"}}`,
+			`{"op":11}`,
+		} {
+			if _, err := s.onEvent(websocket.TextMessage, []byte(payload)); err != nil {
+				t.Errorf("onEvent returned error: %+v", err)
+			}
+		}
+	}()
+
+	// The heartbeat ACK is processed while the handler is still blocked.
+	select {
+	case <-read:
+	case <-time.After(time.Second):
+		t.Fatal("gateway events were not read while a handler was blocked")
+	}
+	s.RLock()
+	acked := !s.LastHeartbeatAck.IsZero()
+	s.RUnlock()
+	if !acked {
+		t.Error("heartbeat ACK was not processed while a handler was blocked")
+	}
+
+	close(release)
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case id := <-handled:
+			if id != expected {
+				t.Errorf("handled %s, expected %s", id, expected)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %s was not handled", expected)
+		}
+	}
+}
+
+func TestOrderedEvents(t *testing.T) {
+	s, _ := New()
+	s.EventHandlerWorkers = 4
+	s.EventQueueSize = 10
+	s.OrderedEvents = true
+
+	// Find a second channel handled by a different worker than the first.
+	other := "b"
+	for i := 0; eventWorkerIndex(other, 4) == eventWorkerIndex("a", 4); i++ {
+		other = "b" + strconv.Itoa(i)
+	}
+
+	otherHandled := make(chan struct{})
+	handled := make(chan struct{}, 3)
+	var running int32
+	var order []string
+	s.AddHandler(func(s *Session, m *MessageCreate) {
+		if m.ChannelID == other {
+			close(otherHandled)
+			return
+		}
+
+		if atomic.AddInt32(&running, 1) != 1 {
+			t.Error("events in the same channel were handled at the same time")
+		}
+		defer atomic.AddInt32(&running, -1)
+
+		// The first event in the channel blocks until the other channel,
+		// queued after it, has been handled.
+		if m.ID == "a1" {
+			select {
+			case <-otherHandled:
+			case <-time.After(time.Second):
+				t.Error("an event in another channel was held up by a slow handler")
+			}
+		}
+		order = append(order, m.ID)
+		handled <- struct{}{}
+	})
+
+	for i, payload := range []string{
+		`{"id":"a1","channel_id":"a"}`,
+		`{"id":"a2","channel_id":"a"}`,
+		`{"id":"b1","channel_id":"` + other + `"}`,
+		`{"id":"a3","channel_id":"a"}`,
+	} {
+		if _, err := s.onEvent(websocket.TextMessage, dispatch(i+1, "MESSAGE_CREATE", payload)); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-handled:
+		case <-time.After(2 * time.Second):
+			t.Fatal("events were not handled")
+		}
+	}
+
+	if strings.Join(order, ",") != "a1,a2,a3" {
+		t.Errorf("events in the same channel were handled in the order %v", order)
+	}
+}
+
+func TestEventWorkersStateOrder(t *testing.T) {
+	s, _ := New()
+	s.EventHandlerWorkers = 4
+	s.EventQueueSize = 100
+	s.State.GuildAdd(&Guild{ID: "guild"})
+
+	// The workers are held up by the handlers, and call them in any order.
+	release := make(chan struct{})
+	s.AddHandler(func(s *Session, m *GuildMemberAdd) {
+		<-release
+	})
+
+	// State is updated in the order the events are received, before their
+	// handlers are called.
+	seq := 0
+	for i := 0; i < 20; i++ {
+		id := strconv.Itoa(i)
+		member := `{"guild_id":"guild","user":{"id":"` + id + `"}}`
+		for _, e := range []struct {
+			t     string
+			added bool
+		}{
+			{"GUILD_MEMBER_ADD", true},
+			{"GUILD_MEMBER_REMOVE", false},
+		} {
+			seq++
+			if _, err := s.onEvent(websocket.TextMessage, dispatch(seq, e.t, member)); err != nil {
+				t.Fatalf("onEvent returned error: %+v", err)
+			}
+			if _, err := s.State.Member("guild", id); (err == nil) != e.added {
+				t.Fatalf("member %s in State after %s: %v", id, e.t, err)
+			}
+		}
+	}
+
+	g, err := s.State.Guild("guild")
+	if err != nil {
+		t.Fatalf("State.Guild returned error: %+v", err)
+	}
+	s.State.RLock()
+	members := len(g.Members)
+	s.State.RUnlock()
+	if members != 0 {
+		t.Errorf("guild in State has %d members, expected every added member to be removed", members)
+	}
+
+	close(release)
+	s.Shutdown(context.Background())
+}
+
+func TestOrderedGuildEvents(t *testing.T) {
+	s, _ := New()
+	s.EventHandlerWorkers = 4
+	s.EventQueueSize = 10
+	s.OrderedEvents = true
+
+	// Find another guild handled by the same worker as the guild, and a
+	// channel which would be handled by a different one.
+	other, channel := "o", "c"
+	for i := 0; eventWorkerIndex(other, 4) != eventWorkerIndex("guild", 4); i++ {
+		other = "o" + strconv.Itoa(i)
+	}
+	for i := 0; eventWorkerIndex(channel, 4) == eventWorkerIndex("guild", 4); i++ {
+		channel = "c" + strconv.Itoa(i)
+	}
+
+	// The worker of the guild is held up by the other guild.
+	release := make(chan struct{})
+	s.AddHandler(func(s *Session, g *GuildCreate) {
+		if g.ID == other {
+			<-release
+		}
+	})
+	handled := make(chan struct{})
+	s.AddHandler(func(s *Session, c *ChannelCreate) {
+		close(handled)
+	})
+
+	for i, e := range []struct{ t, data string }{
+		{"READY", `{"v":6,"session_id":"session","user":{"id":"bot"},"guilds":[{"id":"guild","unavailable":true},{"id":"` + other + `","unavailable":true}]}`},
+		{"GUILD_CREATE", `{"id":"` + other + `","name":"other"}`},
+		{"GUILD_CREATE", `{"id":"guild","name":"guild","channels":[{"id":"existing"}]}`},
+		{"CHANNEL_CREATE", `{"id":"` + channel + `","guild_id":"guild"}`},
+	} {
+		if _, err := s.onEvent(websocket.TextMessage, dispatch(i+1, e.t, e.data)); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+	}
+
+	// The channel is not created before its guild, however long the
+	// other guild takes.
+	select {
+	case <-handled:
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("ChannelCreate was not handled")
+	}
+	s.Shutdown(context.Background())
+
+	g, err := s.State.Guild("guild")
+	if err != nil {
+		t.Fatalf("State.Guild returned error: %+v", err)
+	}
+	if g.Name != "guild" || g.Unavailable {
+		t.Errorf("guild in State was %+v, expected the guild from GuildCreate", g)
+	}
+	if len(g.Channels) != 2 {
+		t.Errorf("guild in State had %d channels, expected 2", len(g.Channels))
+	}
+	if _, err := s.State.Channel(channel); err != nil {
+		t.Errorf("State.Channel returned error for the created channel: %+v", err)
+	}
+}
+
+func TestOrderedChannelEvents(t *testing.T) {
+	s, _ := New()
+	s.EventHandlerWorkers = 4
+	s.EventQueueSize = 10
+	s.OrderedEvents = true
+	s.State.GuildAdd(&Guild{ID: "guild"})
+
+	// Find a channel which would be handled by a different worker than
+	// its guild.
+	channel := "c"
+	for i := 0; eventWorkerIndex(channel, 4) == eventWorkerIndex("guild", 4); i++ {
+		channel = "c" + strconv.Itoa(i)
+	}
+
+	var created int32
+	s.AddHandler(func(s *Session, c *ChannelCreate) {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&created, 1)
+	})
+	messages := make(chan bool, 1)
+	s.AddHandler(func(s *Session, m *MessageCreate) {
+		messages <- atomic.LoadInt32(&created) == 1
+	})
+
+	for i, e := range []struct{ t, data string }{
+		{"CHANNEL_CREATE", `{"id":"` + channel + `","guild_id":"guild"}`},
+		{"MESSAGE_CREATE", `{"id":"message","channel_id":"` + channel + `","guild_id":"guild"}`},
+	} {
+		if _, err := s.onEvent(websocket.TextMessage, dispatch(i+1, e.t, e.data)); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+	}
+
+	select {
+	case afterCreate := <-messages:
+		if !afterCreate {
+			t.Error("a MessageCreate handler ran before the ChannelCreate handler of its channel returned")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("MessageCreate was not handled")
+	}
+	s.Shutdown(context.Background())
+}
+
+func TestSerializeReady(t *testing.T) {
+	for _, workers := range []int{0, 4} {
+		s, _ := New()
+		s.SerializeReady = true
+		s.EventHandlerWorkers = workers
+		s.EventQueueSize = 10
+
+		var ready int32
+		s.AddHandler(func(s *Session, r *Ready) {
+			time.Sleep(50 * time.Millisecond)
+			atomic.StoreInt32(&ready, 1)
+		})
+
+		guilds := make(chan bool, 2)
+		s.AddHandler(func(s *Session, g *GuildCreate) {
+			guilds <- atomic.LoadInt32(&ready) == 1
+		})
+
+		for i, e := range []struct{ t, data string }{
+			{"READY", `{"v":6,"session_id":"session","user":{"id":"bot"},"guilds":[{"id":"a","unavailable":true},{"id":"b","unavailable":true}]}`},
+			{"GUILD_CREATE", `{"id":"a","name":"a"}`},
+			{"GUILD_CREATE", `{"id":"b","name":"b"}`},
+		} {
+			if _, err := s.onEvent(websocket.TextMessage, dispatch(i+1, e.t, e.data)); err != nil {
+				t.Fatalf("onEvent returned error: %+v", err)
+			}
+		}
+
+		for i := 0; i < 2; i++ {
+			select {
+			case afterReady := <-guilds:
+				if !afterReady {
+					t.Errorf("with %d workers, a GuildCreate handler ran before the Ready handler returned", workers)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("with %d workers, GuildCreate was not handled", workers)
+			}
+		}
+	}
+}
+
+func TestReadyDispatchedBeforeQueued(t *testing.T) {
+	s, _ := New()
+	s.EventHandlerWorkers = 1
+	s.EventQueueSize = 10
+
+	// The worker is held up by the handler of an earlier event.
+	release := make(chan struct{})
+	s.AddHandler(func(s *Session, m *MessageCreate) {
+		<-release
+	})
+	handled := make(chan struct{})
+	s.AddHandler(func(s *Session, r *Ready) {
+		close(handled)
+	})
+
+	for i, e := range []struct{ t, data string }{
+		{"MESSAGE_CREATE", `{"id":"message","channel_id":"channel"}`},
+		{"READY", `{"v":6,"session_id":"session","user":{"id":"bot"},"guilds":[]}`},
+	} {
+		if _, err := s.onEvent(websocket.TextMessage, dispatch(i+1, e.t, e.data)); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+	}
+
+	// The session is set up before the Ready handlers are called.
+	if s.sessionID != "session" {
+		t.Errorf("session ID was %q before Ready was handled, expected session", s.sessionID)
+	}
+	if s.State.User == nil || s.State.User.ID != "bot" {
+		t.Errorf("State.User was %+v before Ready was handled, expected bot", s.State.User)
+	}
+
+	close(release)
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("Ready was not handled")
+	}
+	s.Shutdown(context.Background())
+}
+
+func TestHydrateReactions(t *testing.T) {
+	var requests []string
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		w.Write([]byte(`{"id":"fetched","channel_id":"channel","content":"from the API"}`))
+	})
+	s.SyncEvents = true
+	s.HydrateReactions = true
+	s.State.MaxMessageCount = 10
+	s.State.GuildAdd(&Guild{ID: "guild", Channels: []*Channel{{ID: "channel", GuildID: "guild"}}})
+	s.State.MessageAdd(&Message{ID: "cached", ChannelID: "channel", Content: "from State"})
+
+	var added []*Message
+	s.AddHandler(func(s *Session, r *MessageReactionAdd) {
+		added = append(added, r.Message)
+	})
+	var removed *Message
+	s.AddHandler(func(s *Session, r *MessageReactionRemove) {
+		removed = r.Message
+	})
+
+	reaction := `{"user_id":"user","channel_id":"channel","message_id":%q,"emoji":{"name":"👍"}}`
+	for i, e := range []struct{ t, message string }{
+		{"MESSAGE_REACTION_ADD", "cached"},
+		{"MESSAGE_REACTION_ADD", "fetched"},
+		{"MESSAGE_REACTION_REMOVE", "cached"},
+	} {
+		if _, err := s.onEvent(websocket.TextMessage, dispatch(i+1, e.t, fmt.Sprintf(reaction, e.message))); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+	}
+
+	if len(added) != 2 || added[0] == nil || added[0].Content != "from State" {
+		t.Fatalf("MessageReactionAdd messages were %+v, expected the cached message first", added)
+	}
+	if added[1] == nil || added[1].Content != "from the API" {
+		t.Errorf("MessageReactionAdd message was %+v, expected the fetched message", added[1])
+	}
+	if removed == nil || removed.ID != "cached" {
+		t.Errorf("MessageReactionRemove message was %+v, expected the cached message", removed)
+	}
+	if len(requests) != 1 || requests[0] != "/api/v6/channels/channel/messages/fetched" {
+		t.Errorf("requests were %q, expected only the uncached message to be fetched", requests)
+	}
+
+	s.HydrateReactions = false
+	added = nil
+	if _, err := s.onEvent(websocket.TextMessage, dispatch(4, "MESSAGE_REACTION_ADD", fmt.Sprintf(reaction, "cached"))); err != nil {
+		t.Fatalf("onEvent returned error: %+v", err)
+	}
+	if len(added) != 1 || added[0] != nil {
+		t.Errorf("MessageReactionAdd message was set without HydrateReactions")
+	}
+}
+
+func TestHydrateReactionsAsync(t *testing.T) {
+	for _, workers := range []int{0, 2} {
+		fetch := make(chan struct{})
+		s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+			<-fetch
+			w.Write([]byte(`{"id":"fetched","channel_id":"channel","content":"from the API"}`))
+		})
+		s.HydrateReactions = true
+		s.EventHandlerWorkers = workers
+
+		added := make(chan *Message, 1)
+		s.AddHandler(func(s *Session, r *MessageReactionAdd) {
+			added <- r.Message
+		})
+
+		// The event is handled while the message is still being fetched.
+		reaction := `{"user_id":"user","channel_id":"channel","message_id":"fetched","emoji":{"name":"👍"}}`
+		if _, err := s.onEvent(websocket.TextMessage, dispatch(1, "MESSAGE_REACTION_ADD", reaction)); err != nil {
+			t.Fatalf("onEvent returned error: %+v", err)
+		}
+		close(fetch)
+
+		select {
+		case m := <-added:
+			if m == nil || m.Content != "from the API" {
+				t.Errorf("MessageReactionAdd message was %+v with %d workers, expected the fetched message", m, workers)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("MessageReactionAdd handler was not called with %d workers", workers)
+		}
+		s.Shutdown(context.Background())
+	}
+}
+
+func TestHydrateReactionsCopy(t *testing.T) {
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	})
+	s.SyncEvents = true
+	s.HydrateReactions = true
+	s.State.MaxMessageCount = 10
+	s.State.GuildAdd(&Guild{ID: "guild", Channels: []*Channel{{ID: "channel", GuildID: "guild"}}})
+	s.State.MessageAdd(&Message{ID: "message", ChannelID: "channel", Content: "from State"})
+
+	var added *Message
+	s.AddHandler(func(s *Session, r *MessageReactionAdd) {
+		added = r.Message
+	})
+
+	reaction := `{"user_id":"user","channel_id":"channel","message_id":"message","emoji":{"name":"👍"}}`
+	if _, err := s.onEvent(websocket.TextMessage, dispatch(1, "MESSAGE_REACTION_ADD", reaction)); err != nil {
+		t.Fatalf("onEvent returned error: %+v", err)
+	}
+
+	// Later events update the message in State, not the one of the handler.
+	cached, err := s.State.Message("channel", "message")
+	if err != nil {
+		t.Fatalf("State.Message returned error: %+v", err)
+	}
+	if added == nil || added == cached || added.Content != "from State" {
+		t.Fatalf("MessageReactionAdd message was %+v, expected a copy of the cached message", added)
+	}
+	if _, err := s.onEvent(websocket.TextMessage, dispatch(2, "MESSAGE_UPDATE", `{"id":"message","channel_id":"channel","content":"edited"}`)); err != nil {
+		t.Fatalf("onEvent returned error: %+v", err)
+	}
+	if added.Content != "from State" {
+		t.Errorf("MessageReactionAdd message was changed to %q by a later event", added.Content)
+	}
+}
+
+func TestEventKey(t *testing.T) {
+	tests := []struct {
+		event interface{}
+		key   string
+	}{
+		{&MessageCreate{&Message{ChannelID: "channel"}}, "channel"},
+		{&MessageCreate{}, ""},
+		{&MessageCreate{&Message{ChannelID: "channel", GuildID: "guild"}}, "guild"},
+		{&MessageReactionAdd{MessageReaction: &MessageReaction{ChannelID: "channel"}}, "channel"},
+		{&MessageReactionAdd{MessageReaction: &MessageReaction{ChannelID: "channel", GuildID: "guild"}}, "guild"},
+		{&ChannelUpdate{&Channel{ID: "channel", GuildID: "guild"}}, "guild"},
+		{&ChannelCreate{&Channel{ID: "channel"}}, "channel"},
+		{&GuildMemberAdd{&Member{GuildID: "guild"}}, "guild"},
+		{&GuildRoleDelete{GuildID: "guild"}, "guild"},
+		{&Event{Struct: &TypingStart{ChannelID: "channel"}}, "channel"},
+		{&Ready{}, ""},
+	}
+
+	for _, tt := range tests {
+		if key := eventKey(tt.event); key != tt.key {
+			t.Errorf("eventKey(%T) = %q, expected %q", tt.event, key, tt.key)
+		}
+	}
+}
+
+func TestGuildAllowlist(t *testing.T) {
+	gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+		conn.WriteMessage(websocket.TextMessage, hello(45000))
+
+		if op, err := readOp(conn); err != nil || op.Op != 2 {
+			t.Errorf("expected identify, got %d, %v", op.Op, err)
+			return
+		}
+
+		conn.WriteMessage(websocket.TextMessage, dispatch(1, "READY", `{"session_id":"session","user":{"id":"bot"}}`))
+		conn.WriteMessage(websocket.TextMessage, dispatch(2, "GUILD_CREATE", `{"id":"allowed"}`))
+		conn.WriteMessage(websocket.TextMessage, dispatch(3, "GUILD_CREATE", `{"id":"disallowed"}`))
+
+		readOp(conn)
+	})
+	defer gw.Close()
+
+	left := make(chan string, 2)
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			left <- r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+	})
+	s.GuildAllowlist = []string{"allowed"}
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open() returned error: %+v", err)
+	}
+	defer s.Close()
+
+	select {
+	case path := <-left:
+		if path != "/api/v6/users/@me/guilds/disallowed" {
+			t.Errorf("left %s, expected the disallowed guild", path)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the disallowed guild was not left")
+	}
+
+	select {
+	case path := <-left:
+		t.Errorf("left %s, expected only the disallowed guild to be left", path)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	s := &Session{
+		ReconnectInitialBackoff:    500 * time.Millisecond,
+		ReconnectMaxBackoff:        5 * time.Second,
+		ReconnectBackoffMultiplier: 3,
+	}
+
+	b := s.reconnectBackoff()
+	expected := []time.Duration{
+		500 * time.Millisecond,
+		1500 * time.Millisecond,
+		4500 * time.Millisecond,
+		5 * time.Second,
+		5 * time.Second,
+	}
+	for i, e := range expected {
+		if d := b.next(); d != e {
+			t.Errorf("delay %d is %s, expected %s", i, d, e)
+		}
+	}
+
+	// Jitter spreads each delay around its base delay.
+	s.ReconnectJitter = 0.2
+	for _, r := range []struct {
+		random   float64
+		expected time.Duration
+	}{
+		{0, 400 * time.Millisecond},
+		{0.5, 500 * time.Millisecond},
+		{0.75, 550 * time.Millisecond},
+	} {
+		b = s.reconnectBackoff()
+		b.random = func() float64 { return r.random }
+		if d := b.next(); d != r.expected {
+			t.Errorf("delay with random %v is %s, expected %s", r.random, d, r.expected)
+		}
+	}
+
+	// The defaults match the previously hardcoded backoff.
+	b = (&Session{}).reconnectBackoff()
+	for _, e := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if d := b.next(); d != e {
+			t.Errorf("default delay is %s, expected %s", d, e)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		b.next()
+	}
+	if d := b.next(); d != 10*time.Minute {
+		t.Errorf("default max delay is %s, expected 10m", d)
+	}
+}
+
+func TestGatewayUserAgent(t *testing.T) {
+	agent := make(chan string, 1)
+	gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+		agent <- r.Header.Get("User-Agent")
+		conn.WriteMessage(websocket.TextMessage, hello(45000))
+		readOp(conn)
+		conn.WriteMessage(websocket.TextMessage, dispatch(1, "READY", `{"session_id":"session","user":{"id":"bot"}}`))
+		readOp(conn)
+	})
+	defer gw.Close()
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+	})
+	s.UserAgent = "DiscordBot (https://example.com/mybot, 1.2.3)"
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open() returned error: %+v", err)
+	}
+	defer s.Close()
+
+	if a := <-agent; a != s.UserAgent {
+		t.Errorf("gateway handshake User-Agent is %q, expected %q", a, s.UserAgent)
+	}
+}
+
+func TestGatewayDialer(t *testing.T) {
+	gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+		conn.WriteMessage(websocket.TextMessage, hello(45000))
+		readOp(conn)
+		conn.WriteMessage(websocket.TextMessage, dispatch(1, "READY", `{"session_id":"session","user":{"id":"bot"}}`))
+		readOp(conn)
+	})
+	defer gw.Close()
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+	})
+
+	var dialed []string
+	s.Dialer = &websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			dialed = append(dialed, addr)
+			return net.Dial(network, addr)
+		},
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open() returned error: %+v", err)
+	}
+	defer s.Close()
+
+	if len(dialed) != 1 || dialed[0] != strings.TrimPrefix(gw.Server.URL, "http://") {
+		t.Errorf("dialer dialed %q, expected the gateway", dialed)
+	}
+}
+
+func TestOpenDialTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening, %s", err)
+	}
+	defer ln.Close()
+
+	// Connections are accepted, but the websocket upgrade is never answered.
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, c := range conns {
+				c.Close()
+			}
+		}()
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, c)
+		}
+	}()
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"ws://` + ln.Addr().String() + `"}`))
+	})
+	s.DialTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	if err := s.Open(); err != ErrGatewayTimeout {
+		s.Close()
+		t.Fatalf("Open() returned %v, expected ErrGatewayTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Open() returned after %s, expected it to time out after 100ms", elapsed)
+	}
+}
+
+func TestOpenHandshakeTimeout(t *testing.T) {
+	for _, sendHello := range []bool{false, true} {
+		sendHello := sendHello
+		gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+			if sendHello {
+				conn.WriteMessage(websocket.TextMessage, hello(45000))
+			}
+			// READY is never sent.
+			for {
+				if _, err := readOp(conn); err != nil {
+					return
+				}
+			}
+		})
+
+		s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+		})
+		s.HandshakeTimeout = 100 * time.Millisecond
+
+		start := time.Now()
+		err := s.Open()
+		elapsed := time.Since(start)
+		gw.Close()
+
+		if err != ErrGatewayTimeout {
+			s.Close()
+			t.Fatalf("with hello sent %t, Open() returned %v, expected ErrGatewayTimeout", sendHello, err)
+		}
+		if elapsed > time.Second {
+			t.Errorf("with hello sent %t, Open() returned after %s, expected it to time out after 100ms", sendHello, elapsed)
+		}
+	}
+}
+
+func TestGatewayCloseFatal(t *testing.T) {
+	for _, code := range []int{GatewayCloseAuthenticationFailed, GatewayCloseInvalidIntents, GatewayCloseDisallowedIntents} {
+		code := code
+		gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+			conn.WriteMessage(websocket.TextMessage, hello(45000))
+			readOp(conn)
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, "misconfigured"))
+			readOp(conn)
+		})
+
+		s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+		})
+
+		err := s.Open()
+		gw.Close()
+
+		closeErr, ok := err.(*GatewayCloseError)
+		if !ok || closeErr.Code != code || closeErr.Text != "misconfigured" {
+			s.Close()
+			t.Fatalf("Open() returned %v, expected a GatewayCloseError with code %d", err, code)
+		}
+		if !closeErr.Fatal() {
+			t.Errorf("close code %d is not fatal", code)
+		}
+	}
+
+	if (&GatewayCloseError{Code: GatewayCloseSessionTimedOut}).Fatal() {
+		t.Error("close code 4009 is fatal, expected the session to reconnect")
+	}
+}
+
+func TestGatewayCloseStopsReconnect(t *testing.T) {
+	tests := []struct {
+		name string
+		// the close codes sent on each connection, after READY or RESUMED
+		// if ready is set, or else right after IDENTIFY or RESUME
+		codes []int
+		ready bool
+	}{
+		{"connected", []int{GatewayCloseDisallowedIntents}, true},
+		{"reconnecting", []int{GatewayCloseUnknownError, GatewayCloseAuthenticationFailed}, false},
+	}
+
+	for _, test := range tests {
+		test := test
+		var connections int32
+		gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+			n := int(atomic.AddInt32(&connections, 1))
+			if n > len(test.codes) {
+				return
+			}
+
+			conn.WriteMessage(websocket.TextMessage, hello(45000))
+			readOp(conn)
+			if test.ready || n == 1 {
+				conn.WriteMessage(websocket.TextMessage, dispatch(n, "READY", `{"session_id":"session","user":{"id":"bot"}}`))
+			}
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(test.codes[n-1], ""))
+			readOp(conn)
+		})
+
+		s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+		})
+		s.ReconnectInitialBackoff = 10 * time.Millisecond
+
+		if err := s.Open(); err != nil {
+			t.Fatalf("%s: Open() returned error: %+v", test.name, err)
+		}
+
+		// Closing a connection takes a second, a reconnect would follow.
+		time.Sleep(time.Duration(len(test.codes))*time.Second + 500*time.Millisecond)
+		s.Close()
+		gw.Close()
+
+		if n := atomic.LoadInt32(&connections); int(n) != len(test.codes) {
+			t.Errorf("%s: the gateway was connected to %d times, expected %d", test.name, n, len(test.codes))
+		}
+	}
+}
+
+func TestDisconnectAndResumed(t *testing.T) {
+	var connections int32
+	gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+		conn.WriteMessage(websocket.TextMessage, hello(45000))
+		readOp(conn)
+		if atomic.AddInt32(&connections, 1) == 1 {
+			conn.WriteMessage(websocket.TextMessage, dispatch(1, "READY", `{"session_id":"session","user":{"id":"bot"}}`))
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(GatewayCloseSessionTimedOut, ""))
+		} else {
+			conn.WriteMessage(websocket.TextMessage, dispatch(2, "RESUMED", `{}`))
+		}
+		readOp(conn)
+	})
+	defer gw.Close()
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+	})
+	s.ReconnectInitialBackoff = 10 * time.Millisecond
+
+	disconnects := make(chan int, 2)
+	s.AddHandler(func(s *Session, d *Disconnect) {
+		disconnects <- d.Code
+	})
+	resumed := make(chan struct{}, 1)
+	s.AddHandler(func(s *Session, r *Resumed) {
+		resumed <- struct{}{}
+	})
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open() returned error: %+v", err)
+	}
+
+	select {
+	case code := <-disconnects:
+		if code != GatewayCloseSessionTimedOut {
+			t.Errorf("Disconnect has code %d, expected %d", code, GatewayCloseSessionTimedOut)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Disconnect was not dispatched after the gateway closed the connection")
+	}
+
+	select {
+	case <-resumed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Resumed was not dispatched after reconnecting")
+	}
+
+	s.Close()
+	if code := <-disconnects; code != 0 {
+		t.Errorf("Disconnect after Close has code %d, expected 0", code)
+	}
+}
+
+func TestHeartbeatInterval(t *testing.T) {
+	heartbeats := make(chan time.Time, 100)
+	gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+		conn.WriteMessage(websocket.TextMessage, hello(45000))
+		readOp(conn)
+		conn.WriteMessage(websocket.TextMessage, dispatch(1, "READY", `{"session_id":"session","user":{"id":"bot"}}`))
+		for {
+			var op testGatewayOp
+			if err := conn.ReadJSON(&op); err != nil {
+				return
+			}
+			if op.Op == 1 {
+				heartbeats <- time.Now()
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"op":11}`))
+			}
+		}
+	})
+	defer gw.Close()
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+	})
+	s.HeartbeatInterval = 50 * time.Millisecond
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open() returned error: %+v", err)
+	}
+	defer s.Close()
+
+	// With the interval from Hello, a single heartbeat would be sent.
+	var first time.Time
+	for i := 0; i < 4; i++ {
+		select {
+		case sent := <-heartbeats:
+			if i == 0 {
+				first = sent
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%d heartbeats were sent, expected them to be sent every 50ms", i)
+		}
+	}
+	if elapsed := time.Since(first); elapsed < 100*time.Millisecond {
+		t.Errorf("4 heartbeats were sent in %s, expected 150ms", elapsed)
+	}
+
+	for _, test := range []struct {
+		override, expected time.Duration
+	}{
+		{0, 41250},
+		{10 * time.Second, 10000},
+		{time.Microsecond, 1},
+	} {
+		s := &Session{HeartbeatInterval: test.override}
+		if interval := s.heartbeatInterval(41250); interval != test.expected {
+			t.Errorf("with HeartbeatInterval %s, the interval is %dms, expected %dms", test.override, interval, test.expected)
+		}
+	}
+}
+
+func TestGuildSubscribe(t *testing.T) {
+	subscribe := make(chan testGatewayOp, 1)
+	gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+		conn.WriteMessage(websocket.TextMessage, hello(45000))
+		readOp(conn)
+		conn.WriteMessage(websocket.TextMessage, dispatch(1, "READY", `{"session_id":"session","user":{"id":"bot"}}`))
+
+		op, _ := readOp(conn)
+		subscribe <- op
+		readOp(conn)
+	})
+	defer gw.Close()
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+	})
+
+	if err := s.GuildSubscribe("guild", nil); err != ErrWSNotFound {
+		t.Errorf("GuildSubscribe without a connection returned %v, expected ErrWSNotFound", err)
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open() returned error: %+v", err)
+	}
+	defer s.Close()
+
+	if err := s.GuildSubscribe("guild", map[string][][2]int{"channel": {{0, 99}, {100, 199}}}); err != nil {
+		t.Fatalf("GuildSubscribe returned error: %+v", err)
+	}
+
+	op := <-subscribe
+	if op.Op != 14 {
+		t.Fatalf("gateway got op %d, expected 14", op.Op)
+	}
+	expected := `{"guild_id":"guild","typing":true,"threads":true,"activities":true,"channels":{"channel":[[0,99],[100,199]]}}`
+	if string(op.Data) != expected {
+		t.Errorf("op 14 payload is %s, expected %s", op.Data, expected)
+	}
+}
+
+func TestChunkGuildsOnStartup(t *testing.T) {
+	requested := make(chan string, 3)
+	gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+		conn.WriteMessage(websocket.TextMessage, hello(45000))
+		readOp(conn)
+		conn.WriteMessage(websocket.TextMessage, dispatch(1, "READY", `{"session_id":"session","user":{"id":"bot"},"guilds":[{"id":"a","unavailable":true},{"id":"b","unavailable":true}]}`))
+
+		for {
+			op, err := readOp(conn)
+			if err != nil {
+				return
+			}
+			if op.Op != 8 {
+				t.Errorf("gateway got op %d, expected member requests", op.Op)
+				continue
+			}
+
+			var data requestGuildMembersData
+			json.Unmarshal(op.Data, &data)
+			if data.Query != "" || data.Limit != 0 {
+				t.Errorf("members were requested with %+v, expected all members", data)
+			}
+			requested <- data.GuildID
+		}
+	})
+	defer gw.Close()
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+	})
+	s.ChunkGuildsOnStartup = true
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open() returned error: %+v", err)
+	}
+	defer s.Close()
+
+	for _, expected := range []string{"a", "b"} {
+		select {
+		case id := <-requested:
+			if id != expected {
+				t.Errorf("members of guild %s were requested, expected %s", id, expected)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("members of guild %s were not requested", expected)
+		}
+	}
+}
+
+func TestReconnectIdentify(t *testing.T) {
+	var connections int32
+	gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+		n := atomic.AddInt32(&connections, 1)
+		conn.WriteMessage(websocket.TextMessage, hello(45000))
+		if op, _ := readOp(conn); op.Op != 2 {
+			t.Errorf("connection %d started with op %d, expected an identify", n, op.Op)
+		}
+
+		if n == 1 {
+			conn.WriteMessage(websocket.TextMessage, dispatch(1, "READY", `{"session_id":"first","user":{"id":"bot"},"guilds":[{"id":"a","unavailable":true},{"id":"b","unavailable":true}]}`))
+			conn.WriteMessage(websocket.TextMessage, dispatch(2, "GUILD_CREATE", `{"id":"a","name":"a","channels":[{"id":"a-general"}]}`))
+			conn.WriteMessage(websocket.TextMessage, dispatch(3, "GUILD_CREATE", `{"id":"b","name":"b","channels":[{"id":"b-general"}]}`))
+
+			// The session is invalidated, and the connection then lost, so
+			// the session reconnects with a new identify.
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"op":9,"d":false}`))
+			if op, _ := readOp(conn); op.Op != 2 {
+				t.Errorf("session responded to an invalid session with op %d, expected an identify", op.Op)
+			}
+			return
+		}
+
+		conn.WriteMessage(websocket.TextMessage, dispatch(1, "READY", `{"session_id":"second","user":{"id":"bot"},"guilds":[{"id":"a","unavailable":true}]}`))
+		conn.WriteMessage(websocket.TextMessage, dispatch(2, "GUILD_CREATE", `{"id":"a","name":"a","channels":[{"id":"a-general"}]}`))
+		for {
+			if _, err := readOp(conn); err != nil {
+				return
+			}
+		}
+	})
+	defer gw.Close()
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+	})
+	s.SyncEvents = true
+
+	created := make(chan string, 3)
+	s.AddHandler(func(s *Session, g *GuildCreate) {
+		created <- g.ID
+	})
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open() returned error: %+v", err)
+	}
+	defer s.Close()
+
+	for _, expected := range []string{"a", "b", "a"} {
+		select {
+		case id := <-created:
+			if id != expected {
+				t.Errorf("GuildCreate handler got guild %s, expected %s", id, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("GuildCreate handler was not called for guild %s after reconnecting", expected)
+		}
+	}
+
+	s.State.RLock()
+	guilds := len(s.State.Guilds)
+	s.State.RUnlock()
+	if guilds != 1 {
+		t.Errorf("state has %d guilds after reconnecting, expected 1", guilds)
+	}
+	if _, err := s.State.Guild("b"); err == nil {
+		t.Error("state kept the guild of the previous session")
+	}
+	if _, err := s.State.Channel("b-general"); err == nil {
+		t.Error("state kept the channel of the previous session")
+	}
+	if c, err := s.State.Channel("a-general"); err != nil || c.GuildID != "a" {
+		t.Errorf("state has channel %+v and error %v, expected the channel of guild a", c, err)
+	}
+}
+
+func TestInvalidSessionResumable(t *testing.T) {
+	resumed := make(chan testGatewayOp, 1)
+	gw := newTestGateway(t, func(conn *websocket.Conn, r *http.Request) {
+		conn.WriteMessage(websocket.TextMessage, hello(45000))
+		readOp(conn)
+		conn.WriteMessage(websocket.TextMessage, dispatch(1, "READY", `{"session_id":"abc","user":{"id":"bot"}}`))
+		conn.WriteMessage(websocket.TextMessage, dispatch(2, "TYPING_START", `{"channel_id":"c"}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"op":9,"d":true}`))
+
+		op, _ := readOp(conn)
+		resumed <- op
+		for {
+			if _, err := readOp(conn); err != nil {
+				return
+			}
+		}
+	})
+	defer gw.Close()
+
+	s := newTestSession(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + gw.URL() + `"}`))
+	})
+	s.SyncEvents = true
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open() returned error: %+v", err)
+	}
+	defer s.Close()
+
+	select {
+	case op := <-resumed:
+		if op.Op != 6 {
+			t.Fatalf("session responded to a resumable invalid session with op %d, expected a resume", op.Op)
+		}
+		var data struct {
+			SessionID string `json:"session_id"`
+			Sequence  int64  `json:"seq"`
+		}
+		if err := json.Unmarshal(op.Data, &data); err != nil {
+			t.Fatalf("error unmarshalling resume packet, %s", err)
+		}
+		if data.SessionID != "abc" || data.Sequence != 2 {
+			t.Errorf("session resumed session %q at sequence %d, expected session abc at sequence 2", data.SessionID, data.Sequence)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not respond to a resumable invalid session")
+	}
+}
+
+func TestGatewayLimiter(t *testing.T) {
+	l := &gatewayLimiter{limit: 2, window: 100 * time.Millisecond}
+
+	start := time.Now()
+	l.wait()
+	l.wait()
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Errorf("commands under the limit were delayed by %s", d)
+	}
+
+	l.wait()
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Errorf("command over the limit was sent after %s, expected it to wait for the window", d)
+	}
+}